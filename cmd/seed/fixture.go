@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// Fixture is the seed file format. It can be written as YAML or JSON.
+type Fixture struct {
+	Modules []FixtureModule `yaml:"modules" json:"modules"`
+}
+
+// FixtureModule describes one module, its metadata and, optionally, its image.
+// Image is a path relative to the image directory; FileFormat defaults to the
+// MIME type implied by the image's file extension.
+type FixtureModule struct {
+	Name           string   `yaml:"name" json:"name"`
+	IP             string   `yaml:"ip" json:"ip"`
+	Port           int32    `yaml:"port" json:"port"`
+	Image          string   `yaml:"image" json:"image"`
+	FileFormat     string   `yaml:"fileformat" json:"fileformat"`
+	ProxyTimeoutMS *int64   `yaml:"proxy_timeout_ms" json:"proxy_timeout_ms"`
+	Description    string   `yaml:"description" json:"description"`
+	Version        string   `yaml:"version" json:"version"`
+	Author         string   `yaml:"author" json:"author"`
+	Tags           []string `yaml:"tags" json:"tags"`
+	Homepage       string   `yaml:"homepage" json:"homepage"`
+	Capabilities   []string `yaml:"capabilities" json:"capabilities"`
+}
+
+// seedModule is a fixture module whose image has been loaded and validated.
+type seedModule struct {
+	Register       service.RegisterInput
+	Image          *service.ImageInput
+	ProxyTimeoutMS *int64
+}
+
+// loadFixture reads a YAML or JSON fixture, chosen by file extension.
+// Unknown fields are rejected so typos do not silently drop data.
+func loadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&fixture)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&fixture)
+	default:
+		return nil, fmt.Errorf("unsupported fixture format %q (expected .yaml, .yml or .json)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	return &fixture, nil
+}
+
+// prepare validates every module with the same rules as the gRPC and REST APIs
+// and loads images from imageDir. Nothing is written until the whole fixture is valid.
+func (f *Fixture) prepare(imageDir string) ([]seedModule, error) {
+	seen := make(map[string]bool)
+	plan := make([]seedModule, 0, len(f.Modules))
+
+	for i, m := range f.Modules {
+		in := service.RegisterInput{Name: m.Name, IP: m.IP, Port: m.Port, Metadata: service.Metadata{
+			Description:  m.Description,
+			Version:      m.Version,
+			Author:       m.Author,
+			Tags:         m.Tags,
+			Homepage:     m.Homepage,
+			Capabilities: m.Capabilities,
+		}}
+		if err := service.ValidateRegisterInput(in); err != nil {
+			return nil, fmt.Errorf("module %d (%s): %w", i, m.Name, err)
+		}
+
+		if seen[m.Name] {
+			return nil, fmt.Errorf("module %d: duplicate name %q", i, m.Name)
+		}
+		seen[m.Name] = true
+
+		if m.ProxyTimeoutMS != nil && *m.ProxyTimeoutMS < 0 {
+			return nil, fmt.Errorf("module %d (%s): proxy timeout cannot be negative", i, m.Name)
+		}
+
+		seed := seedModule{Register: in, ProxyTimeoutMS: m.ProxyTimeoutMS}
+
+		if m.Image != "" {
+			image, err := m.loadImage(imageDir)
+			if err != nil {
+				return nil, fmt.Errorf("module %d (%s): %w", i, m.Name, err)
+			}
+			seed.Image = image
+		}
+
+		plan = append(plan, seed)
+	}
+
+	return plan, nil
+}
+
+// loadImage reads the module's image file and validates it.
+func (m FixtureModule) loadImage(imageDir string) (*service.ImageInput, error) {
+	path := filepath.Join(imageDir, m.Image)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	format := m.FileFormat
+	if format == "" {
+		format = mime.TypeByExtension(filepath.Ext(path))
+		// TypeByExtension may append parameters such as "; charset=utf-8"
+		format, _, _ = strings.Cut(format, ";")
+	}
+
+	image := &service.ImageInput{Image: data, FileFormat: format}
+
+	// The module ID is only known after registration; the name stands in for
+	// it so the remaining fields can be validated up front.
+	check := *image
+	check.ModuleID = m.Name
+	if err := service.ValidateImageInput(check); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
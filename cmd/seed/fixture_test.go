@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemoFixtureIsValid(t *testing.T) {
+	fixture, err := loadFixture("../../fixtures/demo.yaml")
+	require.NoError(t, err)
+
+	plan, err := fixture.prepare("../../fixtures")
+	require.NoError(t, err)
+	require.NotEmpty(t, plan)
+
+	for _, m := range plan {
+		require.NotNil(t, m.Image, m.Register.Name)
+		assert.Equal(t, "image/svg+xml", m.Image.FileFormat)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("json", func(t *testing.T) {
+		fixture, err := loadFixture(write("seed.json", `{"modules":[{"name":"a","ip":"10.0.0.1","port":80}]}`))
+		require.NoError(t, err)
+		assert.Equal(t, "a", fixture.Modules[0].Name)
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		_, err := loadFixture(write("typo.yaml", "modules:\n  - name: a\n    prot: 80\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		_, err := loadFixture(write("seed.toml", ""))
+		assert.ErrorContains(t, err, "unsupported fixture format")
+	})
+}
+
+func TestPrepareValidation(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "icon.txt"), []byte("hi"), 0o644))
+
+	tests := map[string]Fixture{
+		"invalid address": {Modules: []FixtureModule{{Name: "a", IP: "not a host", Port: 80}}},
+		"duplicate name": {Modules: []FixtureModule{
+			{Name: "a", IP: "10.0.0.1", Port: 80},
+			{Name: "a", IP: "10.0.0.2", Port: 80},
+		}},
+		"missing image":    {Modules: []FixtureModule{{Name: "a", IP: "10.0.0.1", Port: 80, Image: "missing.png"}}},
+		"non-image file":   {Modules: []FixtureModule{{Name: "a", IP: "10.0.0.1", Port: 80, Image: "icon.txt"}}},
+		"negative timeout": {Modules: []FixtureModule{{Name: "a", IP: "10.0.0.1", Port: 80, ProxyTimeoutMS: new(int64)}}},
+		"invalid version":  {Modules: []FixtureModule{{Name: "a", IP: "10.0.0.1", Port: 80, Version: "one"}}},
+	}
+	*tests["negative timeout"].Modules[0].ProxyTimeoutMS = -1
+
+	for name, fixture := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := fixture.prepare(dir)
+			assert.Error(t, err)
+		})
+	}
+}
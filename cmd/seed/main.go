@@ -0,0 +1,177 @@
+// Command seed loads modules and their images from a fixture file into the
+// database, so every developer and CI environment starts from the same
+// platform state. Seeding is idempotent: modules are matched by name and
+// updated in place when they already exist.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/The-OpenPlatform/backend/internal/config"
+	"github.com/The-OpenPlatform/backend/internal/db"
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+func main() {
+	fixturePath := flag.String("fixture", "fixtures/demo.yaml", "path to the YAML or JSON fixture file")
+	imageDir := flag.String("images", "", "directory containing module images (default: the fixture's directory)")
+	reset := flag.Bool("reset", false, "delete all existing modules before seeding")
+	dryRun := flag.Bool("dry-run", false, "validate the fixture and print the planned changes without writing")
+	loader := config.Bind(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if loader.PrintConfig() {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fixture, err := loadFixture(*fixturePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dir := *imageDir
+	if dir == "" {
+		dir = filepath.Dir(*fixturePath)
+	}
+
+	plan, err := fixture.prepare(dir)
+	if err != nil {
+		log.Fatalf("invalid fixture: %v", err)
+	}
+
+	// A dry run must not change the schema either
+	if *dryRun {
+		db.MustConnectWithoutMigrations(cfg.DB)
+	} else {
+		db.MustConnect(cfg.DB)
+	}
+	defer db.Close()
+
+	s := &seeder{modules: service.NewModules(db.DB), dryRun: *dryRun}
+	if err := s.run(context.Background(), plan, *reset); err != nil {
+		log.Fatalf("seeding failed: %v", err)
+	}
+}
+
+// seeder applies a validated plan through the module service.
+type seeder struct {
+	modules *service.Modules
+	dryRun  bool
+}
+
+func (s *seeder) run(ctx context.Context, plan []seedModule, reset bool) error {
+	prefix := ""
+	if s.dryRun {
+		prefix = "[dry-run] "
+	}
+
+	if reset {
+		if s.dryRun {
+			log.Printf("%swould delete all existing modules", prefix)
+		} else {
+			deleted, err := s.modules.DeleteAll(ctx)
+			if err != nil {
+				return err
+			}
+			log.Printf("deleted %d existing module(s)", deleted)
+		}
+	}
+
+	for _, m := range plan {
+		moduleID, err := s.upsert(ctx, m, reset)
+		if err != nil {
+			return err
+		}
+
+		if m.Image == nil {
+			continue
+		}
+
+		if s.dryRun {
+			log.Printf("%swould set %s image for %s", prefix, m.Image.FileFormat, m.Register.Name)
+			continue
+		}
+
+		image := *m.Image
+		image.ModuleID = moduleID
+		if err := s.modules.SetupImage(ctx, image); err != nil {
+			return err
+		}
+		log.Printf("set %s image for %s", image.FileFormat, m.Register.Name)
+	}
+
+	log.Printf("%sseeded %d module(s)", prefix, len(plan))
+	return nil
+}
+
+// upsert creates the module or updates the existing module with the same name.
+// It returns the module ID, which is empty for modules a dry run would create.
+func (s *seeder) upsert(ctx context.Context, m seedModule, reset bool) (string, error) {
+	var existing *service.Module
+	if !(reset && s.dryRun) {
+		var err error
+		existing, err = s.modules.FindByName(ctx, m.Register.Name)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return "", err
+		}
+	}
+
+	md := m.Register.Metadata
+	tags, capabilities := []string(md.Tags), []string(md.Capabilities)
+	update := service.UpdateInput{
+		IP:             &m.Register.IP,
+		Port:           &m.Register.Port,
+		ProxyTimeoutMS: m.ProxyTimeoutMS,
+		Description:    &md.Description,
+		Version:        &md.Version,
+		Author:         &md.Author,
+		Tags:           &tags,
+		Homepage:       &md.Homepage,
+		Capabilities:   &capabilities,
+	}
+
+	if existing != nil {
+		if s.dryRun {
+			log.Printf("[dry-run] would update module %s (%s)", m.Register.Name, existing.ModuleID)
+			return existing.ModuleID, nil
+		}
+
+		if _, err := s.modules.Update(ctx, existing.ModuleID, update); err != nil {
+			return "", err
+		}
+		log.Printf("updated module %s (%s)", m.Register.Name, existing.ModuleID)
+		return existing.ModuleID, nil
+	}
+
+	if s.dryRun {
+		log.Printf("[dry-run] would create module %s", m.Register.Name)
+		return "", nil
+	}
+
+	moduleID, err := s.modules.Register(ctx, m.Register)
+	if err != nil {
+		return "", err
+	}
+
+	if m.ProxyTimeoutMS != nil {
+		if _, err := s.modules.Update(ctx, moduleID, update); err != nil {
+			return "", err
+		}
+	}
+
+	log.Printf("created module %s (%s)", m.Register.Name, moduleID)
+	return moduleID, nil
+}
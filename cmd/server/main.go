@@ -1,44 +1,391 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/The-OpenPlatform/backend/internal/api"
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/auth"
+	"github.com/The-OpenPlatform/backend/internal/ca"
+	"github.com/The-OpenPlatform/backend/internal/certs"
+	"github.com/The-OpenPlatform/backend/internal/config"
 	"github.com/The-OpenPlatform/backend/internal/db"
 	"github.com/The-OpenPlatform/backend/internal/grpc/modules"
+	"github.com/The-OpenPlatform/backend/internal/lifecycle"
+	"github.com/The-OpenPlatform/backend/internal/oidc"
+	"github.com/The-OpenPlatform/backend/internal/probe"
+	"github.com/The-OpenPlatform/backend/internal/service"
+	"github.com/The-OpenPlatform/backend/internal/users"
 )
 
 func main() {
-	db.MustConnect()
-	defer db.Close()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		runUser(os.Args[2:])
+		return
+	}
 
-	r := api.SetupRouter()
-	go startGRPCServer()
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	cfg := mustLoadConfig(fs, os.Args[1:])
 
-	log.Println("Server is running on port 3000")
-	log.Fatal(http.ListenAndServe(":3000", r))
+	db.MustConnect(cfg.DB)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := run(ctx, cfg)
+	stop()
+
+	db.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-func startGRPCServer() {
-	lis, err := net.Listen("tcp", ":50051")
+// run serves HTTP and gRPC and runs the background workers until ctx is
+// cancelled or any of them fails, then drains both servers. The instance
+// reports not-ready for the configured drain delay before the listeners
+// close, so load balancers stop sending new traffic first.
+func run(ctx context.Context, cfg *config.Config) error {
+	ready := &lifecycle.Readiness{}
+	liveness := livenessConfig(cfg.Modules)
+
+	issuer, err := newIssuer(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	provider, err := newOIDCProvider(ctx, cfg.OIDC)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return err
+	}
+
+	grpcServer, reloader, err := newGRPCServer(cfg, liveness, ready, issuer)
+	if err != nil {
+		return err
+	}
+
+	httpLis, err := net.Listen("tcp", cfg.HTTP.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.HTTP.Addr, err)
+	}
+
+	grpcLis, err := net.Listen("tcp", cfg.GRPC.Addr)
+	if err != nil {
+		httpLis.Close()
+		return fmt.Errorf("failed to listen on %s: %w", cfg.GRPC.Addr, err)
+	}
+
+	httpServer := &http.Server{
+		Handler:           api.SetupRouter(cfg, ready, issuer, provider),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
 	}
 
-	grpcServer := grpc.NewServer()
-	registerGRPCServices(grpcServer)
+	g, gctx := errgroup.WithContext(ctx)
 
-	log.Println("gRPC server running on :50051")
-	if err := grpcServer.Serve(lis); err != nil {
+	if reloader != nil {
+		g.Go(func() error {
+			reloader.Watch(gctx, cfg.GRPC.TLSReloadInterval)
+			return nil
+		})
+	}
+
+	if issuer != nil {
+		g.Go(func() error {
+			issuer.WatchRevocations(gctx, cfg.CA.RevocationRefresh)
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		reapSessions(gctx, users.NewStore(db.DB), sessionReapInterval)
+		return nil
+	})
+
+	g.Go(func() error {
+		modules.NewReaper(liveness).Run(gctx)
+		return nil
+	})
+
+	g.Go(func() error {
+		probe.New(probeConfig(cfg.Modules)).Run(gctx)
+		return nil
+	})
+
+	g.Go(func() error {
+		log.Printf("Server is running on %s", httpLis.Addr())
+		if err := httpServer.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("HTTP server failed: %w", err)
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		log.Printf("gRPC server running on %s", grpcLis.Addr())
+		if err := grpcServer.Serve(grpcLis); err != nil {
+			return fmt.Errorf("gRPC server failed: %w", err)
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		<-gctx.Done()
+		ready.SetReady(false)
+
+		// Only a requested stop waits for load balancers; a failed server
+		// already stopped receiving traffic
+		if ctx.Err() != nil {
+			log.Printf("shutting down, draining for %s", cfg.Shutdown.DrainDelay)
+			time.Sleep(cfg.Shutdown.DrainDelay)
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+		defer cancel()
 
-		log.Fatalf("failed to serve: %v", err)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				log.Printf("HTTP server did not drain in time: %v", err)
+				httpServer.Close()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := lifecycle.StopGRPC(shutdownCtx, grpcServer); err != nil {
+				log.Printf("gRPC server did not drain in time: %v", err)
+			}
+		}()
+		wg.Wait()
+
+		log.Println("servers stopped")
+		return nil
+	})
+
+	ready.SetReady(true)
+	return g.Wait()
+}
+
+// sessionReapInterval is how often expired login sessions are deleted.
+const sessionReapInterval = time.Hour
+
+// reapSessions deletes expired login sessions every interval until ctx is
+// cancelled.
+func reapSessions(ctx context.Context, store *users.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.DeleteExpiredSessions(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("failed to delete expired sessions: %v", err)
+			}
+		}
 	}
 }
 
-func registerGRPCServices(grpcServer *grpc.Server) {
-	modules.RegisterModulesServiceServer(grpcServer, &modules.Server{})
+// mustLoadConfig binds the configuration flags to fs, parses args and
+// resolves the configuration. With -print-config it prints the redacted
+// configuration and exits.
+func mustLoadConfig(fs *flag.FlagSet, args []string) *config.Config {
+	loader := config.Bind(fs)
+	fs.Parse(args)
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if loader.PrintConfig() {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	return cfg
+}
+
+// newIssuer loads or creates the internal CA and its revocation list.
+// It returns nil when the CA is disabled.
+func newIssuer(ctx context.Context, cfg *config.Config) (*ca.Issuer, error) {
+	if !cfg.CA.Enabled {
+		return nil, nil
+	}
+
+	authority, err := ca.LoadOrCreate(ctx, ca.FileStore{Dir: cfg.CA.Dir}, cfg.CA.CommonName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+
+	issuer := ca.NewIssuer(authority, db.DB, cfg.CA.CertTTL)
+	if err := issuer.RefreshRevocations(ctx); err != nil {
+		return nil, err
+	}
+
+	return issuer, nil
+}
+
+// newOIDCProvider discovers the configured identity provider. It returns
+// nil when OIDC is disabled.
+func newOIDCProvider(ctx context.Context, cfg config.OIDC) (*oidc.Provider, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	mapping, err := oidc.ParseRoleMapping(cfg.RoleMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return oidc.New(ctx, oidc.Config{
+		IssuerURL:     cfg.IssuerURL,
+		ClientID:      cfg.ClientID,
+		ClientSecret:  cfg.ClientSecret,
+		RedirectURL:   cfg.RedirectURL,
+		Scopes:        cfg.Scopes,
+		Audiences:     cfg.Audiences,
+		UsernameClaim: cfg.UsernameClaim,
+		RoleClaim:     cfg.RoleClaim,
+		RoleMapping:   mapping,
+		DefaultRole:   users.Role(cfg.DefaultRole),
+	})
+}
+
+// newGRPCServer creates the gRPC server with its transport security and
+// interceptors and registers the modules and health services. The returned
+// reloader is nil unless TLS is enabled. A non-nil issuer makes the server
+// trust and check the certificates it issues.
+func newGRPCServer(cfg *config.Config, liveness modules.LivenessConfig, ready *lifecycle.Readiness, issuer *ca.Issuer) (*grpc.Server, *certs.Reloader, error) {
+	var moduleOpts []service.Option
+	if cfg.Modules.ResolveHosts {
+		moduleOpts = append(moduleOpts, service.WithResolver(net.DefaultResolver))
+	}
+
+	server := &modules.Server{
+		Liveness:      liveness,
+		Modules:       service.NewModules(db.DB, moduleOpts...),
+		CA:            issuer,
+		InferAddress:  cfg.Modules.InferAddress,
+		WatchInterval: cfg.Modules.WatchInterval,
+	}
+	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(cfg.GRPC.MaxRecvMsgBytes)}
+
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+
+	var reloader *certs.Reloader
+	if cfg.GRPC.TLSEnabled() {
+		var err error
+		var certOpts []certs.Option
+		if issuer != nil {
+			certOpts = append(certOpts,
+				certs.WithClientCAs(issuer.Authority().Certificate()),
+				certs.WithVerifyConnection(issuer.VerifyConnection))
+		}
+
+		reloader, err = certs.NewReloader(cfg.GRPC.TLSCert, cfg.GRPC.TLSKey, cfg.GRPC.TLSClientCA, clientAuthType(cfg.GRPC.TLSClientAuth), certOpts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+
+		if cfg.GRPC.TLSClientAuth != "none" {
+			unary = append(unary, modules.UnaryIdentityInterceptor(server.Modules))
+			stream = append(stream, modules.StreamIdentityInterceptor(server.Modules))
+		}
+	} else {
+		log.Println("gRPC TLS is disabled")
+	}
+
+	// API keys go first so that a valid key stands in for a module credential
+	keys := apikeys.NewStore(db.DB)
+	unary = append(unary, modules.UnaryAPIKeyInterceptor(keys))
+	stream = append(stream, modules.StreamAPIKeyInterceptor(keys))
+
+	if cfg.Auth.RequireModuleAuth {
+		server.Auth = auth.NewStore(db.DB)
+		unary = append(unary, modules.UnaryAuthInterceptor(server.Auth))
+		stream = append(stream, modules.StreamAuthInterceptor(server.Auth))
+	} else {
+		log.Println("module authentication is disabled")
+	}
+
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	grpcServer := grpc.NewServer(opts...)
+
+	modules.RegisterModulesServiceServer(grpcServer, server)
+
+	healthServer := health.NewServer()
+	ready.OnChange(func(ready bool) {
+		if ready {
+			healthServer.Resume()
+		} else {
+			healthServer.Shutdown()
+		}
+	})
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	return grpcServer, reloader, nil
+}
+
+// clientAuthType maps the grpc.tls_client_auth setting to the TLS policy.
+func clientAuthType(mode string) tls.ClientAuthType {
+	switch mode {
+	case "require":
+		return tls.RequireAndVerifyClientCert
+	case "optional":
+		return tls.VerifyClientCertIfGiven
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// livenessConfig converts the module settings into the liveness configuration.
+func livenessConfig(cfg config.Modules) modules.LivenessConfig {
+	return modules.LivenessConfig{
+		Interval:     cfg.HeartbeatInterval,
+		StaleAfter:   cfg.StaleAfter,
+		OfflineAfter: cfg.OfflineAfter,
+		InstanceTTL:  cfg.InstanceTTL,
+	}
+}
+
+// probeConfig converts the module settings into the active probe configuration.
+func probeConfig(cfg config.Modules) probe.Config {
+	return probe.Config{
+		Interval:    cfg.ProbeInterval,
+		Timeout:     cfg.ProbeTimeout,
+		Mode:        cfg.ProbeMode,
+		HTTPPath:    cfg.ProbeHTTPPath,
+		Concurrency: cfg.ProbeConcurrency,
+		Retention:   cfg.ProbeRetention,
+	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/The-OpenPlatform/backend/internal/db"
+	"github.com/The-OpenPlatform/backend/internal/db/migrations"
+)
+
+const migrateUsage = `usage: server migrate [-dry-run] [config flags] <command>
+
+commands:
+  up            apply all pending migrations
+  down [-steps N]
+                revert the last N migrations (default 1)
+  status        print the current and latest schema version
+`
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the SQL that would run without executing it")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+	cfg := mustLoadConfig(fs, args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db.MustConnectWithoutMigrations(cfg.DB)
+	defer db.Close()
+
+	migrator, err := migrations.New(db.DB)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+	migrator.DryRun = *dryRun
+	migrator.Out = os.Stdout
+
+	ctx := context.Background()
+
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		fmt.Printf("%d migration(s) applied\n", len(applied))
+
+	case "down":
+		downFlags := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := downFlags.Int("steps", 1, "number of migrations to revert")
+		downFlags.Parse(fs.Args()[1:])
+
+		reverted, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+		fmt.Printf("%d migration(s) reverted\n", len(reverted))
+
+	case "status":
+		current, err := migrator.Current(ctx)
+		if err != nil {
+			log.Fatalf("failed to read schema version: %v", err)
+		}
+		fmt.Printf("current version: %d\nlatest version:  %d\n", current, migrator.Latest())
+
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
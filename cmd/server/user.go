@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/The-OpenPlatform/backend/internal/db"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+const userUsage = `usage: server user [config flags] <command>
+
+commands:
+  add [-role ROLE] <username>
+                create a user; the password is read from the first line of stdin
+  list          print all users
+`
+
+// runUser implements the user subcommand, which creates the first accounts
+// before anyone can log in to the REST API.
+func runUser(args []string) {
+	fs := flag.NewFlagSet("user", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, userUsage)
+		fmt.Fprintln(os.Stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+	cfg := mustLoadConfig(fs, args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db.MustConnect(cfg.DB)
+	defer db.Close()
+
+	store := users.NewStore(db.DB)
+	ctx := context.Background()
+
+	switch fs.Arg(0) {
+	case "add":
+		addFlags := flag.NewFlagSet("add", flag.ExitOnError)
+		role := addFlags.String("role", string(users.RoleViewer), "role of the new user: viewer, operator or admin")
+		addFlags.Parse(fs.Args()[1:])
+
+		if addFlags.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			log.Fatalf("failed to read password from stdin: %v", err)
+		}
+
+		user, err := store.Create(ctx, addFlags.Arg(0), strings.TrimRight(password, "\r\n"), users.Role(*role))
+		if err != nil {
+			log.Fatalf("failed to create user: %v", err)
+		}
+		fmt.Printf("created %s user %s (%s)\n", user.Role, user.Username, user.UserID)
+
+	case "list":
+		list, err := store.List(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, user := range list {
+			fmt.Printf("%s\t%s\t%s\n", user.UserID, user.Username, user.Role)
+		}
+
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
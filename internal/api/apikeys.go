@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+// apiKeyRequest is the body of POST /api/api-keys.
+type apiKeyRequest struct {
+	Name   string          `json:"name"`
+	Scopes []apikeys.Scope `json:"scopes"`
+	// TTL is a Go duration such as "720h"; empty creates a key that does
+	// not expire.
+	TTL string `json:"ttl"`
+}
+
+// apiKeyResponse carries the secret of a newly created key, which is never
+// shown again.
+type apiKeyResponse struct {
+	Secret string `json:"key"`
+	*apikeys.Key
+}
+
+// CreateAPIKey creates an API key for a machine client.
+func CreateAPIKey(store *apikeys.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req apiKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var ttl time.Duration
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+				http.Error(w, "Validation failed: ttl must be a positive duration", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var createdBy string
+		if user, ok := users.FromContext(r.Context()); ok {
+			createdBy = user.Username
+		}
+
+		secret, key, err := store.Create(r.Context(), apikeys.CreateInput{
+			Name:      req.Name,
+			Scopes:    req.Scopes,
+			TTL:       ttl,
+			CreatedBy: createdBy,
+		})
+		switch {
+		case errors.Is(err, apikeys.ErrValidation):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, apiKeyResponse{Secret: secret, Key: key})
+	}
+}
+
+// ListAPIKeys returns all API keys without their secrets.
+func ListAPIKeys(store *apikeys.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := store.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, keys)
+	}
+}
+
+// RevokeAPIKey prevents further use of an API key.
+func RevokeAPIKey(store *apikeys.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := store.Revoke(r.Context(), chi.URLParam(r, "id"))
+		switch {
+		case errors.Is(err, apikeys.ErrNotFound):
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
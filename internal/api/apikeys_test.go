@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+// fakeKeys accepts a single API key.
+type fakeKeys struct {
+	token string
+	key   *apikeys.Key
+}
+
+func (f fakeKeys) Verify(_ context.Context, token string) (*apikeys.Key, error) {
+	if token != f.token {
+		return nil, apikeys.ErrInvalidKey
+	}
+	return f.key, nil
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	store := apikeys.NewStore(sqlx.NewDb(mockDB, "sqlmock"))
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(sqlmock.AnyArg(), "deploy", pq.StringArray{"modules:write"}, "alice", int64(86400)).
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "name", "scopes", "created_by", "expires_at", "last_used_at", "revoked_at", "created_at"}).
+			AddRow("k1", "deploy", "{modules:write}", "alice", now.Add(24*time.Hour), nil, nil, now))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", strings.NewReader(`{"name":"deploy","scopes":["modules:write"],"ttl":"24h"}`))
+	req = req.WithContext(users.WithUser(req.Context(), &users.User{Username: "alice", Role: users.RoleAdmin}))
+	rec := httptest.NewRecorder()
+	CreateAPIKey(store)(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "k1", body["key_id"])
+	assert.Equal(t, []interface{}{"modules:write"}, body["scopes"])
+	assert.True(t, strings.HasPrefix(body["key"].(string), "opk_"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	rec = httptest.NewRecorder()
+	CreateAPIKey(store)(rec, httptest.NewRequest(http.MethodPost, "/api/api-keys", strings.NewReader(`{"name":"deploy","scopes":["root"]}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	store, _ := newUserStore(t)
+	keys := fakeKeys{token: "opk_good", key: &apikeys.Key{Name: "ci", Scopes: pq.StringArray{"modules:read"}}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	proxy := authenticate(store, keys, "", nil)(requireAccessByMethod(readModules, writeModules)(ok))
+	admin := authenticate(store, keys, "", nil)(requireRole(users.RoleAdmin)(ok))
+
+	for _, tt := range []struct {
+		name    string
+		handler http.Handler
+		method  string
+		key     string
+		code    int
+	}{
+		{"read with read scope", proxy, http.MethodGet, "opk_good", http.StatusNoContent},
+		{"write with read scope", proxy, http.MethodPost, "opk_good", http.StatusForbidden},
+		{"invalid key", proxy, http.MethodGet, "opk_bad", http.StatusUnauthorized},
+		{"user-only route", admin, http.MethodGet, "opk_good", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		req.Header.Set(apiKeyHeader, tt.key)
+		rec := httptest.NewRecorder()
+		tt.handler.ServeHTTP(rec, req)
+		assert.Equal(t, tt.code, rec.Code, tt.name)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// proxyInstance is an instance of a module the proxy may route to.
+type proxyInstance struct {
+	InstanceID string `db:"instance_id"`
+	Host       string `db:"host"`
+	Port       int    `db:"port"`
+	Weight     int    `db:"weight"`
+}
+
+// balancer picks the instance that serves a proxied request according to
+// the module's load balancing policy. Its state lives in memory, so every
+// backend replica balances on its own.
+type balancer struct {
+	mu sync.Mutex
+	// next is the round-robin position of every module.
+	next map[string]int
+	// current holds the smooth weighted round-robin state of every module.
+	current map[string]map[string]int
+	// active counts the requests in flight to every instance.
+	active map[string]int
+}
+
+func newBalancer() *balancer {
+	return &balancer{
+		next:    make(map[string]int),
+		current: make(map[string]map[string]int),
+		active:  make(map[string]int),
+	}
+}
+
+// pick chooses one of instances, which must not be empty, and counts the
+// request as in flight until the returned function is called. Choosing and
+// counting under one lock keeps concurrent least connections picks from
+// all seeing the same idle instance. The state of the policies the module
+// does not use is dropped.
+func (b *balancer) pick(moduleID, policy string, instances []proxyInstance) (proxyInstance, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var instance proxyInstance
+	switch policy {
+	case service.PolicyLeastConnections:
+		delete(b.current, moduleID)
+		instance = b.leastConnections(moduleID, instances)
+	case service.PolicyWeighted:
+		delete(b.next, moduleID)
+		instance = b.weighted(moduleID, instances)
+	default:
+		delete(b.current, moduleID)
+		instance = instances[b.advance(moduleID, len(instances))]
+	}
+
+	b.active[instance.InstanceID]++
+	return instance, func() { b.release(instance.InstanceID) }
+}
+
+// forget drops the state of a module that is gone or has no instance to
+// route to.
+func (b *balancer) forget(moduleID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.next, moduleID)
+	delete(b.current, moduleID)
+}
+
+// advance returns the next round-robin position of a module.
+func (b *balancer) advance(moduleID string, n int) int {
+	i := b.next[moduleID] % n
+	b.next[moduleID] = i + 1
+	return i
+}
+
+// leastConnections picks the instance with the fewest requests in flight.
+// Ties are broken round-robin so that idle instances share the load.
+func (b *balancer) leastConnections(moduleID string, instances []proxyInstance) proxyInstance {
+	start := b.advance(moduleID, len(instances))
+	best := start
+	for i := 1; i < len(instances); i++ {
+		j := (start + i) % len(instances)
+		if b.active[instances[j].InstanceID] < b.active[instances[best].InstanceID] {
+			best = j
+		}
+	}
+	return instances[best]
+}
+
+// weighted uses the smooth weighted round-robin of nginx, which interleaves
+// the instances instead of sending each its share in one burst.
+func (b *balancer) weighted(moduleID string, instances []proxyInstance) proxyInstance {
+	previous := b.current[moduleID]
+	current := make(map[string]int, len(instances))
+
+	total, best := 0, 0
+	for i, instance := range instances {
+		current[instance.InstanceID] = previous[instance.InstanceID] + instance.Weight
+		total += instance.Weight
+		if current[instance.InstanceID] > current[instances[best].InstanceID] {
+			best = i
+		}
+	}
+
+	current[instances[best].InstanceID] -= total
+	b.current[moduleID] = current
+	return instances[best]
+}
+
+// release ends a request to an instance counted by pick.
+func (b *balancer) release(instanceID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.active[instanceID]--; b.active[instanceID] <= 0 {
+		delete(b.active, instanceID)
+	}
+}
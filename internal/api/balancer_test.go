@@ -0,0 +1,82 @@
+package api
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+func pickSequence(b *balancer, policy string, instances []proxyInstance, n int) []string {
+	var picked []string
+	for i := 0; i < n; i++ {
+		instance, release := b.pick("m1", policy, instances)
+		release()
+		picked = append(picked, instance.InstanceID)
+	}
+	return picked
+}
+
+func TestBalancer(t *testing.T) {
+	instances := []proxyInstance{{InstanceID: "a", Weight: 3}, {InstanceID: "b", Weight: 1}, {InstanceID: "c", Weight: 1}}
+
+	t.Run("round robin", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "c", "a"}, pickSequence(newBalancer(), service.PolicyRoundRobin, instances, 4))
+	})
+
+	t.Run("weighted interleaves by weight", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b", "a", "c", "a"}, pickSequence(newBalancer(), service.PolicyWeighted, instances, 5))
+	})
+
+	t.Run("weighted adapts to removed instances", func(t *testing.T) {
+		b := newBalancer()
+		pickSequence(b, service.PolicyWeighted, instances, 2)
+		assert.Equal(t, []string{"a", "a", "a"}, pickSequence(b, service.PolicyWeighted, instances[:1], 3))
+	})
+
+	t.Run("drops state of other policies and forgotten modules", func(t *testing.T) {
+		b := newBalancer()
+		pickSequence(b, service.PolicyWeighted, instances, 2)
+		pickSequence(b, service.PolicyRoundRobin, instances, 2)
+		assert.NotContains(t, b.current, "m1")
+		assert.Contains(t, b.next, "m1")
+
+		b.forget("m1")
+		assert.Empty(t, b.next)
+		assert.Empty(t, b.current)
+	})
+
+	t.Run("least connections", func(t *testing.T) {
+		b := newBalancer()
+		a, releaseA := b.pick("m1", service.PolicyLeastConnections, instances)
+		bb, _ := b.pick("m1", service.PolicyLeastConnections, instances)
+		c, _ := b.pick("m1", service.PolicyLeastConnections, instances)
+		assert.Equal(t, []string{"a", "b", "c"}, []string{a.InstanceID, bb.InstanceID, c.InstanceID})
+
+		releaseA()
+		next, _ := b.pick("m1", service.PolicyLeastConnections, instances)
+		assert.Equal(t, "a", next.InstanceID)
+	})
+
+	t.Run("concurrent least connections picks spread evenly", func(t *testing.T) {
+		b := newBalancer()
+		releases := make([]func(), 30)
+		var wg sync.WaitGroup
+		for i := range releases {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, releases[i] = b.pick("m1", service.PolicyLeastConnections, instances)
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, map[string]int{"a": 10, "b": 10, "c": 10}, b.active)
+
+		for _, release := range releases {
+			release()
+		}
+		assert.Empty(t, b.active)
+	})
+}
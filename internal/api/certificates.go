@@ -0,0 +1,42 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/The-OpenPlatform/backend/internal/ca"
+)
+
+// ListCertificates returns the unexpired certificates issued to modules.
+func ListCertificates(issuer *ca.Issuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		certs, err := issuer.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, certs)
+	}
+}
+
+// RevokeCertificate revokes a module certificate by its hex serial number.
+// New handshakes with the certificate are rejected from then on.
+func RevokeCertificate(issuer *ca.Issuer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := issuer.Revoke(r.Context(), strings.ToLower(chi.URLParam(r, "serial")))
+		switch {
+		case errors.Is(err, ca.ErrNotFound):
+			http.Error(w, "Certificate not found", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
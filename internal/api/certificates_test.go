@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/ca"
+)
+
+func TestRevokeCertificate(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	authority, err := ca.LoadOrCreate(context.Background(), ca.FileStore{Dir: t.TempDir()}, "Test CA")
+	require.NoError(t, err)
+	issuer := ca.NewIssuer(authority, sqlx.NewDb(mockDB, "sqlmock"), time.Hour)
+
+	r := chi.NewRouter()
+	r.Delete("/api/certificates/{serial}", RevokeCertificate(issuer))
+
+	mock.ExpectExec(`UPDATE module_certificates SET revoked_at`).WithArgs("abc").WillReturnResult(sqlmock.NewResult(0, 1))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/certificates/ABC", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	mock.ExpectExec(`UPDATE module_certificates SET revoked_at`).WithArgs("ff").WillReturnResult(sqlmock.NewResult(0, 0))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/certificates/ff", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
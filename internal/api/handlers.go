@@ -1,12 +1,20 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
 )
 
 func rootHandler(w http.ResponseWriter, r *http.Request) {
@@ -25,33 +33,38 @@ func getStatus(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(podName))
 }
 
+// GetModulesWithImages lists modules together with their images in a single query.
+// Results are paginated with an opaque cursor (?limit=&after=), can be filtered by
+// name prefix (?name=), status (?status=ONLINE,STALE) and tags (?tag=ui,beta,
+// matching modules with all of them) and sorted with
+// ?sort=name|created_at (prefix '-' for descending). When more results exist, a
+// Link header with rel="next" points at the following page.
 func GetModulesWithImages(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var modules []Module
-		err := db.Select(&modules, `SELECT module_id, name FROM modules`)
+		params, err := parseListParams(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		query, args := buildModuleListQuery(params)
+
+		var rows []moduleRow
+		if err := db.SelectContext(r.Context(), &rows, query, args...); err != nil {
+			log.Printf("failed to list modules: %v", err)
+			http.Error(w, "failed to list modules", http.StatusInternalServerError)
 			return
 		}
 
-		for i := range modules {
-			var images []struct {
-				ModuleID   string `db:"module_id"`
-				Image      []byte `db:"image"`
-				FileFormat string `db:"fileformat"`
-			}
-
-			err := db.Select(&images, `SELECT module_id, image, fileformat FROM images WHERE module_id=$1`, modules[i].ModuleID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			for _, img := range images {
-				base64Data := base64.StdEncoding.EncodeToString(img.Image)
-				dataURL := fmt.Sprintf("data:%s;base64,%s", img.FileFormat, base64Data)
-				modules[i].Images = append(modules[i].Images, Image{ModuleID: img.ModuleID, DataURL: dataURL})
-			}
+		if len(rows) > params.Limit {
+			rows = rows[:params.Limit]
+			last := rows[len(rows)-1]
+			w.Header().Set("Link", nextLink(r, last.cursor(params.Sort)))
+		}
+
+		modules := make([]Module, 0, len(rows))
+		for _, row := range rows {
+			modules = append(modules, row.toModule(params.Images))
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -59,13 +72,177 @@ func GetModulesWithImages(db *sqlx.DB) http.HandlerFunc {
 	}
 }
 
+// moduleRow is a module joined with its (optional) image.
+type moduleRow struct {
+	ModuleID   string         `db:"module_id"`
+	Name       string         `db:"name"`
+	Status     string         `db:"status"`
+	LastSeenAt *time.Time     `db:"last_seen_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+	FileFormat sql.NullString `db:"fileformat"`
+	Image      []byte         `db:"image"`
+	ImageHash  sql.NullString `db:"image_hash"`
+	service.Metadata
+}
+
+// cursor returns the pagination cursor pointing just after this row.
+func (row moduleRow) cursor(sort string) cursor {
+	key := row.Name
+	if strings.TrimPrefix(sort, "-") == "created_at" {
+		key = row.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return cursor{Sort: sort, Key: key, ModuleID: row.ModuleID}
+}
+
+// toModule converts the row into the API representation.
+func (row moduleRow) toModule(mode string) Module {
+	module := Module{
+		ModuleID:   row.ModuleID,
+		Name:       row.Name,
+		Status:     row.Status,
+		LastSeenAt: row.LastSeenAt,
+		Metadata:   row.Metadata,
+	}
+
+	if !row.ImageHash.Valid {
+		return module
+	}
+
+	switch mode {
+	case imagesAsDataURL:
+		base64Data := base64.StdEncoding.EncodeToString(row.Image)
+		dataURL := fmt.Sprintf("data:%s;base64,%s", row.FileFormat.String, base64Data)
+		module.Images = []Image{{ModuleID: row.ModuleID, DataURL: dataURL}}
+	case imagesAsURL:
+		module.Images = []Image{{ModuleID: row.ModuleID, URL: moduleImageURL(row.ModuleID, row.ImageHash.String)}}
+	}
+
+	return module
+}
+
+// buildModuleListQuery assembles the keyset-paginated module list query.
+// One extra row is requested to detect whether a next page exists.
+func buildModuleListQuery(p listParams) (string, []interface{}) {
+	column, desc := p.sortColumn()
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	// Only transfer image bytes when they are inlined into the response
+	imageColumn := "NULL::bytea"
+	if p.Images == imagesAsDataURL {
+		imageColumn = "i.image"
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if p.NamePrefix != "" {
+		conditions = append(conditions, "m.name LIKE "+arg(escapeLike(p.NamePrefix)+"%"))
+	}
+
+	if len(p.Statuses) > 0 {
+		conditions = append(conditions, "m.status = ANY("+arg(pq.Array(p.Statuses))+")")
+	}
+
+	if len(p.Tags) > 0 {
+		conditions = append(conditions, "m.tags @> "+arg(pq.Array(p.Tags)))
+	}
+
+	if p.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, m.module_id) %s (%s, %s)",
+			column, comparison, arg(p.After.Key), arg(p.After.ModuleID)))
+	}
+
+	query := `SELECT m.module_id, m.name, m.status, m.last_seen_at, m.created_at,
+		m.description, m.version, m.author, m.tags, m.homepage, m.capabilities,
+		i.fileformat, ` + imageColumn + ` AS image, i.hash AS image_hash
+		FROM modules m
+		LEFT JOIN images i ON i.module_id = m.module_id`
+
+	if len(conditions) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += fmt.Sprintf("\n\t\tORDER BY %s %s, m.module_id %s LIMIT %s", column, direction, direction, arg(p.Limit+1))
+
+	return query, args
+}
+
+// Image representations selectable with the images query parameter.
+const (
+	imagesAsDataURL = "data"
+	imagesAsURL     = "url"
+	imagesOmitted   = "none"
+)
+
+// imageMode reads the images query parameter, defaulting to inline data URLs.
+func imageMode(r *http.Request) (string, bool) {
+	switch mode := r.URL.Query().Get("images"); mode {
+	case "":
+		return imagesAsDataURL, true
+	case imagesAsDataURL, imagesAsURL, imagesOmitted:
+		return mode, true
+	default:
+		return "", false
+	}
+}
+
+// moduleImages returns a module's images in the requested representation.
+func moduleImages(ctx context.Context, db *sqlx.DB, moduleID, mode string) ([]Image, error) {
+	switch mode {
+	case imagesAsURL:
+		return imageURLs(ctx, db, moduleID)
+	case imagesOmitted:
+		return nil, nil
+	default:
+		return loadImages(ctx, db, moduleID)
+	}
+}
+
+// loadImages returns the images of a module encoded as data URLs.
+func loadImages(ctx context.Context, db *sqlx.DB, moduleID string) ([]Image, error) {
+	var images []struct {
+		ModuleID   string `db:"module_id"`
+		Image      []byte `db:"image"`
+		FileFormat string `db:"fileformat"`
+	}
+
+	err := db.SelectContext(ctx, &images, `SELECT module_id, image, fileformat FROM images WHERE module_id=$1`, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Image
+	for _, img := range images {
+		base64Data := base64.StdEncoding.EncodeToString(img.Image)
+		dataURL := fmt.Sprintf("data:%s;base64,%s", img.FileFormat, base64Data)
+		result = append(result, Image{ModuleID: img.ModuleID, DataURL: dataURL})
+	}
+
+	return result, nil
+}
+
 type Module struct {
-	ModuleID string  `db:"module_id" json:"module_id"`
-	Name     string  `db:"name" json:"name"`
-	Images   []Image `json:"images"`
+	ModuleID   string     `db:"module_id" json:"module_id"`
+	Name       string     `db:"name" json:"name"`
+	Status     string     `db:"status" json:"status"`
+	LastSeenAt *time.Time `db:"last_seen_at" json:"last_seen_at,omitempty"`
+	service.Metadata
+	Images []Image `json:"images"`
+	// LBPolicy, Instances and Dependencies are only included for a single module.
+	LBPolicy     string               `json:"lb_policy,omitempty"`
+	Instances    []service.Instance   `json:"instances,omitempty"`
+	Dependencies []service.Dependency `json:"dependencies,omitempty"`
 }
 
 type Image struct {
 	ModuleID string `json:"module_id"`
-	DataURL  string `json:"data_url"` // base64 image data
+	DataURL  string `json:"data_url,omitempty"` // base64 image data
+	URL      string `json:"url,omitempty"`      // link to the binary image endpoint
 }
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var moduleListColumns = []string{"module_id", "name", "status", "last_seen_at", "created_at", "fileformat", "image", "image_hash"}
+
+func TestGetModulesWithImages(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("returns first page with next link", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+
+		mock.ExpectQuery(`(?s)FROM modules m\s+LEFT JOIN images i.*WHERE m.name LIKE \$1 AND m.status = ANY\(\$2\).*ORDER BY m.name ASC, m.module_id ASC LIMIT \$3`).
+			WithArgs("da\\_%", sqlmock.AnyArg(), 3).
+			WillReturnRows(sqlmock.NewRows(moduleListColumns).
+				AddRow("m1", "da_a", "ONLINE", nil, created, "image/png", []byte("img"), "h1").
+				AddRow("00000000-0000-4000-a000-000000000002", "da_b", "ONLINE", nil, created, nil, nil, nil).
+				AddRow("m3", "da_c", "ONLINE", nil, created, nil, nil, nil))
+
+		rec := httptest.NewRecorder()
+		GetModulesWithImages(sqlx.NewDb(mockDB, "sqlmock"))(rec,
+			httptest.NewRequest(http.MethodGet, "/api/modules?limit=2&name=da_&status=online", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[
+			{"module_id":"m1","name":"da_a","status":"ONLINE","images":[{"module_id":"m1","data_url":"data:image/png;base64,aW1n"}]},
+			{"module_id":"00000000-0000-4000-a000-000000000002","name":"da_b","status":"ONLINE","images":null}
+		]`, rec.Body.String())
+
+		link := rec.Header().Get("Link")
+		require.True(t, strings.HasSuffix(link, `>; rel="next"`), link)
+		next, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`))
+		require.NoError(t, err)
+		assert.Equal(t, "2", next.Query().Get("limit"))
+		assert.Equal(t, "da_", next.Query().Get("name"))
+
+		c, err := decodeCursor(next.Query().Get("after"))
+		require.NoError(t, err)
+		assert.Equal(t, cursor{Sort: "name", Key: "da_b", ModuleID: "00000000-0000-4000-a000-000000000002"}, *c)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("continues after cursor in descending order", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+
+		after := cursor{Sort: "-created_at", Key: created.Format(time.RFC3339Nano), ModuleID: "00000000-0000-4000-a000-000000000002"}
+		mock.ExpectQuery(regexp.QuoteMeta(`NULL::bytea AS image`)+`(?s).*`+
+			regexp.QuoteMeta(`WHERE (m.created_at, m.module_id) < ($1, $2)`)+`.*`+
+			regexp.QuoteMeta(`ORDER BY m.created_at DESC, m.module_id DESC LIMIT $3`)).
+			WithArgs(after.Key, "00000000-0000-4000-a000-000000000002", 51).
+			WillReturnRows(sqlmock.NewRows(moduleListColumns).
+				AddRow("m1", "alpha", "STALE", nil, created, "image/png", nil, "h1"))
+
+		rec := httptest.NewRecorder()
+		GetModulesWithImages(sqlx.NewDb(mockDB, "sqlmock"))(rec,
+			httptest.NewRequest(http.MethodGet, "/api/modules?sort=-created_at&images=url&after="+after.encode(), nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Link"))
+		assert.JSONEq(t, `[{"module_id":"m1","name":"alpha","status":"STALE","images":[{"module_id":"m1","url":"/api/modules/m1/image?v=h1"}]}]`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("filters by tag and returns metadata", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+
+		columns := append(moduleListColumns, "description", "version", "author", "tags", "homepage", "capabilities")
+		mock.ExpectQuery(`(?s)m.tags, m.homepage.*WHERE m.tags @> \$1`).
+			WithArgs(pq.Array([]string{"ui", "beta"}), 51).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("m1", "dashboard", "ONLINE", nil, created, nil, nil, nil,
+					"Platform overview", "1.4.0", "ops", "{ui,beta}", "https://example.com", "{ui.widget}"))
+
+		rec := httptest.NewRecorder()
+		GetModulesWithImages(sqlx.NewDb(mockDB, "sqlmock"))(rec,
+			httptest.NewRequest(http.MethodGet, "/api/modules?tag=UI,beta", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `[{"module_id":"m1","name":"dashboard","status":"ONLINE","images":null,
+			"description":"Platform overview","version":"1.4.0","author":"ops","tags":["ui","beta"],
+			"homepage":"https://example.com","capabilities":["ui.widget"]}]`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		mismatched := cursor{Sort: "name", Key: "a", ModuleID: "00000000-0000-4000-a000-000000000001"}.encode()
+		badID := cursor{Sort: "name", Key: "a", ModuleID: "m1"}.encode()
+		badKey := cursor{Sort: "created_at", Key: "yesterday", ModuleID: "00000000-0000-4000-a000-000000000001"}.encode()
+		for _, query := range []string{"limit=0", "limit=1000", "sort=ip_port", "status=DEAD", "tag=ui,,beta", "after=!!",
+			"sort=-name&after=" + mismatched, "after=" + badID, "sort=created_at&after=" + badKey} {
+			rec := httptest.NewRecorder()
+			GetModulesWithImages(nil)(rec, httptest.NewRequest(http.MethodGet, "/api/modules?"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+	})
+
+	t.Run("hides database errors", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+
+		mock.ExpectQuery(`FROM modules m`).WillReturnError(errors.New(`pq: relation "modules" does not exist`))
+
+		rec := httptest.NewRecorder()
+		GetModulesWithImages(sqlx.NewDb(mockDB, "sqlmock"))(rec, httptest.NewRequest(http.MethodGet, "/api/modules", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "pq:")
+	})
+}
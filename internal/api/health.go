@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/The-OpenPlatform/backend/internal/lifecycle"
+)
+
+// readinessPingTimeout bounds the database check of the readiness endpoint.
+const readinessPingTimeout = 2 * time.Second
+
+// healthzHandler reports that the process is alive. It stays 200 while
+// draining so orchestrators do not restart an instance that is shutting down.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler reports whether the instance should receive traffic: it must
+// be marked ready and able to reach the database.
+func readyzHandler(ready *lifecycle.Readiness, db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessPingTimeout)
+		defer cancel()
+
+		if err := db.PingContext(ctx); err != nil {
+			http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("ready"))
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/lifecycle"
+)
+
+func TestReadyz(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	ready := &lifecycle.Readiness{}
+	handler := readyzHandler(ready, sqlx.NewDb(mockDB, "sqlmock"))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	ready.SetReady(true)
+	mock.ExpectPing()
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	mock.ExpectPing().WillReturnError(assert.AnError)
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	ready.SetReady(false)
+	rec = httptest.NewRecorder()
+	healthzHandler(rec, httptest.NewRequest(http.MethodGet, "/api/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// imageMeta is everything needed to answer a conditional image request
+// without loading the image bytes.
+type imageMeta struct {
+	FileFormat string    `db:"fileformat"`
+	UpdatedAt  time.Time `db:"updated_at"`
+	Hash       string    `db:"hash"`
+}
+
+// GetModuleImage serves a module's image as raw bytes.
+// It sets a strong ETag derived from the SHA-256 of the image and Last-Modified
+// from updated_at, and answers conditional requests with 304 Not Modified.
+// Images are served with a sandboxing Content-Security-Policy.
+// Requests carrying ?v=<hash> that matches the current image are cacheable forever.
+func GetModuleImage(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		moduleID := chi.URLParam(r, "id")
+		if !service.ValidID(moduleID) {
+			http.Error(w, "image not found", http.StatusNotFound)
+			return
+		}
+
+		var meta imageMeta
+		err := db.GetContext(r.Context(), &meta,
+			`SELECT fileformat, updated_at, hash FROM images WHERE module_id = $1`, moduleID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "image not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		etag := `"` + meta.Hash + `"`
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		// SVG images can carry scripts; keep them inert when opened directly.
+		// The policy is harmless for raster formats, so it is always sent.
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", meta.UpdatedAt.UTC().Format(http.TimeFormat))
+		if v := r.URL.Query().Get("v"); v != "" && v == meta.Hash {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "public, no-cache")
+		}
+
+		if notModified(r, etag, meta.UpdatedAt) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var image []byte
+		err = db.GetContext(r.Context(), &image, `SELECT image FROM images WHERE module_id = $1`, moduleID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "image not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", meta.FileFormat)
+		http.ServeContent(w, r, "", meta.UpdatedAt, bytes.NewReader(image))
+	}
+}
+
+// notModified evaluates If-None-Match and, when absent, If-Modified-Since
+// as described in RFC 9110 section 13.2.2.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !modified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// imageURLs returns links to the binary image endpoint for a module.
+// The URLs carry the content hash so they can be cached indefinitely.
+func imageURLs(ctx context.Context, db *sqlx.DB, moduleID string) ([]Image, error) {
+	var hashes []string
+	err := db.SelectContext(ctx, &hashes, `SELECT hash FROM images WHERE module_id = $1`, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Image
+	for _, hash := range hashes {
+		result = append(result, Image{ModuleID: moduleID, URL: moduleImageURL(moduleID, hash)})
+	}
+
+	return result, nil
+}
+
+func moduleImageURL(moduleID, hash string) string {
+	return "/api/modules/" + moduleID + "/image?v=" + hash
+}
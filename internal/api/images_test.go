@@ -0,0 +1,128 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newImageRouter(t *testing.T) (http.Handler, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	r := chi.NewRouter()
+	r.Get("/api/modules/{id}/image", GetModuleImage(sqlx.NewDb(mockDB, "sqlmock")))
+	return r, mock
+}
+
+func TestGetModuleImage(t *testing.T) {
+	updatedAt := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	metaRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"fileformat", "updated_at", "hash"}).AddRow("image/png", updatedAt, "abc123")
+	}
+
+	t.Run("serves image bytes with caching headers", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+		mock.ExpectQuery(`SELECT fileformat, updated_at`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(metaRows())
+		mock.ExpectQuery(`SELECT image FROM images`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"image"}).AddRow([]byte("PNGDATA")))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/image", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "PNGDATA", rec.Body.String())
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `"abc123"`, rec.Header().Get("ETag"))
+		assert.Equal(t, updatedAt.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+		assert.Equal(t, "public, no-cache", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("SVG is sandboxed", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+		mock.ExpectQuery(`SELECT fileformat, updated_at`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"fileformat", "updated_at", "hash"}).AddRow("image/SVG+xml", updatedAt, "abc123"))
+		mock.ExpectQuery(`SELECT image FROM images`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"image"}).AddRow([]byte("<svg/>")))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/image", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/SVG+xml", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "sandbox")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("versioned URLs are immutable", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+		mock.ExpectQuery(`SELECT fileformat, updated_at`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(metaRows())
+		mock.ExpectQuery(`SELECT image FROM images`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"image"}).AddRow([]byte("PNGDATA")))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/image?v=abc123", nil))
+
+		assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("matching ETag returns 304 without loading the image", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+		mock.ExpectQuery(`SELECT fileformat, updated_at`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(metaRows())
+
+		req := httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/image", nil)
+		req.Header.Set("If-None-Match", `"other", "abc123"`)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("If-Modified-Since returns 304", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+		mock.ExpectQuery(`SELECT fileformat, updated_at`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(metaRows())
+
+		req := httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/image", nil)
+		req.Header.Set("If-Modified-Since", updatedAt.Format(http.TimeFormat))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	})
+
+	t.Run("missing image", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+		mock.ExpectQuery(`SELECT fileformat, updated_at`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnError(sql.ErrNoRows)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/image", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("malformed module ID", func(t *testing.T) {
+		router, mock := newImageRouter(t)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/m1/image", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// maxImageSize bounds the size of images uploaded through the REST API.
+const maxImageSize = 10 << 20
+
+// GetModule returns a single module including its images, instances,
+// dependencies and load balancing policy. Like the list endpoint, it honours the images query
+// parameter.
+func GetModule(svc *service.Modules, db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		module, err := svc.Get(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		resp := Module{
+			ModuleID:   module.ModuleID,
+			Name:       module.Name,
+			Status:     module.Status,
+			LastSeenAt: module.LastSeenAt,
+			Metadata:   module.Metadata,
+			LBPolicy:   module.LBPolicy,
+		}
+
+		mode, ok := imageMode(r)
+		if !ok {
+			http.Error(w, "invalid images parameter (expected data, url or none)", http.StatusBadRequest)
+			return
+		}
+
+		resp.Images, err = moduleImages(r.Context(), db, module.ModuleID, mode)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp.Instances, err = svc.Instances(r.Context(), module.ModuleID)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		resp.Dependencies, err = svc.Dependencies(r.Context(), module.ModuleID)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// CreateModule registers a new module from a JSON body with name, ip, port
+// and optional metadata.
+func CreateModule(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var in service.RegisterInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		moduleID, err := svc.Register(r.Context(), in)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		module, err := svc.Get(r.Context(), moduleID)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/api/modules/"+moduleID)
+		writeJSON(w, http.StatusCreated, module)
+	}
+}
+
+// UpdateModule applies a partial JSON update to a module.
+func UpdateModule(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var in service.UpdateInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		module, err := svc.Update(r.Context(), chi.URLParam(r, "id"), in)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, module)
+	}
+}
+
+// ListModuleInstances lists the instances of a module.
+func ListModuleInstances(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instances, err := svc.Instances(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, instances)
+	}
+}
+
+// DeleteModuleInstance removes an instance of a module. The primary instance
+// cannot be removed.
+func DeleteModuleInstance(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := svc.RemoveInstance(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "instanceID")); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetModuleGraph returns the dependency graph of all modules with the order
+// to start them in.
+func GetModuleGraph(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		graph, err := svc.Graph(r.Context())
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, graph)
+	}
+}
+
+// GetModuleReadiness reports which modules have dependencies that are
+// missing, of the wrong version or unhealthy. With ?ready=false only those
+// modules are listed.
+func GetModuleReadiness(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filter *bool
+		if value := r.URL.Query().Get("ready"); value != "" {
+			ready, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "invalid ready parameter (expected true or false)", http.StatusBadRequest)
+				return
+			}
+			filter = &ready
+		}
+
+		readiness, err := svc.Readiness(r.Context())
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		modules := make([]service.Readiness, 0, len(readiness))
+		for _, module := range readiness {
+			if filter == nil || module.Ready == *filter {
+				modules = append(modules, module)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, modules)
+	}
+}
+
+// PutModuleImage stores the raw request body as the module image.
+// The Content-Type header is used as the image file format.
+func PutModuleImage(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		image, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImageSize))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				http.Error(w, fmt.Sprintf("image too large (max %d bytes)", maxErr.Limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "failed to read image: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = svc.SetupImage(r.Context(), service.ImageInput{
+			ModuleID:   chi.URLParam(r, "id"),
+			Image:      image,
+			FileFormat: r.Header.Get("Content-Type"),
+		})
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteModule removes a module. Like the gRPC Delete, it succeeds even if
+// the module does not exist.
+func DeleteModule(svc *service.Modules) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := svc.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeServiceError maps service errors onto HTTP status codes.
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		http.Error(w, fmt.Sprintf("Validation failed: %s", err.Error()), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, "Module not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInstanceNotFound):
+		http.Error(w, "Instance not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrConflict):
+		http.Error(w, "Module with the same name already exists", http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+func TestGetModuleReadiness(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	handler := GetModuleReadiness(service.NewModules(sqlx.NewDb(mockDB, "sqlmock")))
+
+	mock.ExpectQuery(`SELECT m.module_id, m.name, m.version, m.status`).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id", "name", "version", "status", "healthy"}).
+			AddRow("m1", "auth", "1.4.0", "STALE", false).
+			AddRow("m2", "dashboard", "", "ONLINE", true))
+	mock.ExpectQuery(`SELECT m.name AS module`).
+		WillReturnRows(sqlmock.NewRows([]string{"module", "name", "version_range"}).AddRow("dashboard", "auth", ""))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/modules/readiness?ready=false", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"module_id":"m2","name":"dashboard","ready":false,
+		"unmet":[{"from":"dashboard","to":"auth","problem":"unhealthy"}]}]`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/modules/readiness?ready=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
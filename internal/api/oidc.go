@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/The-OpenPlatform/backend/internal/oidc"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+// oidcStateCookie keeps the login state between the redirect to the
+// identity provider and the callback.
+const oidcStateCookie = "openplatform_oidc"
+
+// oidcLoginTimeout bounds how long a user may take at the identity provider.
+const oidcLoginTimeout = 10 * time.Minute
+
+// OIDCLogin starts a login at the identity provider.
+func OIDCLogin(provider *oidc.Provider, secure bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, err := oidc.NewLoginState()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Lax, not Strict: the callback is a top-level navigation from the provider
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state.Encode(),
+			Path:     "/api/auth/oidc",
+			MaxAge:   int(oidcLoginTimeout.Seconds()),
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		http.Redirect(w, r, provider.AuthCodeURL(state), http.StatusFound)
+	}
+}
+
+// OIDCCallback completes a login: it redeems the authorization code, maps
+// the user's claims to a role, stores the user and starts a session.
+func OIDCCallback(provider *oidc.Provider, store *users.Store, ttl time.Duration, redirectTo string, secure bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil {
+			http.Error(w, "login expired, please start again", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc", MaxAge: -1, HttpOnly: true, Secure: secure})
+
+		state, ok := oidc.DecodeLoginState(cookie.Value)
+		q := r.URL.Query()
+		if !ok || subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state.State)) != 1 {
+			http.Error(w, "invalid login state", http.StatusBadRequest)
+			return
+		}
+
+		if e := q.Get("error"); e != "" {
+			http.Error(w, "identity provider refused the login: "+e, http.StatusUnauthorized)
+			return
+		}
+
+		identity, err := provider.Exchange(r.Context(), q.Get("code"), state)
+		if err != nil {
+			log.Printf("OIDC login failed: %v", err)
+			http.Error(w, "login failed", http.StatusUnauthorized)
+			return
+		}
+
+		if identity.Role == "" {
+			http.Error(w, "forbidden: your account has no role on this platform", http.StatusForbidden)
+			return
+		}
+
+		user, err := store.UpsertExternal(r.Context(), identity.ExternalID(), identity.Username, identity.Role)
+		switch {
+		case errors.Is(err, users.ErrConflict):
+			http.Error(w, "username "+identity.Username+" is used by another account", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		token, expiresAt, err := store.CreateSession(r.Context(), user.UserID, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		setSessionCookie(w, token, expiresAt, secure)
+		http.Redirect(w, r, redirectTo, http.StatusFound)
+	}
+}
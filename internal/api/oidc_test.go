@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/oidc"
+	"github.com/The-OpenPlatform/backend/internal/oidc/oidctest"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+func newTestProvider(t *testing.T) (*oidc.Provider, *oidctest.Issuer) {
+	t.Helper()
+
+	issuer := oidctest.NewIssuer(t, "platform")
+	provider, err := oidc.New(context.Background(), oidc.Config{
+		IssuerURL:     issuer.URL,
+		ClientID:      "platform",
+		RedirectURL:   "http://platform.example/api/auth/oidc/callback",
+		Scopes:        []string{"openid"},
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "groups",
+		RoleMapping:   map[string]users.Role{"ops": users.RoleOperator},
+	})
+	require.NoError(t, err)
+
+	return provider, issuer
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	store, mock := newUserStore(t)
+	issuer.SetClaims(map[string]interface{}{"sub": "1234", "preferred_username": "alice", "groups": []string{"ops"}})
+
+	rec := httptest.NewRecorder()
+	OIDCLogin(provider, true)(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	stateCookie := rec.Result().Cookies()[0]
+	assert.True(t, stateCookie.Secure)
+
+	// Log in at the issuer, which redirects back to the callback
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO users .* ON CONFLICT \(external_id\)`).
+		WithArgs("alice", users.RoleOperator, issuer.URL+"|1234").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "role", "created_at", "updated_at"}).AddRow("u1", "alice", "operator", now, now))
+	mock.ExpectQuery(`INSERT INTO user_sessions`).WithArgs(sqlmock.AnyArg(), "u1", int64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at"}).AddRow(now.Add(time.Hour)))
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rec = httptest.NewRecorder()
+	OIDCCallback(provider, store, time.Hour, "/", true)(rec, req)
+
+	require.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+	assert.Equal(t, "/", rec.Header().Get("Location"))
+
+	var session *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			session = c
+		}
+	}
+	require.NotNil(t, session)
+	assert.Regexp(t, `^ops_`, session.Value)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// A forged state is rejected before the code is redeemed
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?state=forged&code=x", nil)
+	req.AddCookie(stateCookie)
+	rec = httptest.NewRecorder()
+	OIDCCallback(provider, store, time.Hour, "/", true)(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestBearerTokenAuthentication(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	store, _ := newUserStore(t)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	handler := authenticate(store, nil, "", provider)(requireRole(users.RoleOperator)(ok))
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"mapped group", issuer.Sign(map[string]interface{}{"sub": "1", "groups": []string{"ops"}}), http.StatusNoContent},
+		{"no mapped group", issuer.Sign(map[string]interface{}{"sub": "1"}), http.StatusForbidden},
+		{"wrong audience", issuer.Sign(map[string]interface{}{"sub": "1", "aud": "other", "groups": []string{"ops"}}), http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/modules", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tt.code, rec.Code, tt.name)
+	}
+}
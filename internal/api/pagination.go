@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// Page size limits for list endpoints.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// moduleSorts maps the sort query parameter onto the column it orders by.
+// A leading '-' sorts descending.
+var moduleSorts = map[string]string{
+	"name":       "m.name",
+	"created_at": "m.created_at",
+}
+
+// moduleStatuses are the status values accepted by the status filter.
+var moduleStatuses = map[string]bool{
+	"ONLINE":  true,
+	"STALE":   true,
+	"OFFLINE": true,
+}
+
+// listParams are the parsed query parameters of GET /api/modules.
+type listParams struct {
+	Limit      int
+	After      *cursor
+	NamePrefix string
+	Statuses   []string
+	Tags       []string
+	Sort       string
+	Images     string
+}
+
+// sortColumn returns the column and direction for the requested sort.
+func (p listParams) sortColumn() (string, bool) {
+	desc := strings.HasPrefix(p.Sort, "-")
+	return moduleSorts[strings.TrimPrefix(p.Sort, "-")], desc
+}
+
+// cursor identifies the last row of a page. It is bound to the sort order it
+// was produced for, so it cannot be replayed against a different ordering.
+type cursor struct {
+	Sort     string `json:"s"`
+	Key      string `json:"k"`
+	ModuleID string `json:"id"`
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || !service.ValidID(c.ModuleID) {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	// The key is compared against the sort column, so it must parse as that
+	// column's type.
+	if strings.TrimPrefix(c.Sort, "-") == "created_at" {
+		if _, err := time.Parse(time.RFC3339Nano, c.Key); err != nil {
+			return nil, fmt.Errorf("malformed cursor")
+		}
+	}
+
+	return &c, nil
+}
+
+// parseListParams validates the pagination, filter and sort parameters.
+func parseListParams(r *http.Request) (listParams, error) {
+	q := r.URL.Query()
+	p := listParams{Limit: defaultPageSize, Sort: "name"}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return p, fmt.Errorf("invalid limit (must be 1-%d)", maxPageSize)
+		}
+		p.Limit = limit
+	}
+
+	if v := q.Get("sort"); v != "" {
+		if _, ok := moduleSorts[strings.TrimPrefix(v, "-")]; !ok {
+			return p, fmt.Errorf("invalid sort %q (expected name or created_at, optionally prefixed with '-')", v)
+		}
+		p.Sort = v
+	}
+
+	if v := q.Get("after"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			return p, err
+		}
+		if c.Sort != p.Sort {
+			return p, fmt.Errorf("cursor does not match sort order")
+		}
+		p.After = c
+	}
+
+	p.NamePrefix = q.Get("name")
+
+	if v := q.Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !moduleStatuses[status] {
+				return p, fmt.Errorf("invalid status %q", status)
+			}
+			p.Statuses = append(p.Statuses, status)
+		}
+	}
+
+	if v := q.Get("tag"); v != "" {
+		for _, tag := range strings.Split(v, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag == "" {
+				return p, fmt.Errorf("invalid tag filter %q", v)
+			}
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+
+	mode, ok := imageMode(r)
+	if !ok {
+		return p, fmt.Errorf("invalid images parameter (expected data, url or none)")
+	}
+	p.Images = mode
+
+	return p, nil
+}
+
+// nextLink builds an RFC 8288 Link header pointing at the page after c,
+// keeping every other query parameter of the current request.
+func nextLink(r *http.Request, c cursor) string {
+	q := r.URL.Query()
+	q.Set("after", c.encode())
+
+	next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return fmt.Sprintf(`<%s>; rel="next"`, next.String())
+}
+
+// escapeLike escapes LIKE wildcards so a name prefix is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
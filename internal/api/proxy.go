@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+// proxyTarget is the routing information stored for a module.
+type proxyTarget struct {
+	Status         string        `db:"status"`
+	ProxyTimeoutMS sql.NullInt64 `db:"proxy_timeout_ms"`
+	LBPolicy       string        `db:"lb_policy"`
+}
+
+// ModuleProxy reverse-proxies /api/modules/{id}/proxy/* to one of the module's
+// instances. Instances that are OFFLINE or failed their last probe are
+// skipped, and the module's load balancing policy picks among the others.
+// Responses are flushed as they arrive so streaming endpoints work, and
+// WebSocket upgrades are passed through to the module.
+type ModuleProxy struct {
+	db             *sqlx.DB
+	defaultTimeout time.Duration
+	balancer       *balancer
+
+	// transports caches one transport per response header timeout so that
+	// connections to modules are pooled across requests.
+	transports sync.Map
+}
+
+// NewModuleProxy creates a module proxy backed by the given database.
+// defaultTimeout bounds how long the proxy waits for a module's response
+// headers when the module has no timeout of its own configured.
+func NewModuleProxy(db *sqlx.DB, defaultTimeout time.Duration) *ModuleProxy {
+	return &ModuleProxy{db: db, defaultTimeout: defaultTimeout, balancer: newBalancer()}
+}
+
+func (p *ModuleProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	moduleID := chi.URLParam(r, "id")
+	if !service.ValidID(moduleID) {
+		http.Error(w, "module not found", http.StatusNotFound)
+		return
+	}
+
+	var target proxyTarget
+	err := p.db.GetContext(r.Context(), &target,
+		`SELECT status, proxy_timeout_ms, lb_policy FROM modules WHERE module_id = $1`, moduleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		p.balancer.forget(moduleID)
+		http.Error(w, "module not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if target.Status == "OFFLINE" {
+		p.balancer.forget(moduleID)
+		http.Error(w, "module is offline", http.StatusServiceUnavailable)
+		return
+	}
+
+	var instances []proxyInstance
+	err = p.db.SelectContext(r.Context(), &instances,
+		`SELECT instance_id, host, port, weight FROM module_instances
+		WHERE module_id = $1 AND status <> 'OFFLINE' AND healthy IS NOT FALSE
+		ORDER BY created_at, instance_id`, moduleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(instances) == 0 {
+		p.balancer.forget(moduleID)
+		http.Error(w, "no healthy instance of the module is available", http.StatusServiceUnavailable)
+		return
+	}
+
+	instance, release := p.balancer.pick(moduleID, target.LBPolicy, instances)
+	defer release()
+
+	upstream := &url.URL{Scheme: "http", Host: net.JoinHostPort(instance.Host, strconv.Itoa(instance.Port))}
+	prefix := strings.TrimSuffix(r.URL.Path, "/"+chi.URLParam(r, "*"))
+
+	timeout := p.defaultTimeout
+	if target.ProxyTimeoutMS.Valid && target.ProxyTimeoutMS.Int64 > 0 {
+		timeout = time.Duration(target.ProxyTimeoutMS.Int64) * time.Millisecond
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			pr.Out.URL.Path = "/" + chi.URLParam(r, "*")
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = upstream.Host
+			pr.SetXForwarded()
+			pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+			pr.Out.Header.Set("X-OpenPlatform-Module-Id", moduleID)
+			pr.Out.Header.Set("X-OpenPlatform-Instance-Id", instance.InstanceID)
+			forwardUser(pr.Out)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			rewriteLocation(resp, upstream, prefix)
+			return nil
+		},
+		Transport:     p.transport(timeout),
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("proxy to module %s instance %s failed: %v", moduleID, instance.InstanceID, err)
+			status := http.StatusBadGateway
+			var netErr net.Error
+			if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+				status = http.StatusGatewayTimeout
+			}
+			http.Error(w, http.StatusText(status), status)
+		},
+	}
+
+	proxy.ServeHTTP(w, r)
+}
+
+// forwardUser replaces the platform credentials of an outgoing request with
+// headers naming the authenticated user or API key, so modules never see
+// session tokens or keys but can still tell who is calling.
+func forwardUser(out *http.Request) {
+	out.Header.Del("Authorization")
+	out.Header.Del(apiKeyHeader)
+	out.Header.Del("X-OpenPlatform-User")
+	out.Header.Del("X-OpenPlatform-Role")
+
+	if cookies := out.Cookies(); len(cookies) > 0 {
+		out.Header.Del("Cookie")
+		for _, c := range cookies {
+			if c.Name != sessionCookie {
+				out.AddCookie(c)
+			}
+		}
+	}
+
+	if name := principal(out.Context()); name != "" {
+		out.Header.Set("X-OpenPlatform-User", name)
+	}
+
+	if user, ok := users.FromContext(out.Context()); ok {
+		out.Header.Set("X-OpenPlatform-Role", string(user.Role))
+	}
+}
+
+// transport returns a pooled transport that waits at most timeout for response headers.
+// The timeout does not apply to the body, so long-lived streams are not cut off.
+func (p *ModuleProxy) transport(timeout time.Duration) http.RoundTripper {
+	if t, ok := p.transports.Load(timeout); ok {
+		return t.(http.RoundTripper)
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ResponseHeaderTimeout = timeout
+	actual, _ := p.transports.LoadOrStore(timeout, t)
+	return actual.(http.RoundTripper)
+}
+
+// rewriteLocation maps redirects that point at the module back onto the proxy prefix,
+// so clients keep talking to the backend instead of the module directly.
+func rewriteLocation(resp *http.Response, upstream *url.URL, prefix string) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return
+	}
+
+	loc, err := url.Parse(location)
+	if err != nil {
+		return
+	}
+
+	if loc.IsAbs() && loc.Host != upstream.Host {
+		return
+	}
+
+	if loc.IsAbs() || strings.HasPrefix(loc.Path, "/") {
+		loc.Scheme = ""
+		loc.Host = ""
+		loc.Path = prefix + loc.Path
+		resp.Header.Set("Location", loc.String())
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+func newProxyRouter(t *testing.T) (http.Handler, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	r := chi.NewRouter()
+	r.Handle("/api/modules/{id}/proxy/*", NewModuleProxy(sqlx.NewDb(mockDB, "sqlmock"), time.Second))
+	return r, mock
+}
+
+func expectTarget(mock sqlmock.Sqlmock, moduleID, ipPort, status string, timeoutMS interface{}) {
+	mock.ExpectQuery(`SELECT status, proxy_timeout_ms, lb_policy FROM modules`).
+		WithArgs(moduleID).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "proxy_timeout_ms", "lb_policy"}).
+			AddRow(status, timeoutMS, "round_robin"))
+	if status != "OFFLINE" {
+		expectInstances(mock, moduleID, ipPort)
+	}
+}
+
+// expectInstances returns routable instances i1, i2, ... at the given addresses.
+func expectInstances(mock sqlmock.Sqlmock, moduleID string, addresses ...string) {
+	rows := sqlmock.NewRows([]string{"instance_id", "host", "port", "weight"})
+	for i, address := range addresses {
+		host, port, _ := net.SplitHostPort(address)
+		rows.AddRow(fmt.Sprintf("i%d", i+1), host, port, 1)
+	}
+	mock.ExpectQuery(`SELECT instance_id, host, port, weight FROM module_instances`).WithArgs(moduleID).WillReturnRows(rows)
+}
+
+func TestModuleProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.Header().Set("X-Seen-Prefix", r.Header.Get("X-Forwarded-Prefix"))
+			w.Header().Set("X-Seen-Module", r.Header.Get("X-OpenPlatform-Module-Id"))
+			w.Header().Set("X-Seen-Instance", r.Header.Get("X-OpenPlatform-Instance-Id"))
+			w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+		}
+	}))
+	defer upstream.Close()
+	address := strings.TrimPrefix(upstream.URL, "http://")
+
+	t.Run("forwards path, query and headers", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		expectTarget(mock, "00000000-0000-4000-a000-000000000001", address, "ONLINE", nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/items/42?x=1", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "/items/42?x=1", rec.Body.String())
+		assert.Equal(t, "/api/modules/00000000-0000-4000-a000-000000000001/proxy", rec.Header().Get("X-Seen-Prefix"))
+		assert.Equal(t, "00000000-0000-4000-a000-000000000001", rec.Header().Get("X-Seen-Module"))
+		assert.Equal(t, "i1", rec.Header().Get("X-Seen-Instance"))
+	})
+
+	t.Run("reaches instances by DNS name", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		expectTarget(mock, "00000000-0000-4000-a000-000000000001", strings.Replace(address, "127.0.0.1", "localhost", 1), "ONLINE", nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/items", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "/items?", rec.Body.String())
+	})
+
+	t.Run("spreads requests over instances", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		var seen []string
+		for i := 0; i < 4; i++ {
+			mock.ExpectQuery(`SELECT status, proxy_timeout_ms, lb_policy FROM modules`).
+				WillReturnRows(sqlmock.NewRows([]string{"status", "proxy_timeout_ms", "lb_policy"}).AddRow("ONLINE", nil, "round_robin"))
+			expectInstances(mock, "00000000-0000-4000-a000-000000000001", address, address)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/", nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			seen = append(seen, rec.Header().Get("X-Seen-Instance"))
+		}
+
+		assert.Equal(t, []string{"i1", "i2", "i1", "i2"}, seen)
+	})
+
+	t.Run("rejects modules without healthy instances", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		mock.ExpectQuery(`SELECT status, proxy_timeout_ms, lb_policy FROM modules`).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "proxy_timeout_ms", "lb_policy"}).AddRow("ONLINE", nil, "round_robin"))
+		expectInstances(mock, "00000000-0000-4000-a000-000000000001")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("rewrites redirects onto the proxy prefix", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		expectTarget(mock, "00000000-0000-4000-a000-000000000001", address, "ONLINE", nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/redirect", nil))
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/login", rec.Header().Get("Location"))
+	})
+
+	t.Run("applies per-module timeout", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		expectTarget(mock, "00000000-0000-4000-a000-000000000001", address, "ONLINE", 50)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/slow", nil))
+
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	})
+
+	t.Run("rejects offline modules", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		expectTarget(mock, "00000000-0000-4000-a000-000000000001", address, "OFFLINE", nil)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("unknown module", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+		mock.ExpectQuery(`SELECT status`).WithArgs("00000000-0000-4000-a000-000000000404").WillReturnError(sql.ErrNoRows)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000404/proxy/", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed module ID", func(t *testing.T) {
+		router, mock := newProxyRouter(t)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/modules/nope/proxy/", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestForwardUserStripsCredentials(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/", nil)
+	req.Header.Set("Authorization", "Bearer ops_secret")
+	req.Header.Set("X-OpenPlatform-Role", "admin")
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: "ops_secret"})
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	req = req.WithContext(users.WithUser(req.Context(), &users.User{Username: "alice", Role: users.RoleViewer}))
+
+	forwardUser(req)
+
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Equal(t, "theme=dark", req.Header.Get("Cookie"))
+	assert.Equal(t, "alice", req.Header.Get("X-OpenPlatform-User"))
+	assert.Equal(t, "viewer", req.Header.Get("X-OpenPlatform-Role"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/modules/00000000-0000-4000-a000-000000000001/proxy/", nil)
+	req.Header.Set(apiKeyHeader, "opk_secret")
+	req = req.WithContext(apikeys.WithKey(req.Context(), &apikeys.Key{Name: "ci"}))
+
+	forwardUser(req)
+
+	assert.Empty(t, req.Header.Get(apiKeyHeader))
+	assert.Equal(t, "api-key:ci", req.Header.Get("X-OpenPlatform-User"))
+	assert.Empty(t, req.Header.Get("X-OpenPlatform-Role"))
+}
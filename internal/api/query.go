@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/models"
+	"github.com/The-OpenPlatform/backend/internal/query"
+)
+
+// Headers describing a streamed query result. The row count, truncation
+// and late errors are only known at the end and are sent as trailers.
+const (
+	queryColumnsHeader    = "X-Query-Columns"
+	queryRowsTrailer      = "X-Query-Rows"
+	queryTruncatedTrailer = "X-Query-Truncated"
+	queryErrorTrailer     = "X-Query-Error"
+)
+
+// errKeyWrite is returned when an API key attempts to modify data.
+var errKeyWrite = fmt.Errorf("%w: API keys may only run read-only queries", query.ErrNotAllowed)
+
+// RunQuery runs an ad-hoc SQL statement. Statements are checked against the
+// allowlist of the query package first. API keys may only run read-only
+// statements, as their query:read scope implies.
+//
+// Read-only results are streamed in the format selected by the Accept
+// header: JSON with the column schema (the default), NDJSON or CSV. The
+// columns and their types are also sent in the X-Query-Columns header.
+func RunQuery(runner *query.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := negotiateQueryFormat(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.QueryRequest
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeQueryError(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stmt, err := query.Parse(req.Query)
+		if err != nil {
+			rejectStatement(w, r, runner, &query.Statement{SQL: req.Query}, req.Params, err)
+			return
+		}
+
+		runStatement(w, r, runner, format, stmt, req.Params)
+	}
+}
+
+// negotiateQueryFormat picks the result format or answers 406.
+func negotiateQueryFormat(w http.ResponseWriter, r *http.Request) (query.Format, bool) {
+	format, ok := query.NegotiateFormat(r.Header.Get("Accept"))
+	if !ok {
+		writeQueryError(w, "supported formats are application/json, application/x-ndjson and text/csv", http.StatusNotAcceptable)
+	}
+	return format, ok
+}
+
+// runStatement runs a checked statement on behalf of the caller and writes
+// its result.
+func runStatement(w http.ResponseWriter, r *http.Request, runner *query.Runner, format query.Format, stmt *query.Statement, params []interface{}) {
+	if _, byKey := apikeys.FromContext(r.Context()); byKey && !stmt.ReadOnly {
+		rejectStatement(w, r, runner, stmt, params, errKeyWrite)
+		return
+	}
+
+	ctx := query.WithActor(r.Context(), principal(r.Context()))
+
+	if !stmt.ReadOnly {
+		affected, err := runner.Exec(ctx, stmt, params)
+		if err != nil {
+			writeQueryResultError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, models.QueryResponse{
+			Success: true,
+			Data:    map[string]interface{}{"message": "Query executed successfully"},
+			Rows:    int(affected),
+		})
+		return
+	}
+
+	started := false
+	summary, err := runner.Stream(ctx, stmt, params, w, format.NewEncoder(), func(cols []query.Column) {
+		started = true
+		columns, _ := json.Marshal(cols)
+		w.Header().Set("Content-Type", string(format))
+		w.Header().Set(queryColumnsHeader, string(columns))
+		w.Header().Set("Trailer", queryRowsTrailer+", "+queryTruncatedTrailer+", "+queryErrorTrailer)
+		w.WriteHeader(http.StatusOK)
+	})
+	if err != nil && !started {
+		writeQueryResultError(w, err)
+		return
+	}
+	if err != nil {
+		// The client most likely went away
+		log.Printf("failed to write query result: %v", err)
+	}
+
+	w.Header().Set(queryRowsTrailer, strconv.Itoa(summary.Rows))
+	w.Header().Set(queryTruncatedTrailer, strconv.FormatBool(summary.Truncated))
+	if summary.Err != nil {
+		w.Header().Set(queryErrorTrailer, summary.Err.Error())
+	}
+}
+
+// rejectStatement records a statement refused before it ran in the audit
+// log and answers with err.
+func rejectStatement(w http.ResponseWriter, r *http.Request, runner *query.Runner, stmt *query.Statement, params []interface{}, err error) {
+	runner.Reject(query.WithActor(r.Context(), principal(r.Context())), stmt, params, err)
+	writeQueryResultError(w, err)
+}
+
+// writeQueryResultError maps query errors to HTTP responses.
+func writeQueryResultError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, query.ErrSyntax), errors.Is(err, query.ErrParams), errors.Is(err, query.ErrFailed),
+		errors.Is(err, query.ErrValidation):
+		writeQueryError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, query.ErrNotAllowed):
+		writeQueryError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, query.ErrNotFound):
+		writeQueryError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, query.ErrConflict):
+		writeQueryError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, query.ErrTimeout):
+		writeQueryError(w, err.Error(), http.StatusGatewayTimeout)
+	default:
+		log.Printf("query failed: %v", err)
+		writeQueryError(w, "Query execution failed", http.StatusInternalServerError)
+	}
+}
+
+func writeQueryError(w http.ResponseWriter, message string, status int) {
+	writeJSON(w, status, models.QueryResponse{Success: false, Error: message})
+}
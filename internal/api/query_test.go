@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/query"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+func TestRunQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	handler := RunQuery(query.NewRunner(sqlx.NewDb(mockDB, "sqlmock"), query.Limits{
+		Timeout:  time.Second,
+		MaxRows:  100,
+		MaxBytes: 1 << 20,
+	}))
+	now := time.Now()
+
+	// created_at used to be rejected for containing "create"
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name, created_at FROM modules WHERE name = \$1`).WithArgs("dashboard").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "created_at"}).AddRow("dashboard", now))
+	mock.ExpectRollback()
+	mock.ExpectExec(`INSERT INTO query_audit`).
+		WithArgs("alice", nil, sqlmock.AnyArg(), `["dashboard"]`, true, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1), true, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/query",
+		strings.NewReader(`{"query":"SELECT name, created_at FROM modules WHERE name = $1","params":["dashboard"]}`))
+	req = req.WithContext(users.WithUser(req.Context(), &users.User{Username: "alice", Role: users.RoleAdmin}))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, true, body["success"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "name", "type": ""},
+		map[string]interface{}{"name": "created_at", "type": ""},
+	}, body["columns"])
+	assert.Equal(t, float64(1), body["rows"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name, port FROM modules`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "port"}).AddRow("dashboard", 8080))
+	mock.ExpectRollback()
+	mock.ExpectExec(`INSERT INTO query_audit`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req = httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"query":"SELECT name, port FROM modules"}`))
+	req.Header.Set("Accept", "text/csv")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	res := rec.Result()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+	assert.Equal(t, `[{"name":"name","type":""},{"name":"port","type":""}]`, res.Header.Get("X-Query-Columns"))
+	assert.Equal(t, "name,port\ndashboard,8080\n", rec.Body.String())
+	assert.Equal(t, "1", res.Trailer.Get("X-Query-Rows"))
+	assert.Equal(t, "false", res.Trailer.Get("X-Query-Truncated"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(`{"query":"SELECT 1"}`))
+	req.Header.Set("Accept", "application/xml")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+
+	// Rejected statements are audited as well
+	for _, tt := range []struct {
+		name     string
+		body     string
+		byKey    bool
+		readOnly bool
+		code     int
+	}{
+		{"not allowed", `{"query":"DROP TABLE modules"}`, false, false, http.StatusForbidden},
+		{"protected table", `{"query":"DELETE FROM query_audit WHERE true"}`, false, false, http.StatusForbidden},
+		{"syntax error", `{"query":"SELEC 1"}`, false, false, http.StatusBadRequest},
+		{"missing params", `{"query":"SELECT * FROM modules WHERE name = $1"}`, false, true, http.StatusBadRequest},
+		{"write with API key", `{"query":"DELETE FROM modules WHERE name = 'x'"}`, true, false, http.StatusForbidden},
+	} {
+		mock.ExpectExec(`INSERT INTO query_audit`).WithArgs(sqlmock.AnyArg(), nil, sqlmock.AnyArg(), "[]",
+			tt.readOnly, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(0), false, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/query", strings.NewReader(tt.body))
+		if tt.byKey {
+			req = req.WithContext(apikeys.WithKey(req.Context(), &apikeys.Key{Name: "ci"}))
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		assert.Equal(t, tt.code, rec.Code, tt.name)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
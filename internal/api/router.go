@@ -1,7 +1,17 @@
 package api
 
 import (
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/auth"
+	"github.com/The-OpenPlatform/backend/internal/ca"
+	"github.com/The-OpenPlatform/backend/internal/config"
 	"github.com/The-OpenPlatform/backend/internal/db"
+	"github.com/The-OpenPlatform/backend/internal/lifecycle"
+	"github.com/The-OpenPlatform/backend/internal/oidc"
+	"github.com/The-OpenPlatform/backend/internal/query"
+	"github.com/The-OpenPlatform/backend/internal/service"
+	"github.com/The-OpenPlatform/backend/internal/users"
+	"net"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -9,25 +19,121 @@ import (
 	"github.com/go-chi/cors"
 )
 
-func SetupRouter() http.Handler {
+// SetupRouter builds the REST API. issuer and provider are optional and
+// enable the certificate and OIDC endpoints.
+func SetupRouter(cfg *config.Config, ready *lifecycle.Readiness, issuer *ca.Issuer, provider *oidc.Provider) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
+	var moduleOpts []service.Option
+	if cfg.Modules.ResolveHosts {
+		moduleOpts = append(moduleOpts, service.WithResolver(net.DefaultResolver))
+	}
+
+	modules := service.NewModules(db.DB, moduleOpts...)
+	accounts := users.NewStore(db.DB)
+	keys := apikeys.NewStore(db.DB)
+
+	// A nil *oidc.Provider must not become a non-nil interface
+	var bearer bearerVerifier
+	if provider != nil {
+		bearer = provider
+	}
+
 	r.Route("/api", func(r chi.Router) {
+		r.Use(authenticate(accounts, keys, cfg.Auth.AdminToken, bearer))
+
 		r.Get("/", rootHandler)
 		r.Get("/hello", helloHandler)
 		r.Get("/status", getStatus)
+		r.Get("/healthz", healthzHandler)
+		r.Get("/readyz", readyzHandler(ready, db.DB))
 		r.Get("/modules", GetModulesWithImages(db.DB))
+		r.Get("/modules/graph", GetModuleGraph(modules))
+		r.Get("/modules/readiness", GetModuleReadiness(modules))
+		r.Get("/modules/{id}", GetModule(modules, db.DB))
+		r.Get("/modules/{id}/image", GetModuleImage(db.DB))
+		r.Get("/modules/{id}/instances", ListModuleInstances(modules))
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireAccess(writeModules))
+			r.Post("/modules", CreateModule(modules))
+			r.Patch("/modules/{id}", UpdateModule(modules))
+			r.Delete("/modules/{id}", DeleteModule(modules))
+			r.Put("/modules/{id}/image", PutModuleImage(modules))
+			r.Delete("/modules/{id}/instances/{instanceID}", DeleteModuleInstance(modules))
+		})
+
+		r.With(requireAccessByMethod(readModules, writeModules)).
+			Handle("/modules/{id}/proxy/*", NewModuleProxy(db.DB, cfg.Modules.ProxyTimeout))
+
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/login", Login(accounts, cfg.Auth.SessionTTL, cfg.Auth.CookieSecure))
+			r.Post("/logout", Logout(accounts, cfg.Auth.CookieSecure))
+			r.With(requireRole(users.RoleViewer)).Get("/me", CurrentUser)
+
+			if provider != nil {
+				r.Get("/oidc/login", OIDCLogin(provider, cfg.Auth.CookieSecure))
+				r.Get("/oidc/callback", OIDCCallback(provider, accounts, cfg.Auth.SessionTTL, cfg.OIDC.PostLoginRedirect, cfg.Auth.CookieSecure))
+			}
+		})
+
+		r.Route("/users", func(r chi.Router) {
+			r.Use(requireRole(users.RoleAdmin))
+			r.Get("/", ListUsers(accounts))
+			r.Post("/", CreateUser(accounts))
+			r.Patch("/{id}", UpdateUser(accounts))
+			r.Delete("/{id}", DeleteUser(accounts))
+		})
+
+		tokens := auth.NewStore(db.DB)
+		r.Route("/join-tokens", func(r chi.Router) {
+			r.Use(requireRole(users.RoleAdmin))
+			r.Post("/", CreateJoinToken(tokens, cfg.Auth.JoinTokenTTL))
+			r.Get("/", ListJoinTokens(tokens))
+			r.Delete("/{id}", RevokeJoinToken(tokens))
+		})
+
+		runner := query.NewRunner(db.DB, query.Limits{
+			Timeout:  cfg.Query.Timeout,
+			MaxRows:  cfg.Query.MaxRows,
+			MaxBytes: cfg.Query.MaxBytes,
+		})
+		r.With(requireAccess(runQueries)).Post("/query", RunQuery(runner))
+
+		queries := query.NewStore(db.DB)
+		r.Route("/queries", func(r chi.Router) {
+			r.With(requireAccess(runQueries)).Get("/", ListSavedQueries(queries))
+			r.With(requireAccess(runQueries)).Post("/{name}/run", RunSavedQuery(queries, runner))
+			r.With(requireRole(users.RoleAdmin)).Post("/", CreateSavedQuery(queries))
+			r.With(requireRole(users.RoleAdmin)).Delete("/{name}", DeleteSavedQuery(queries))
+		})
+		r.With(requireRole(users.RoleAdmin)).Get("/query-audit", ListQueryAudit(queries))
+
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Use(requireRole(users.RoleAdmin))
+			r.Post("/", CreateAPIKey(keys))
+			r.Get("/", ListAPIKeys(keys))
+			r.Delete("/{id}", RevokeAPIKey(keys))
+		})
+
+		if issuer != nil {
+			r.Route("/certificates", func(r chi.Router) {
+				r.Use(requireRole(users.RoleAdmin))
+				r.Get("/", ListCertificates(issuer))
+				r.Delete("/{serial}", RevokeCertificate(issuer))
+			})
+		}
 	})
 
 	return r
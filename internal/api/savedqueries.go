@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/The-OpenPlatform/backend/internal/models"
+	"github.com/The-OpenPlatform/backend/internal/query"
+)
+
+// CreateSavedQuery saves a named statement. The statement is checked like
+// an ad-hoc query but not run.
+func CreateSavedQuery(store *query.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.SavedQueryRequest
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			writeQueryError(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		saved, err := store.Create(r.Context(), query.SaveInput{
+			Name:        req.Name,
+			Description: req.Description,
+			SQL:         req.Query,
+			Params:      req.Params,
+			CreatedBy:   principal(r.Context()),
+		})
+		if err != nil {
+			writeQueryResultError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, saved)
+	}
+}
+
+// ListSavedQueries returns all saved queries.
+func ListSavedQueries(store *query.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		saved, err := store.List(r.Context())
+		if err != nil {
+			writeQueryResultError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, saved)
+	}
+}
+
+// DeleteSavedQuery removes a saved query.
+func DeleteSavedQuery(store *query.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := store.Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+			writeQueryResultError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RunSavedQuery runs a saved query like POST /api/query. The optional body
+// may pass params; without them the saved defaults are used.
+func RunSavedQuery(store *query.Store, runner *query.Runner) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := negotiateQueryFormat(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.QueryRequest
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeQueryError(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Query != "" {
+			writeQueryError(w, "the statement of a saved query cannot be replaced", http.StatusBadRequest)
+			return
+		}
+
+		saved, err := store.Get(r.Context(), chi.URLParam(r, "name"))
+		if err != nil {
+			writeQueryResultError(w, err)
+			return
+		}
+
+		stmt, err := saved.Statement()
+		if err != nil {
+			rejectStatement(w, r, runner, &query.Statement{SQL: saved.SQL, Name: saved.Name}, req.Params, err)
+			return
+		}
+
+		params := req.Params
+		if params == nil {
+			if params, err = saved.Defaults(); err != nil {
+				writeQueryResultError(w, err)
+				return
+			}
+		}
+
+		runStatement(w, r, runner, format, stmt, params)
+	}
+}
+
+// ListQueryAudit returns the query audit log, newest first. It is filtered
+// by the actor and name query parameters and paged with limit and before;
+// the Link header points at the next page.
+func ListQueryAudit(store *query.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := query.AuditFilter{
+			Actor:     q.Get("actor"),
+			QueryName: q.Get("name"),
+			Limit:     defaultPageSize,
+		}
+
+		if v := q.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxPageSize {
+				http.Error(w, fmt.Sprintf("invalid limit (must be 1-%d)", maxPageSize), http.StatusBadRequest)
+				return
+			}
+			f.Limit = limit
+		}
+
+		if v := q.Get("before"); v != "" {
+			before, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || before < 1 {
+				http.Error(w, "invalid before (must be an audit ID)", http.StatusBadRequest)
+				return
+			}
+			f.Before = before
+		}
+
+		entries, err := store.Audit(r.Context(), f)
+		if err != nil {
+			writeQueryResultError(w, err)
+			return
+		}
+
+		if len(entries) == f.Limit {
+			q.Set("before", strconv.FormatInt(entries[len(entries)-1].AuditID, 10))
+			next := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+		}
+
+		writeJSON(w, http.StatusOK, entries)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/query"
+)
+
+var savedQueryColumns = []string{"query_id", "name", "description", "sql", "params", "created_by", "created_at", "updated_at"}
+
+func TestRunSavedQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	store := query.NewStore(sqlxDB)
+	runner := query.NewRunner(sqlxDB, query.Limits{Timeout: time.Second, MaxRows: 100, MaxBytes: 1 << 20})
+
+	r := chi.NewRouter()
+	r.Post("/api/queries/{name}/run", RunSavedQuery(store, runner))
+
+	const sql = "SELECT name FROM modules WHERE port > $1"
+	now := time.Now()
+	expectSaved := func() {
+		mock.ExpectQuery(`SELECT .* FROM saved_queries WHERE name = \$1`).WithArgs("high-ports").
+			WillReturnRows(sqlmock.NewRows(savedQueryColumns).AddRow("q1", "high-ports", "", sql, []byte(`[8000]`), "alice", now, now))
+	}
+
+	// Without a body the defaults are used
+	expectSaved()
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name FROM modules`).WithArgs("8000").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("dashboard"))
+	mock.ExpectRollback()
+	mock.ExpectExec(`INSERT INTO query_audit`).
+		WithArgs("api-key:ci", "high-ports", sql, `[8000]`, true, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1), true, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/queries/high-ports/run", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	req = req.WithContext(apikeys.WithKey(req.Context(), &apikeys.Key{Name: "ci"}))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "{\"name\":\"dashboard\"}\n", rec.Body.String())
+
+	// Params in the body replace the defaults
+	expectSaved()
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name FROM modules`).WithArgs("9000").WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectRollback()
+	mock.ExpectExec(`INSERT INTO query_audit`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/queries/high-ports/run", strings.NewReader(`{"params":[9000]}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/queries/high-ports/run", strings.NewReader(`{"query":"SELECT 1"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	mock.ExpectQuery(`SELECT .* FROM saved_queries`).WithArgs("missing").WillReturnRows(sqlmock.NewRows(savedQueryColumns))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/queries/missing/run", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateSavedQuery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	handler := CreateSavedQuery(query.NewStore(sqlx.NewDb(mockDB, "sqlmock")))
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO saved_queries`).
+		WithArgs("offline", "Modules that stopped reporting", "SELECT name FROM modules WHERE status = 'OFFLINE'", "[]", "").
+		WillReturnRows(sqlmock.NewRows(savedQueryColumns).
+			AddRow("q1", "offline", "Modules that stopped reporting", "SELECT name FROM modules WHERE status = 'OFFLINE'", []byte(`[]`), "", now, now))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/queries", strings.NewReader(
+		`{"name":"offline","description":"Modules that stopped reporting","query":"SELECT name FROM modules WHERE status = 'OFFLINE'"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "offline", body["name"])
+	assert.Equal(t, []interface{}{}, body["params"])
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/queries", strings.NewReader(`{"name":"drop","query":"DROP TABLE modules"}`)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListQueryAudit(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	handler := ListQueryAudit(query.NewStore(sqlx.NewDb(mockDB, "sqlmock")))
+	columns := []string{"audit_id", "actor", "query_name", "sql", "params", "read_only", "started_at", "duration_ms", "rows", "success", "error"}
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT .* FROM query_audit`).WithArgs("alice", "", int64(0), 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "alice", nil, "SELECT 1", []byte(`[]`), true, now, 2, 1, true, ""))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/query-audit?actor=alice&limit=1", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `</api/query-audit?actor=alice&before=7&limit=1>; rel="next"`, rec.Header().Get("Link"))
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/query-audit?before=x", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
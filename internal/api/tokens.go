@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/The-OpenPlatform/backend/internal/auth"
+)
+
+// joinTokenRequest is the body of POST /api/join-tokens.
+type joinTokenRequest struct {
+	Description string `json:"description"`
+	ModuleName  string `json:"module_name"`
+	MaxUses     int    `json:"max_uses"`
+	// TTL is a Go duration such as "1h"; empty uses the configured default.
+	TTL string `json:"ttl"`
+}
+
+// joinTokenResponse carries the secret of a newly minted token, which is
+// never shown again.
+type joinTokenResponse struct {
+	Token string `json:"token"`
+	*auth.JoinToken
+}
+
+// CreateJoinToken mints a join token that modules present when registering.
+func CreateJoinToken(store *auth.Store, defaultTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req joinTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ttl := defaultTTL
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+				http.Error(w, "Validation failed: ttl must be a positive duration", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if req.MaxUses < 0 {
+			http.Error(w, "Validation failed: max_uses cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		secret, token, err := store.MintJoinToken(r.Context(), auth.MintInput{
+			Description: req.Description,
+			ModuleName:  req.ModuleName,
+			MaxUses:     req.MaxUses,
+			TTL:         ttl,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, joinTokenResponse{Token: secret, JoinToken: token})
+	}
+}
+
+// ListJoinTokens returns all join tokens without their secrets.
+func ListJoinTokens(store *auth.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := store.ListJoinTokens(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tokens)
+	}
+}
+
+// RevokeJoinToken prevents further registrations with a join token.
+// Modules that already registered with it keep their credentials.
+func RevokeJoinToken(store *auth.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := store.RevokeJoinToken(r.Context(), chi.URLParam(r, "id"))
+		switch {
+		case errors.Is(err, auth.ErrNotFound):
+			http.Error(w, "Join token not found", http.StatusNotFound)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
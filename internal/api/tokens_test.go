@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/auth"
+)
+
+func TestCreateJoinToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	store := auth.NewStore(sqlx.NewDb(mockDB, "sqlmock"))
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO join_tokens`).
+		WithArgs(sqlmock.AnyArg(), "ci", nil, nil, int64(7200)).
+		WillReturnRows(sqlmock.NewRows([]string{"token_id", "description", "module_name", "max_uses", "uses", "expires_at", "revoked_at", "created_at"}).
+			AddRow("t1", "ci", nil, nil, 0, now.Add(2*time.Hour), nil, now))
+
+	rec := httptest.NewRecorder()
+	CreateJoinToken(store, time.Hour)(rec, httptest.NewRequest(http.MethodPost, "/api/join-tokens",
+		strings.NewReader(`{"description":"ci","ttl":"2h"}`)))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "t1", body["token_id"])
+	assert.True(t, strings.HasPrefix(body["token"].(string), "opj_"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	for _, invalid := range []string{`{"ttl":"-1h"}`, `{"ttl":"soon"}`, `{"max_uses":-1}`, `not json`} {
+		rec := httptest.NewRecorder()
+		CreateJoinToken(store, time.Hour)(rec, httptest.NewRequest(http.MethodPost, "/api/join-tokens", strings.NewReader(invalid)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, invalid)
+	}
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/oidc"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+// sessionCookie carries the session token for browser clients.
+const sessionCookie = "openplatform_session"
+
+// apiKeyHeader carries the API key of machine clients.
+const apiKeyHeader = "X-API-Key"
+
+// adminTokenUser is the principal of requests authenticated with the static
+// admin token.
+var adminTokenUser = &users.User{Username: "admin-token", Role: users.RoleAdmin}
+
+// loginRequest is the body of POST /api/auth/login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginResponse returns the new session token and the logged in user.
+type loginResponse struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      *users.User `json:"user"`
+}
+
+// userRequest is the body of POST and PATCH /api/users.
+type userRequest struct {
+	Username string      `json:"username"`
+	Password *string     `json:"password"`
+	Role     *users.Role `json:"role"`
+}
+
+// bearerVerifier authenticates bearer tokens issued by an identity provider.
+type bearerVerifier interface {
+	VerifyBearer(ctx context.Context, token string) (*oidc.Identity, error)
+}
+
+// keyVerifier checks API keys.
+type keyVerifier interface {
+	Verify(ctx context.Context, token string) (*apikeys.Key, error)
+}
+
+// permission is what a route requires of the caller: a user role or, for
+// API keys, a scope. Routes without a scope do not accept API keys.
+type permission struct {
+	role  users.Role
+	scope apikeys.Scope
+}
+
+var (
+	readModules  = permission{role: users.RoleViewer, scope: apikeys.ScopeModulesRead}
+	writeModules = permission{role: users.RoleOperator, scope: apikeys.ScopeModulesWrite}
+	runQueries   = permission{role: users.RoleAdmin, scope: apikeys.ScopeQueryRead}
+)
+
+// authenticate identifies the caller from an API key, a bearer token or the
+// session cookie and stores the key or user in the request context. The
+// static admin token, when configured, authenticates as an admin, and
+// tokens that are not session tokens are passed to bearer when it is set.
+// Requests without valid credentials continue anonymously; requireAccess
+// rejects them where needed.
+func authenticate(store *users.Store, keys keyVerifier, adminToken string, bearer bearerVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey := r.Header.Get(apiKeyHeader); apiKey != "" && keys != nil {
+				key, err := keys.Verify(r.Context(), apiKey)
+				switch {
+				case errors.Is(err, apikeys.ErrInvalidKey):
+					next.ServeHTTP(w, r)
+				case err != nil:
+					log.Printf("failed to verify API key: %v", err)
+					http.Error(w, "authentication failed", http.StatusInternalServerError)
+				default:
+					next.ServeHTTP(w, r.WithContext(apikeys.WithKey(r.Context(), key)))
+				}
+				return
+			}
+
+			token := requestToken(r)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+				next.ServeHTTP(w, r.WithContext(users.WithUser(r.Context(), adminTokenUser)))
+				return
+			}
+
+			if bearer != nil && !strings.HasPrefix(token, users.SessionPrefix) {
+				identity, err := bearer.VerifyBearer(r.Context(), token)
+				if err != nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				user := &users.User{Username: identity.Username, Role: identity.Role}
+				next.ServeHTTP(w, r.WithContext(users.WithUser(r.Context(), user)))
+				return
+			}
+
+			user, err := store.VerifySession(r.Context(), token)
+			switch {
+			case errors.Is(err, users.ErrInvalidSession):
+				next.ServeHTTP(w, r)
+			case err != nil:
+				log.Printf("failed to verify session: %v", err)
+				http.Error(w, "authentication failed", http.StatusInternalServerError)
+			default:
+				next.ServeHTTP(w, r.WithContext(users.WithUser(r.Context(), user)))
+			}
+		})
+	}
+}
+
+// requireRole admits users whose role includes role. API keys are rejected.
+func requireRole(role users.Role) func(http.Handler) http.Handler {
+	return requireAccess(permission{role: role})
+}
+
+// requireAccess rejects anonymous callers with 401 and callers that lack p
+// with 403.
+func requireAccess(p permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := apikeys.FromContext(r.Context()); ok {
+				if p.scope == "" {
+					http.Error(w, "forbidden: API keys cannot be used here", http.StatusForbidden)
+					return
+				}
+
+				if !key.Allows(p.scope) {
+					http.Error(w, "forbidden: requires the "+string(p.scope)+" scope", http.StatusForbidden)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, ok := users.FromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="openplatform"`)
+				http.Error(w, "authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			if !user.Role.Allows(p.role) {
+				http.Error(w, "forbidden: requires the "+string(p.role)+" role", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireAccessByMethod requires read for safe methods and write for all others.
+func requireAccessByMethod(read, write permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		readHandler, writeHandler := requireAccess(read)(next), requireAccess(write)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				readHandler.ServeHTTP(w, r)
+			default:
+				writeHandler.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// principal names the authenticated user or API key, or returns "" for
+// anonymous requests.
+func principal(ctx context.Context) string {
+	if key, ok := apikeys.FromContext(ctx); ok {
+		return "api-key:" + key.Name
+	}
+
+	if user, ok := users.FromContext(ctx); ok {
+		return user.Username
+	}
+
+	return ""
+}
+
+// requestToken returns the bearer token or, failing that, the session cookie.
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// Login checks a username and password and starts a session. The token is
+// returned in the body for API clients and set as a cookie for browsers.
+func Login(store *users.Store, ttl time.Duration, secure bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req loginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := store.Authenticate(r.Context(), req.Username, req.Password)
+		switch {
+		case errors.Is(err, users.ErrInvalidLogin):
+			http.Error(w, "invalid username or password", http.StatusUnauthorized)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		token, expiresAt, err := store.CreateSession(r.Context(), user.UserID, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		setSessionCookie(w, token, expiresAt, secure)
+		writeJSON(w, http.StatusOK, loginResponse{Token: token, ExpiresAt: expiresAt, User: user})
+	}
+}
+
+// setSessionCookie hands a session token to a browser. secure is configured
+// rather than derived from the request, which is plain HTTP behind a
+// TLS-terminating proxy.
+func setSessionCookie(w http.ResponseWriter, token string, expiresAt time.Time, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/api",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Logout ends the caller's session and clears the cookie.
+func Logout(store *users.Store, secure bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := store.EndSession(r.Context(), requestToken(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/api", MaxAge: -1, HttpOnly: true, Secure: secure})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CurrentUser returns the authenticated user.
+func CurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, _ := users.FromContext(r.Context())
+	writeJSON(w, http.StatusOK, user)
+}
+
+// ListUsers returns all users.
+func ListUsers(store *users.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := store.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+// CreateUser adds a local account.
+func CreateUser(store *users.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req userRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.Password == nil || req.Role == nil {
+			http.Error(w, "Validation failed: username, password and role are required", http.StatusBadRequest)
+			return
+		}
+
+		user, err := store.Create(r.Context(), req.Username, *req.Password, *req.Role)
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, user)
+	}
+}
+
+// UpdateUser changes a user's role or password.
+func UpdateUser(store *users.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req userRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := store.Update(r.Context(), chi.URLParam(r, "id"), users.Update{Role: req.Role, Password: req.Password})
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, user)
+	}
+}
+
+// DeleteUser removes a user and ends its sessions.
+func DeleteUser(store *users.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := store.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeUserError maps user store errors to HTTP responses.
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, users.ErrValidation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, users.ErrNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, users.ErrConflict):
+		http.Error(w, "User with the same username already exists", http.StatusConflict)
+	case errors.Is(err, users.ErrLastAdmin):
+		http.Error(w, "The last admin cannot be demoted or deleted", http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+func newUserStore(t *testing.T) (*users.Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return users.NewStore(sqlx.NewDb(mockDB, "sqlmock")), mock
+}
+
+func TestRequireRole(t *testing.T) {
+	store, mock := newUserStore(t)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	handler := authenticate(store, nil, "s3cret", nil)(requireRole(users.RoleOperator)(ok))
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM user_sessions`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "role", "created_at", "updated_at"}).AddRow("u1", "alice", "viewer", now, now))
+	mock.ExpectQuery(`FROM user_sessions`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "role", "created_at", "updated_at"}))
+
+	for _, tt := range []struct {
+		name, header string
+		code         int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"admin token", "Bearer s3cret", http.StatusNoContent},
+		{"viewer session", "Bearer ops_viewer", http.StatusForbidden},
+		{"unknown session", "Bearer ops_unknown", http.StatusUnauthorized},
+		{"wrong admin token", "Bearer wrong", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/modules", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tt.code, rec.Code, tt.name)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRequireByMethod(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	handler := requireAccessByMethod(readModules, writeModules)(ok)
+	viewer := &users.User{Username: "alice", Role: users.RoleViewer}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(users.WithUser(req.Context(), viewer)))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(users.WithUser(req.Context(), viewer)))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestLogin(t *testing.T) {
+	store, mock := newUserStore(t)
+	now := time.Now()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	mock.ExpectQuery(`FROM users WHERE username`).WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "role", "created_at", "updated_at", "password_hash"}).
+			AddRow("u1", "alice", "operator", now, now, string(hashed)))
+	mock.ExpectQuery(`INSERT INTO user_sessions`).WithArgs(sqlmock.AnyArg(), "u1", int64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at"}).AddRow(now.Add(time.Hour)))
+
+	rec := httptest.NewRecorder()
+	Login(store, time.Hour, true)(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		strings.NewReader(`{"username":"alice","password":"password1"}`)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body loginResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.True(t, strings.HasPrefix(body.Token, "ops_"))
+	assert.Equal(t, users.RoleOperator, body.User.Role)
+
+	cookie := rec.Result().Cookies()[0]
+	assert.Equal(t, sessionCookie, cookie.Name)
+	assert.Equal(t, body.Token, cookie.Value)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure, "secure is configured, not taken from the plain HTTP request")
+
+	mock.ExpectQuery(`FROM users WHERE username`).WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	rec = httptest.NewRecorder()
+	Login(store, time.Hour, true)(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login",
+		strings.NewReader(`{"username":"alice","password":"nope"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,224 @@
+// Package apikeys manages API keys, the credentials of CI jobs, scripts and
+// other machine clients of the REST and gRPC APIs.
+//
+// A key grants a fixed set of scopes instead of a user role. Keys are
+// random secrets of which only SHA-256 hashes are stored; the secret is
+// shown once, when the key is created. Every successful verification
+// records the time the key was last used.
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/The-OpenPlatform/backend/internal/secrets"
+)
+
+// Scope grants access to a group of operations.
+type Scope string
+
+const (
+	// ScopeModulesRead allows reading modules and read-only proxy requests.
+	ScopeModulesRead Scope = "modules:read"
+	// ScopeModulesWrite allows creating, updating and deleting modules and
+	// any proxy request. It includes modules:read.
+	ScopeModulesWrite Scope = "modules:write"
+	// ScopeQueryRead allows running read-only database queries.
+	ScopeQueryRead Scope = "query:read"
+)
+
+// implied lists the scopes included in another scope.
+var implied = map[Scope][]Scope{
+	ScopeModulesWrite: {ScopeModulesRead},
+}
+
+// Valid reports whether s is a known scope.
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeModulesRead, ScopeModulesWrite, ScopeQueryRead:
+		return true
+	}
+	return false
+}
+
+const (
+	keyPrefix    = "opk_"
+	maxNameBytes = 100
+)
+
+var (
+	// ErrValidation is returned for invalid names, scopes or lifetimes.
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidKey is returned for unknown, expired and revoked keys alike.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrNotFound is returned when a key does not exist.
+	ErrNotFound = errors.New("API key not found")
+)
+
+// Key describes an API key. The secret itself is only returned once, by
+// Create.
+type Key struct {
+	KeyID      string         `db:"key_id" json:"key_id"`
+	Name       string         `db:"name" json:"name"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	CreatedBy  string         `db:"created_by" json:"created_by"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// Allows reports whether the key was granted scope, directly or through a
+// scope that includes it.
+func (k *Key) Allows(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if Scope(granted) == scope || slices.Contains(implied[Scope(granted)], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateInput describes a new key.
+type CreateInput struct {
+	Name   string
+	Scopes []Scope
+	// TTL limits the lifetime of the key; 0 means it does not expire.
+	TTL time.Duration
+	// CreatedBy names the user who created the key.
+	CreatedBy string
+}
+
+const keyColumns = `key_id, name, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+// Store persists API keys.
+type Store struct {
+	db *sqlx.DB
+}
+
+// NewStore creates a store backed by the given database.
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create generates a key and returns its secret together with its
+// description.
+func (s *Store) Create(ctx context.Context, in CreateInput) (string, *Key, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > maxNameBytes {
+		return "", nil, fmt.Errorf("%w: name is required and must be at most %d bytes", ErrValidation, maxNameBytes)
+	}
+
+	if len(in.Scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", ErrValidation)
+	}
+
+	scopes := make([]string, 0, len(in.Scopes))
+	for _, scope := range in.Scopes {
+		if !scope.Valid() {
+			return "", nil, fmt.Errorf("%w: unknown scope %q", ErrValidation, scope)
+		}
+		if !slices.Contains(scopes, string(scope)) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	if in.TTL < 0 {
+		return "", nil, fmt.Errorf("%w: ttl cannot be negative", ErrValidation)
+	}
+
+	var ttl *int64
+	if in.TTL > 0 {
+		seconds := int64(in.TTL.Seconds())
+		ttl = &seconds
+	}
+
+	secret, err := secrets.New()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var key Key
+	query := `INSERT INTO api_keys (key_hash, name, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + $5 * INTERVAL '1 second')
+		RETURNING ` + keyColumns
+
+	if err := s.db.GetContext(ctx, &key, query, secrets.Hash(secret), name, pq.StringArray(scopes), in.CreatedBy, ttl); err != nil {
+		return "", nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return keyPrefix + secret, &key, nil
+}
+
+// List returns all keys, newest first.
+func (s *Store) List(ctx context.Context) ([]Key, error) {
+	keys := []Key{}
+	query := `SELECT ` + keyColumns + ` FROM api_keys ORDER BY created_at DESC`
+
+	if err := s.db.SelectContext(ctx, &keys, query); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke prevents any further use of a key.
+func (s *Store) Revoke(ctx context.Context, keyID string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE key_id::text = $1`, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Verify checks a key, records its use and returns it.
+func (s *Store) Verify(ctx context.Context, token string) (*Key, error) {
+	secret, ok := strings.CutPrefix(token, keyPrefix)
+	if !ok || secret == "" {
+		return nil, ErrInvalidKey
+	}
+
+	var key Key
+	query := `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE key_hash = $1
+		AND revoked_at IS NULL
+		AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		RETURNING ` + keyColumns
+
+	err := s.db.GetContext(ctx, &key, query, secrets.Hash(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+type keyKey struct{}
+
+// WithKey returns a context carrying the key that authenticated a request.
+func WithKey(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// FromContext returns the key stored by WithKey.
+func FromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(keyKey{}).(*Key)
+	return key, ok
+}
@@ -0,0 +1,110 @@
+package apikeys
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/secrets"
+)
+
+func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewStore(sqlx.NewDb(mockDB, "sqlmock")), mock
+}
+
+var keyColumnNames = []string{"key_id", "name", "scopes", "created_by", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+func TestKeyAllows(t *testing.T) {
+	key := &Key{Scopes: pq.StringArray{"modules:write"}}
+	assert.True(t, key.Allows(ScopeModulesWrite))
+	assert.True(t, key.Allows(ScopeModulesRead))
+	assert.False(t, key.Allows(ScopeQueryRead))
+
+	key = &Key{Scopes: pq.StringArray{"modules:read"}}
+	assert.False(t, key.Allows(ScopeModulesWrite))
+}
+
+func TestCreate(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	for name, in := range map[string]CreateInput{
+		"no name":       {Scopes: []Scope{ScopeModulesRead}},
+		"no scopes":     {Name: "ci"},
+		"unknown scope": {Name: "ci", Scopes: []Scope{"modules:admin"}},
+		"negative ttl":  {Name: "ci", Scopes: []Scope{ScopeModulesRead}, TTL: -time.Hour},
+		"name too long": {Name: strings.Repeat("x", 101), Scopes: []Scope{ScopeModulesRead}},
+		"blank name":    {Name: "  ", Scopes: []Scope{ScopeModulesRead}},
+	} {
+		_, _, err := store.Create(ctx, in)
+		assert.ErrorIs(t, err, ErrValidation, name)
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(sqlmock.AnyArg(), "ci", pq.StringArray{"modules:read", "query:read"}, "alice", nil).
+		WillReturnRows(sqlmock.NewRows(keyColumnNames).AddRow("k1", "ci", "{modules:read,query:read}", "alice", nil, nil, nil, now))
+
+	secret, key, err := store.Create(ctx, CreateInput{
+		Name:      "ci",
+		Scopes:    []Scope{ScopeModulesRead, ScopeQueryRead, ScopeModulesRead},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, keyPrefix))
+	assert.Equal(t, pq.StringArray{"modules:read", "query:read"}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerify(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	_, err := store.Verify(ctx, "opm_not-a-key")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP`).
+		WithArgs(secrets.Hash("secret")).
+		WillReturnRows(sqlmock.NewRows(keyColumnNames).AddRow("k1", "ci", "{modules:read}", "alice", nil, now, nil, now))
+
+	key, err := store.Verify(ctx, keyPrefix+"secret")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", key.KeyID)
+	require.NotNil(t, key.LastUsedAt)
+
+	// Unknown, expired and revoked keys match no row
+	mock.ExpectQuery(`UPDATE api_keys`).WithArgs(secrets.Hash("revoked")).WillReturnRows(sqlmock.NewRows(keyColumnNames))
+	_, err = store.Verify(ctx, keyPrefix+"revoked")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevoke(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at`).WithArgs("k1").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.Revoke(ctx, "k1"))
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at`).WithArgs("missing").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, store.Revoke(ctx, "missing"), ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
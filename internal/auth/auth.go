@@ -0,0 +1,220 @@
+// Package auth authenticates modules.
+//
+// Administrators mint join tokens that admit new modules to the platform. A
+// module presents a join token once, when it registers, and receives a
+// per-module credential that authenticates all of its later calls. Tokens and
+// credentials are random secrets of which only SHA-256 hashes are stored.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/The-OpenPlatform/backend/internal/secrets"
+)
+
+const (
+	joinTokenPrefix  = "opj_"
+	credentialPrefix = "opm_"
+)
+
+var (
+	// ErrInvalidJoinToken is returned for unknown, expired, revoked,
+	// exhausted or out-of-scope join tokens.
+	ErrInvalidJoinToken = errors.New("invalid join token")
+	// ErrInvalidCredential is returned when a module credential does not match.
+	ErrInvalidCredential = errors.New("invalid module credential")
+	// ErrNotFound is returned when a join token does not exist.
+	ErrNotFound = errors.New("join token not found")
+)
+
+// JoinToken describes a minted join token. The secret itself is only
+// returned once, by MintJoinToken.
+type JoinToken struct {
+	TokenID     string     `db:"token_id" json:"token_id"`
+	Description string     `db:"description" json:"description"`
+	ModuleName  *string    `db:"module_name" json:"module_name,omitempty"`
+	MaxUses     *int       `db:"max_uses" json:"max_uses,omitempty"`
+	Uses        int        `db:"uses" json:"uses"`
+	ExpiresAt   time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt   *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// MintInput describes the scope of a new join token.
+type MintInput struct {
+	Description string
+	// ModuleName, when set, restricts the token to registering that name.
+	ModuleName string
+	// MaxUses limits how many modules may register with the token; 0 means unlimited.
+	MaxUses int
+	TTL     time.Duration
+}
+
+// Store persists join tokens and module credentials.
+type Store struct {
+	db *sqlx.DB
+}
+
+// NewStore creates a store backed by the given database.
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// MintJoinToken creates a join token and returns its secret together with
+// its description.
+func (s *Store) MintJoinToken(ctx context.Context, in MintInput) (string, *JoinToken, error) {
+	if in.TTL <= 0 {
+		return "", nil, fmt.Errorf("join token TTL must be positive")
+	}
+
+	if in.MaxUses < 0 {
+		return "", nil, fmt.Errorf("join token max uses cannot be negative")
+	}
+
+	secret, err := secrets.New()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var moduleName *string
+	if in.ModuleName != "" {
+		moduleName = &in.ModuleName
+	}
+
+	var maxUses *int
+	if in.MaxUses > 0 {
+		maxUses = &in.MaxUses
+	}
+
+	var token JoinToken
+	query := `INSERT INTO join_tokens (token_hash, description, module_name, max_uses, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + $5 * INTERVAL '1 second')
+		RETURNING token_id, description, module_name, max_uses, uses, expires_at, revoked_at, created_at`
+
+	if err := s.db.GetContext(ctx, &token, query, secrets.Hash(secret), in.Description, moduleName, maxUses, int64(in.TTL.Seconds())); err != nil {
+		return "", nil, fmt.Errorf("failed to create join token: %w", err)
+	}
+
+	return joinTokenPrefix + secret, &token, nil
+}
+
+// ListJoinTokens returns all join tokens, newest first.
+func (s *Store) ListJoinTokens(ctx context.Context) ([]JoinToken, error) {
+	tokens := []JoinToken{}
+	query := `SELECT token_id, description, module_name, max_uses, uses, expires_at, revoked_at, created_at
+		FROM join_tokens ORDER BY created_at DESC`
+
+	if err := s.db.SelectContext(ctx, &tokens, query); err != nil {
+		return nil, fmt.Errorf("failed to list join tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeJoinToken prevents any further use of a join token.
+func (s *Store) RevokeJoinToken(ctx context.Context, tokenID string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE join_tokens SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE token_id::text = $1`, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke join token: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ConsumeJoinToken atomically uses up one registration of a join token for
+// the given module name and returns the token's ID.
+func (s *Store) ConsumeJoinToken(ctx context.Context, token, moduleName string) (string, error) {
+	secret, ok := strings.CutPrefix(token, joinTokenPrefix)
+	if !ok || secret == "" {
+		return "", ErrInvalidJoinToken
+	}
+
+	var tokenID string
+	query := `UPDATE join_tokens SET uses = uses + 1
+		WHERE token_hash = $1
+		AND revoked_at IS NULL
+		AND expires_at > CURRENT_TIMESTAMP
+		AND (max_uses IS NULL OR uses < max_uses)
+		AND (module_name IS NULL OR module_name = $2)
+		RETURNING token_id`
+
+	err := s.db.GetContext(ctx, &tokenID, query, secrets.Hash(secret), moduleName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidJoinToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to consume join token: %w", err)
+	}
+
+	return tokenID, nil
+}
+
+// ReleaseJoinToken gives back a use consumed by a registration that failed.
+func (s *Store) ReleaseJoinToken(ctx context.Context, tokenID string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE join_tokens SET uses = uses - 1 WHERE token_id = $1 AND uses > 0`, tokenID); err != nil {
+		return fmt.Errorf("failed to release join token: %w", err)
+	}
+	return nil
+}
+
+// IssueCredential creates a new credential for a module, replacing any
+// previous one, and returns it. The credential embeds the module ID so it
+// identifies the module on its own.
+func (s *Store) IssueCredential(ctx context.Context, moduleID string) (string, error) {
+	secret, err := secrets.New()
+	if err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO module_credentials (module_id, secret_hash) VALUES ($1, $2)
+		ON CONFLICT (module_id) DO UPDATE SET secret_hash = EXCLUDED.secret_hash, created_at = CURRENT_TIMESTAMP`
+
+	if _, err := s.db.ExecContext(ctx, query, moduleID, secrets.Hash(secret)); err != nil {
+		return "", fmt.Errorf("failed to store module credential: %w", err)
+	}
+
+	return credentialPrefix + moduleID + "_" + secret, nil
+}
+
+// VerifyCredential checks a module credential and returns the ID of the
+// module it belongs to.
+func (s *Store) VerifyCredential(ctx context.Context, credential string) (string, error) {
+	rest, ok := strings.CutPrefix(credential, credentialPrefix)
+	if !ok {
+		return "", ErrInvalidCredential
+	}
+
+	moduleID, secret, ok := strings.Cut(rest, "_")
+	if !ok || moduleID == "" || secret == "" {
+		return "", ErrInvalidCredential
+	}
+
+	var stored []byte
+	err := s.db.GetContext(ctx, &stored, `SELECT secret_hash FROM module_credentials WHERE module_id::text = $1`, moduleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidCredential
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load module credential: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare(stored, secrets.Hash(secret)) != 1 {
+		return "", ErrInvalidCredential
+	}
+
+	return moduleID, nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/secrets"
+)
+
+func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewStore(sqlx.NewDb(mockDB, "sqlmock")), mock
+}
+
+var tokenColumns = []string{"token_id", "description", "module_name", "max_uses", "uses", "expires_at", "revoked_at", "created_at"}
+
+func TestMintJoinToken(t *testing.T) {
+	store, mock := newTestStore(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO join_tokens`).
+		WithArgs(sqlmock.AnyArg(), "ci", "dashboard", 1, int64(3600)).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow("t1", "ci", "dashboard", 1, 0, now.Add(time.Hour), nil, now))
+
+	secret, token, err := store.MintJoinToken(context.Background(), MintInput{
+		Description: "ci",
+		ModuleName:  "dashboard",
+		MaxUses:     1,
+		TTL:         time.Hour,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, joinTokenPrefix))
+	assert.Len(t, secret, len(joinTokenPrefix)+2*secrets.Bytes)
+	assert.Equal(t, "t1", token.TokenID)
+	assert.Equal(t, "dashboard", *token.ModuleName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, _, err = store.MintJoinToken(context.Background(), MintInput{})
+	assert.Error(t, err)
+}
+
+func TestConsumeJoinToken(t *testing.T) {
+	store, mock := newTestStore(t)
+
+	mock.ExpectQuery(`UPDATE join_tokens SET uses = uses \+ 1`).
+		WithArgs(secrets.Hash("abc"), "dashboard").
+		WillReturnRows(sqlmock.NewRows([]string{"token_id"}).AddRow("t1"))
+
+	tokenID, err := store.ConsumeJoinToken(context.Background(), "opj_abc", "dashboard")
+	require.NoError(t, err)
+	assert.Equal(t, "t1", tokenID)
+
+	mock.ExpectQuery(`UPDATE join_tokens SET uses = uses \+ 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"token_id"}))
+
+	_, err = store.ConsumeJoinToken(context.Background(), "opj_expired", "dashboard")
+	assert.ErrorIs(t, err, ErrInvalidJoinToken)
+
+	_, err = store.ConsumeJoinToken(context.Background(), "not-a-token", "dashboard")
+	assert.ErrorIs(t, err, ErrInvalidJoinToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyCredential(t *testing.T) {
+	store, mock := newTestStore(t)
+	moduleID := "6f1c2d4e-0000-4000-a000-000000000001"
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT secret_hash FROM module_credentials`).
+			WithArgs(moduleID).
+			WillReturnRows(sqlmock.NewRows([]string{"secret_hash"}).AddRow(secrets.Hash("s3cret")))
+	}
+
+	got, err := store.VerifyCredential(context.Background(), "opm_"+moduleID+"_s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, moduleID, got)
+
+	_, err = store.VerifyCredential(context.Background(), "opm_"+moduleID+"_wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+
+	for _, malformed := range []string{"", "opm_", "opm_" + moduleID, "opj_" + moduleID + "_s3cret"} {
+		_, err = store.VerifyCredential(context.Background(), malformed)
+		assert.ErrorIs(t, err, ErrInvalidCredential, malformed)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIssueCredential(t *testing.T) {
+	store, mock := newTestStore(t)
+
+	mock.ExpectExec(`INSERT INTO module_credentials .* ON CONFLICT \(module_id\) DO UPDATE`).
+		WithArgs("m1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	credential, err := store.IssueCredential(context.Background(), "m1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(credential, "opm_m1_"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,223 @@
+// Package ca implements the backend's internal certificate authority.
+//
+// Modules submit a certificate signing request when they register or renew,
+// and receive a short-lived client certificate whose common name is their
+// module ID. Issued serial numbers are recorded in the database so that
+// certificates can be revoked; the gRPC server rejects revoked certificates,
+// and certificates of deleted modules, during the TLS handshake.
+package ca
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+// Names of the entries kept in the Store.
+const (
+	certEntry = "ca.crt"
+	keyEntry  = "ca.key"
+)
+
+// caValidity is the lifetime of a newly created CA certificate.
+const caValidity = 10 * 365 * 24 * time.Hour
+
+// ErrInvalidCSR is returned for requests that cannot be parsed or whose
+// signature does not verify.
+var ErrInvalidCSR = errors.New("invalid certificate signing request")
+
+// Authority signs module certificates.
+type Authority struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+}
+
+// LoadOrCreate loads the CA from store, creating and saving a new
+// self-signed CA with the given common name when the store is empty.
+func LoadOrCreate(ctx context.Context, store Store, commonName string) (*Authority, error) {
+	certPEM, certErr := store.Load(ctx, certEntry)
+	keyPEM, keyErr := store.Load(ctx, keyEntry)
+
+	switch {
+	case certErr == nil && keyErr == nil:
+		return parseAuthority(certPEM, keyPEM)
+	case errors.Is(certErr, ErrNotExist) && errors.Is(keyErr, ErrNotExist):
+		return create(ctx, store, commonName)
+	case certErr != nil && !errors.Is(certErr, ErrNotExist):
+		return nil, certErr
+	case keyErr != nil && !errors.Is(keyErr, ErrNotExist):
+		return nil, keyErr
+	default:
+		return nil, fmt.Errorf("CA store contains only one of %s and %s", certEntry, keyEntry)
+	}
+}
+
+func create(ctx context.Context, store Store, commonName string) (*Authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(caValidity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CA key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	// Save the key first: a certificate without its key would block startup
+	if err := store.Save(ctx, keyEntry, keyPEM); err != nil {
+		return nil, err
+	}
+	if err := store.Save(ctx, certEntry, certPEM); err != nil {
+		return nil, err
+	}
+
+	return parseAuthority(certPEM, keyPEM)
+}
+
+func parseAuthority(certPEM, keyPEM []byte) (*Authority, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("CA certificate is not PEM encoded")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("CA key is not PEM encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("CA key cannot sign")
+	}
+
+	return &Authority{cert: cert, certPEM: certPEM, key: key}, nil
+}
+
+// Certificate returns the CA certificate.
+func (a *Authority) Certificate() *x509.Certificate {
+	return a.cert
+}
+
+// CertificatePEM returns the PEM encoded CA certificate that clients use to
+// verify the chain.
+func (a *Authority) CertificatePEM() []byte {
+	return a.certPEM
+}
+
+// ParseCSR decodes a PEM encoded certificate signing request and verifies
+// that it is signed by the key it contains.
+func ParseCSR(csrPEM []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: expected a PEM encoded CERTIFICATE REQUEST", ErrInvalidCSR)
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	return csr, nil
+}
+
+// Sign issues a client certificate for the CSR's public key bound to
+// moduleID. Only the public key is taken from the CSR: subject and SANs are
+// set by the CA, so a module cannot obtain a certificate for another identity.
+func (a *Authority) Sign(csr *x509.CertificateRequest, moduleID string, ttl time.Duration) (*x509.Certificate, []byte, error) {
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.Add(ttl)
+	if notAfter.After(a.cert.NotAfter) {
+		notAfter = a.cert.NotAfter
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: moduleID},
+		URIs:         []*url.URL{ModuleURI(moduleID)},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, csr.PublicKey, a.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse issued certificate: %w", err)
+	}
+
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// ModuleURI returns the URI SAN that identifies a module in its certificate.
+func ModuleURI(moduleID string) *url.URL {
+	return &url.URL{Scheme: "urn", Opaque: "openplatform:module:" + moduleID}
+}
+
+// newSerial returns a random 128-bit serial number.
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
@@ -0,0 +1,185 @@
+package ca
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCSR returns a PEM encoded CSR for a fresh key with the given subject.
+func newCSR(t *testing.T, commonName string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: []string{commonName},
+	}, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func newAuthority(t *testing.T) *Authority {
+	t.Helper()
+
+	authority, err := LoadOrCreate(context.Background(), FileStore{Dir: t.TempDir()}, "Test CA")
+	require.NoError(t, err)
+	return authority
+}
+
+func TestLoadOrCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+	store := FileStore{Dir: dir}
+
+	created, err := LoadOrCreate(context.Background(), store, "Test CA")
+	require.NoError(t, err)
+	assert.True(t, created.Certificate().IsCA)
+	assert.Equal(t, "Test CA", created.Certificate().Subject.CommonName)
+
+	info, err := os.Stat(filepath.Join(dir, keyEntry))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := LoadOrCreate(context.Background(), store, "ignored")
+	require.NoError(t, err)
+	assert.Equal(t, created.CertificatePEM(), loaded.CertificatePEM())
+
+	require.NoError(t, os.Remove(filepath.Join(dir, keyEntry)))
+	_, err = LoadOrCreate(context.Background(), store, "Test CA")
+	assert.ErrorContains(t, err, "only one of")
+}
+
+func TestParseCSR(t *testing.T) {
+	_, err := ParseCSR([]byte("not a csr"))
+	assert.ErrorIs(t, err, ErrInvalidCSR)
+
+	csrPEM := newCSR(t, "dashboard")
+	block, _ := pem.Decode(csrPEM)
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	_, err = ParseCSR(pem.EncodeToMemory(block))
+	assert.ErrorIs(t, err, ErrInvalidCSR)
+
+	csr, err := ParseCSR(newCSR(t, "dashboard"))
+	require.NoError(t, err)
+	assert.Equal(t, "dashboard", csr.Subject.CommonName)
+}
+
+func TestSignBindsModuleID(t *testing.T) {
+	authority := newAuthority(t)
+
+	csr, err := ParseCSR(newCSR(t, "someone-else"))
+	require.NoError(t, err)
+
+	cert, certPEM, err := authority.Sign(csr, "m1", time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, certPEM)
+
+	assert.Equal(t, "m1", cert.Subject.CommonName)
+	assert.Empty(t, cert.DNSNames)
+	assert.Equal(t, "urn:openplatform:module:m1", cert.URIs[0].String())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cert.NotAfter, time.Minute)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(authority.Certificate())
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	assert.NoError(t, err)
+
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	assert.Error(t, err)
+}
+
+// verifiedState describes a handshake in which cert was verified against authority.
+func verifiedState(cert *x509.Certificate, authority *Authority) tls.ConnectionState {
+	return tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert, authority.Certificate()}},
+	}
+}
+
+func newIssuer(t *testing.T) (*Issuer, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewIssuer(newAuthority(t), sqlx.NewDb(mockDB, "sqlmock"), time.Hour), mock
+}
+
+func TestIssueAndRevoke(t *testing.T) {
+	issuer, mock := newIssuer(t)
+	csr, err := ParseCSR(newCSR(t, "dashboard"))
+	require.NoError(t, err)
+
+	var serial string
+	mock.ExpectQuery(`INSERT INTO module_certificates`).
+		WithArgs(sqlmock.AnyArg(), "m1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"serial", "module_id", "not_after", "revoked_at", "created_at"}).
+			AddRow("recorded", "m1", time.Now().Add(time.Hour), nil, time.Now()))
+
+	issued, certPEM, err := issuer.Issue(context.Background(), "m1", csr)
+	require.NoError(t, err)
+	assert.Equal(t, "m1", issued.ModuleID)
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	serial = cert.SerialNumber.Text(16)
+
+	state := verifiedState(cert, issuer.Authority())
+	assert.NoError(t, issuer.VerifyConnection(state))
+
+	mock.ExpectExec(`UPDATE module_certificates SET revoked_at`).WithArgs(serial).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, issuer.Revoke(context.Background(), serial))
+	assert.ErrorIs(t, issuer.VerifyConnection(state), ErrRevoked)
+
+	mock.ExpectExec(`UPDATE module_certificates SET revoked_at`).WithArgs("ff").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, issuer.Revoke(context.Background(), "ff"), ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshRevocations(t *testing.T) {
+	issuer, mock := newIssuer(t)
+	csr, err := ParseCSR(newCSR(t, "dashboard"))
+	require.NoError(t, err)
+
+	cert, _, err := issuer.Authority().Sign(csr, "m1", time.Hour)
+	require.NoError(t, err)
+	state := verifiedState(cert, issuer.Authority())
+
+	mock.ExpectQuery(`NOT EXISTS \(SELECT 1 FROM modules`).
+		WillReturnRows(sqlmock.NewRows([]string{"serial"}).AddRow(cert.SerialNumber.Text(16)))
+	require.NoError(t, issuer.RefreshRevocations(context.Background()))
+	assert.ErrorIs(t, issuer.VerifyConnection(state), ErrRevoked)
+
+	// Certificates from other CAs are left to the configured client CA bundle
+	otherCA := newAuthority(t)
+	other, _, err := otherCA.Sign(csr, "m1", time.Hour)
+	require.NoError(t, err)
+	other.SerialNumber = cert.SerialNumber
+	assert.NoError(t, issuer.VerifyConnection(verifiedState(other, otherCA)))
+
+	mock.ExpectQuery(`FROM module_certificates`).WillReturnRows(sqlmock.NewRows([]string{"serial"}))
+	require.NoError(t, issuer.RefreshRevocations(context.Background()))
+	assert.NoError(t, issuer.VerifyConnection(state))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,180 @@
+package ca
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrNotFound is returned when a certificate serial is unknown.
+	ErrNotFound = errors.New("certificate not found")
+	// ErrRevoked is returned by VerifyConnection for revoked certificates.
+	ErrRevoked = errors.New("certificate has been revoked")
+)
+
+// Certificate describes a certificate issued to a module.
+type Certificate struct {
+	Serial    string     `db:"serial" json:"serial"`
+	ModuleID  string     `db:"module_id" json:"module_id"`
+	NotAfter  time.Time  `db:"not_after" json:"not_after"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Issuer signs module certificates, records them and keeps an in-memory
+// copy of the revocation list for the TLS handshake.
+type Issuer struct {
+	authority *Authority
+	db        *sqlx.DB
+	ttl       time.Duration
+
+	mu      sync.RWMutex
+	revoked map[string]struct{}
+}
+
+// NewIssuer creates an issuer whose certificates are valid for ttl. Call
+// RefreshRevocations before accepting connections.
+func NewIssuer(authority *Authority, db *sqlx.DB, ttl time.Duration) *Issuer {
+	return &Issuer{
+		authority: authority,
+		db:        db,
+		ttl:       ttl,
+		revoked:   make(map[string]struct{}),
+	}
+}
+
+// Authority returns the CA that signs the certificates.
+func (i *Issuer) Authority() *Authority {
+	return i.authority
+}
+
+// Issue signs a certificate for moduleID and records its serial.
+func (i *Issuer) Issue(ctx context.Context, moduleID string, csr *x509.CertificateRequest) (*Certificate, []byte, error) {
+	cert, certPEM, err := i.authority.Sign(csr, moduleID, i.ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var issued Certificate
+	query := `INSERT INTO module_certificates (serial, module_id, not_after) VALUES ($1, $2, $3)
+		RETURNING serial, module_id, not_after, revoked_at, created_at`
+
+	if err := i.db.GetContext(ctx, &issued, query, serialString(cert), moduleID, cert.NotAfter); err != nil {
+		return nil, nil, fmt.Errorf("failed to record certificate: %w", err)
+	}
+
+	return &issued, certPEM, nil
+}
+
+// List returns the certificates that have not expired yet, newest first.
+func (i *Issuer) List(ctx context.Context) ([]Certificate, error) {
+	certs := []Certificate{}
+	query := `SELECT serial, module_id, not_after, revoked_at, created_at
+		FROM module_certificates WHERE not_after > CURRENT_TIMESTAMP ORDER BY created_at DESC`
+
+	if err := i.db.SelectContext(ctx, &certs, query); err != nil {
+		return nil, fmt.Errorf("failed to list certificates: %w", err)
+	}
+
+	return certs, nil
+}
+
+// Revoke revokes a certificate. It takes effect on this server immediately
+// and on other servers at their next refresh.
+func (i *Issuer) Revoke(ctx context.Context, serial string) error {
+	result, err := i.db.ExecContext(ctx,
+		`UPDATE module_certificates SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE serial = $1`, serial)
+	if err != nil {
+		return fmt.Errorf("failed to revoke certificate: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	i.mu.Lock()
+	i.revoked[serial] = struct{}{}
+	i.mu.Unlock()
+
+	return nil
+}
+
+// RefreshRevocations reloads the revocation list. Certificates of modules
+// that no longer exist count as revoked; expired certificates are left out
+// since the handshake rejects them anyway.
+func (i *Issuer) RefreshRevocations(ctx context.Context) error {
+	var serials []string
+	query := `SELECT c.serial FROM module_certificates c
+		WHERE c.not_after > CURRENT_TIMESTAMP
+		AND (c.revoked_at IS NOT NULL
+			OR NOT EXISTS (SELECT 1 FROM modules m WHERE m.module_id = c.module_id))`
+
+	if err := i.db.SelectContext(ctx, &serials, query); err != nil {
+		return fmt.Errorf("failed to load revoked certificates: %w", err)
+	}
+
+	revoked := make(map[string]struct{}, len(serials))
+	for _, serial := range serials {
+		revoked[serial] = struct{}{}
+	}
+
+	i.mu.Lock()
+	i.revoked = revoked
+	i.mu.Unlock()
+
+	return nil
+}
+
+// WatchRevocations refreshes the revocation list every interval until ctx
+// is cancelled. On error the previous list stays in use.
+func (i *Issuer) WatchRevocations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := i.RefreshRevocations(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("revocation refresh failed, keeping previous list: %v", err)
+			}
+		}
+	}
+}
+
+// VerifyConnection rejects handshakes whose client certificate was issued
+// by this CA and has since been revoked. It is meant for
+// tls.Config.VerifyConnection and runs after chain verification.
+func (i *Issuer) VerifyConnection(cs tls.ConnectionState) error {
+	for _, chain := range cs.VerifiedChains {
+		if len(chain) < 2 || !chain[len(chain)-1].Equal(i.authority.cert) {
+			continue
+		}
+
+		serial := serialString(chain[0])
+
+		i.mu.RLock()
+		_, revoked := i.revoked[serial]
+		i.mu.RUnlock()
+
+		if revoked {
+			return fmt.Errorf("%w: serial %s", ErrRevoked, serial)
+		}
+	}
+
+	return nil
+}
+
+// serialString formats a certificate serial as lowercase hex.
+func serialString(cert *x509.Certificate) string {
+	return cert.SerialNumber.Text(16)
+}
@@ -0,0 +1,68 @@
+package ca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotExist is returned by a Store for missing entries.
+var ErrNotExist = errors.New("entry does not exist")
+
+// Store persists CA key material. Implementations must keep entries private,
+// since they include the CA's private key.
+type Store interface {
+	// Load returns the named entry, or ErrNotExist.
+	Load(ctx context.Context, name string) ([]byte, error)
+	// Save creates or replaces the named entry.
+	Save(ctx context.Context, name string, data []byte) error
+}
+
+// FileStore keeps entries as files in a directory.
+type FileStore struct {
+	Dir string
+}
+
+// Load reads the named file.
+func (s FileStore) Load(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// Save writes the named file readable only by the current user. The file is
+// written to a temporary name first so readers never see a partial entry.
+func (s FileStore) Save(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create CA directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, "."+name+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.Dir, name)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
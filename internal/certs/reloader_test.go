@@ -0,0 +1,201 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for the given template.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+	}
+}
+
+func serverTemplate(cn string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+// handshake connects to addr and returns the server certificate's common name.
+func handshake(addr string, ca *testCA, client *tls.Certificate) (string, error) {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	cfg := &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	if client != nil {
+		cfg.Certificates = []tls.Certificate{*client}
+	}
+
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// TLS 1.3 reports client certificate rejections on the first read
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			return "", err
+		}
+	}
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestReloaderRequiresClientCertAndReloads(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	serverCert, serverKey := ca.issue(t, serverTemplate("server-v1"))
+	writeFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey, "ca.crt": ca.pem})
+
+	r, err := NewReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"),
+		filepath.Join(dir, "ca.crt"), tls.RequireAndVerifyClientCert)
+	require.NoError(t, err)
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", r.TLSConfig())
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				time.Sleep(300 * time.Millisecond)
+				conn.Close()
+			}()
+		}
+	}()
+
+	clientPEM, clientKey := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "dashboard"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	client, err := tls.X509KeyPair(clientPEM, clientKey)
+	require.NoError(t, err)
+
+	_, err = handshake(lis.Addr().String(), ca, nil)
+	assert.Error(t, err, "connections without a client certificate must fail")
+
+	cn, err := handshake(lis.Addr().String(), ca, &client)
+	require.NoError(t, err)
+	assert.Equal(t, "server-v1", cn)
+
+	assert.False(t, r.changed())
+
+	serverCert, serverKey = ca.issue(t, serverTemplate("server-v2"))
+	writeFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey})
+	// Make sure the modification is visible even on coarse file system clocks
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "server.crt"), future, future))
+
+	require.True(t, r.changed())
+	require.NoError(t, r.Reload())
+
+	cn, err = handshake(lis.Addr().String(), ca, &client)
+	require.NoError(t, err)
+	assert.Equal(t, "server-v2", cn)
+}
+
+func TestReloadKeepsPreviousCertificatesOnError(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	serverCert, serverKey := ca.issue(t, serverTemplate("server"))
+	writeFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey})
+
+	r, err := NewReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), "", tls.NoClientCert)
+	require.NoError(t, err)
+	before := r.current
+
+	writeFiles(t, dir, map[string][]byte{"server.crt": []byte("garbage")})
+	assert.Error(t, r.Reload())
+	assert.Same(t, before, r.current)
+}
+
+func TestIdentities(t *testing.T) {
+	uri, _ := url.Parse("spiffe://openplatform/module/notes")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "dashboard"},
+		DNSNames: []string{"dashboard.modules.internal"},
+		URIs:     []*url.URL{uri},
+	}
+
+	assert.Equal(t, []string{"dashboard", "dashboard.modules.internal", "spiffe://openplatform/module/notes"}, Identities(cert))
+}
@@ -0,0 +1,480 @@
+// Package config defines the typed configuration of the backend.
+//
+// Settings are resolved in increasing order of precedence from built-in
+// defaults, an optional YAML or JSON config file, environment variables and
+// command-line flags. Each setting declares its file key, environment variable
+// and flag name through struct tags, so the three sources always stay in sync.
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config is the complete backend configuration.
+type Config struct {
+	HTTP     HTTP     `yaml:"http"`
+	GRPC     GRPC     `yaml:"grpc"`
+	DB       DB       `yaml:"db"`
+	CORS     CORS     `yaml:"cors"`
+	Modules  Modules  `yaml:"modules"`
+	Auth     Auth     `yaml:"auth"`
+	OIDC     OIDC     `yaml:"oidc"`
+	CA       CA       `yaml:"ca"`
+	Query    Query    `yaml:"query"`
+	Shutdown Shutdown `yaml:"shutdown"`
+}
+
+// HTTP configures the REST API server.
+type HTTP struct {
+	Addr              string        `yaml:"addr" env:"HTTP_ADDR" flag:"http-addr" usage:"REST API listen address"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"http-read-header-timeout" usage:"maximum time to read request headers"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout" usage:"maximum time to read a full request (0 disables)"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout" usage:"maximum time to write a response (0 disables; proxied streams need 0)"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" usage:"keep-alive idle timeout"`
+}
+
+// GRPC configures the gRPC server.
+type GRPC struct {
+	Addr            string `yaml:"addr" env:"GRPC_ADDR" flag:"grpc-addr" usage:"gRPC listen address"`
+	MaxRecvMsgBytes int    `yaml:"max_recv_msg_bytes" env:"GRPC_MAX_RECV_MSG_BYTES" flag:"grpc-max-recv-msg-bytes" usage:"maximum size of a received gRPC message"`
+
+	// TLS is enabled when TLSCert is set.
+	TLSCert           string        `yaml:"tls_cert" env:"GRPC_TLS_CERT" flag:"grpc-tls-cert" usage:"server certificate file (enables TLS)"`
+	TLSKey            string        `yaml:"tls_key" env:"GRPC_TLS_KEY" flag:"grpc-tls-key" usage:"server private key file"`
+	TLSClientCA       string        `yaml:"tls_client_ca" env:"GRPC_TLS_CLIENT_CA" flag:"grpc-tls-client-ca" usage:"CA bundle used to verify module client certificates"`
+	TLSClientAuth     string        `yaml:"tls_client_auth" env:"GRPC_TLS_CLIENT_AUTH" flag:"grpc-tls-client-auth" usage:"client certificate policy: none, optional or require"`
+	TLSReloadInterval time.Duration `yaml:"tls_reload_interval" env:"GRPC_TLS_RELOAD_INTERVAL" flag:"grpc-tls-reload-interval" usage:"how often certificate files are checked for changes"`
+}
+
+// TLSEnabled reports whether the gRPC server uses TLS.
+func (g *GRPC) TLSEnabled() bool {
+	return g.TLSCert != ""
+}
+
+// DB configures the PostgreSQL connection and pool.
+// DSN, when set, takes precedence over the individual connection fields.
+type DB struct {
+	DSN             string        `yaml:"dsn" env:"DB_DSN" flag:"db-dsn" secret:"true" usage:"PostgreSQL connection string (overrides the individual fields)"`
+	Host            string        `yaml:"host" env:"DB_HOST" flag:"db-host" usage:"database host"`
+	Port            int           `yaml:"port" env:"DB_PORT" flag:"db-port" usage:"database port"`
+	User            string        `yaml:"user" env:"DB_USER" flag:"db-user" usage:"database user"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD" flag:"db-password" secret:"true" usage:"database password"`
+	Name            string        `yaml:"name" env:"DB_NAME" flag:"db-name" usage:"database name"`
+	SSLMode         string        `yaml:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" usage:"TLS mode: disable, require, verify-ca or verify-full"`
+	SSLRootCert     string        `yaml:"sslrootcert" env:"DB_SSLROOTCERT" flag:"db-sslrootcert" usage:"CA certificate used to verify the server"`
+	SSLCert         string        `yaml:"sslcert" env:"DB_SSLCERT" flag:"db-sslcert" usage:"client certificate for TLS authentication"`
+	SSLKey          string        `yaml:"sslkey" env:"DB_SSLKEY" flag:"db-sslkey" usage:"client key for TLS authentication"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" flag:"db-connect-timeout" usage:"timeout for establishing a connection"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections (0 means unlimited)"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a connection (0 means unlimited)"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" usage:"maximum idle time of a connection (0 means unlimited)"`
+	AutoMigrate     bool          `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE" flag:"db-auto-migrate" usage:"apply pending schema migrations on startup"`
+}
+
+// CORS configures cross-origin access to the REST API.
+type CORS struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" flag:"cors-allowed-origins" usage:"comma-separated list of allowed origins"`
+	AllowCredentials bool     `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" flag:"cors-allow-credentials" usage:"allow cookies and authorization headers in cross-origin requests"`
+	MaxAge           int      `yaml:"max_age" env:"CORS_MAX_AGE" flag:"cors-max-age" usage:"seconds browsers may cache preflight responses"`
+}
+
+// Modules configures liveness tracking, health probing and proxying of modules.
+type Modules struct {
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" env:"MODULE_HEARTBEAT_INTERVAL" flag:"module-heartbeat-interval" usage:"heartbeat interval advertised to modules"`
+	StaleAfter        int           `yaml:"stale_after" env:"MODULE_STALE_AFTER" flag:"module-stale-after" usage:"missed heartbeats before a module is STALE"`
+	OfflineAfter      int           `yaml:"offline_after" env:"MODULE_OFFLINE_AFTER" flag:"module-offline-after" usage:"missed heartbeats before a module is OFFLINE"`
+	InstanceTTL       time.Duration `yaml:"instance_ttl" env:"MODULE_INSTANCE_TTL" flag:"module-instance-ttl" usage:"time without heartbeats after which replica instances are removed (0 keeps them)"`
+	ResolveHosts      bool          `yaml:"resolve_hosts" env:"MODULE_RESOLVE_HOSTS" flag:"module-resolve-hosts" usage:"reject module DNS names that do not resolve"`
+	InferAddress      bool          `yaml:"infer_address" env:"MODULE_INFER_ADDRESS" flag:"module-infer-address" usage:"use the connecting address for modules that register without an IP"`
+	ProbeInterval     time.Duration `yaml:"probe_interval" env:"MODULE_PROBE_INTERVAL" flag:"module-probe-interval" usage:"time between active health probe rounds"`
+	ProbeTimeout      time.Duration `yaml:"probe_timeout" env:"MODULE_PROBE_TIMEOUT" flag:"module-probe-timeout" usage:"timeout of a single health probe"`
+	ProbeMode         string        `yaml:"probe_mode" env:"MODULE_PROBE_MODE" flag:"module-probe-mode" usage:"health probe protocol: grpc or http"`
+	ProbeHTTPPath     string        `yaml:"probe_http_path" env:"MODULE_PROBE_HTTP_PATH" flag:"module-probe-http-path" usage:"path requested by HTTP health probes"`
+	ProbeConcurrency  int           `yaml:"probe_concurrency" env:"MODULE_PROBE_CONCURRENCY" flag:"module-probe-concurrency" usage:"modules probed in parallel"`
+	ProbeRetention    time.Duration `yaml:"probe_retention" env:"MODULE_PROBE_RETENTION" flag:"module-probe-retention" usage:"how long probe results are kept"`
+	ProxyTimeout      time.Duration `yaml:"proxy_timeout" env:"MODULE_PROXY_TIMEOUT" flag:"module-proxy-timeout" usage:"default time to wait for proxied module response headers"`
+	WatchInterval     time.Duration `yaml:"watch_interval" env:"MODULE_WATCH_INTERVAL" flag:"module-watch-interval" usage:"how often WatchModules streams check for module changes"`
+}
+
+// Auth configures authentication of modules and administrators.
+type Auth struct {
+	AdminToken        string        `yaml:"admin_token" env:"AUTH_ADMIN_TOKEN" flag:"auth-admin-token" secret:"true" usage:"static bearer token that authenticates with the admin role (empty disables it)"`
+	RequireModuleAuth bool          `yaml:"require_module_auth" env:"AUTH_REQUIRE_MODULE_AUTH" flag:"auth-require-module-auth" usage:"require join tokens to register and credentials for module calls"`
+	JoinTokenTTL      time.Duration `yaml:"join_token_ttl" env:"AUTH_JOIN_TOKEN_TTL" flag:"auth-join-token-ttl" usage:"default lifetime of minted join tokens"`
+	SessionTTL        time.Duration `yaml:"session_ttl" env:"AUTH_SESSION_TTL" flag:"auth-session-ttl" usage:"lifetime of user login sessions"`
+	CookieSecure      bool          `yaml:"cookie_secure" env:"AUTH_COOKIE_SECURE" flag:"auth-cookie-secure" usage:"mark cookies Secure (disable only when serving plain HTTP)"`
+}
+
+// OIDC configures login through an OpenID Connect identity provider. It is
+// enabled when IssuerURL is set.
+type OIDC struct {
+	IssuerURL         string   `yaml:"issuer_url" env:"OIDC_ISSUER_URL" flag:"oidc-issuer-url" usage:"issuer URL of the identity provider (enables OIDC)"`
+	ClientID          string   `yaml:"client_id" env:"OIDC_CLIENT_ID" flag:"oidc-client-id" usage:"client ID registered with the identity provider"`
+	ClientSecret      string   `yaml:"client_secret" env:"OIDC_CLIENT_SECRET" flag:"oidc-client-secret" secret:"true" usage:"client secret (empty for public clients)"`
+	RedirectURL       string   `yaml:"redirect_url" env:"OIDC_REDIRECT_URL" flag:"oidc-redirect-url" usage:"external URL of /api/auth/oidc/callback"`
+	Scopes            []string `yaml:"scopes" env:"OIDC_SCOPES" flag:"oidc-scopes" usage:"scopes requested at login"`
+	Audiences         []string `yaml:"audiences" env:"OIDC_AUDIENCES" flag:"oidc-audiences" usage:"accepted audiences of bearer tokens (defaults to the client ID)"`
+	UsernameClaim     string   `yaml:"username_claim" env:"OIDC_USERNAME_CLAIM" flag:"oidc-username-claim" usage:"claim used as the username"`
+	RoleClaim         string   `yaml:"role_claim" env:"OIDC_ROLE_CLAIM" flag:"oidc-role-claim" usage:"claim holding the groups or roles that are mapped to platform roles"`
+	RoleMapping       []string `yaml:"role_mapping" env:"OIDC_ROLE_MAPPING" flag:"oidc-role-mapping" usage:"claim value to role mappings such as platform-admins=admin"`
+	DefaultRole       string   `yaml:"default_role" env:"OIDC_DEFAULT_ROLE" flag:"oidc-default-role" usage:"role of users without a mapped claim value (empty denies access)"`
+	PostLoginRedirect string   `yaml:"post_login_redirect" env:"OIDC_POST_LOGIN_REDIRECT" flag:"oidc-post-login-redirect" usage:"where the browser is sent after logging in"`
+}
+
+// Enabled reports whether OIDC login is configured.
+func (o *OIDC) Enabled() bool {
+	return o.IssuerURL != ""
+}
+
+// CA configures the internal certificate authority that issues module
+// client certificates. It needs gRPC TLS with client certificates enabled.
+type CA struct {
+	Enabled           bool          `yaml:"enabled" env:"CA_ENABLED" flag:"ca-enabled" usage:"issue client certificates to modules that send a CSR"`
+	Dir               string        `yaml:"dir" env:"CA_DIR" flag:"ca-dir" usage:"directory holding the CA certificate and key (created if missing)"`
+	CommonName        string        `yaml:"common_name" env:"CA_COMMON_NAME" flag:"ca-common-name" usage:"subject of a newly created CA certificate"`
+	CertTTL           time.Duration `yaml:"cert_ttl" env:"CA_CERT_TTL" flag:"ca-cert-ttl" usage:"lifetime of issued module certificates"`
+	RevocationRefresh time.Duration `yaml:"revocation_refresh" env:"CA_REVOCATION_REFRESH" flag:"ca-revocation-refresh" usage:"how often the revocation list is reloaded from the database"`
+}
+
+// Query limits the SQL queries run through POST /api/query.
+type Query struct {
+	Timeout  time.Duration `yaml:"timeout" env:"QUERY_TIMEOUT" flag:"query-timeout" usage:"statement timeout of ad-hoc SQL queries"`
+	MaxRows  int           `yaml:"max_rows" env:"QUERY_MAX_ROWS" flag:"query-max-rows" usage:"maximum number of rows returned by an ad-hoc SQL query"`
+	MaxBytes int           `yaml:"max_bytes" env:"QUERY_MAX_BYTES" flag:"query-max-bytes" usage:"maximum size in bytes of an ad-hoc SQL query result"`
+}
+
+// Shutdown configures how the servers drain when the process is stopped.
+type Shutdown struct {
+	DrainDelay time.Duration `yaml:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY" flag:"shutdown-drain-delay" usage:"time between reporting not-ready and closing the listeners"`
+	Timeout    time.Duration `yaml:"timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"deadline for in-flight requests to finish before connections are closed"`
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:              ":3000",
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       120 * time.Second,
+		},
+		GRPC: GRPC{
+			Addr:              ":50051",
+			MaxRecvMsgBytes:   16 << 20,
+			TLSClientAuth:     "require",
+			TLSReloadInterval: 30 * time.Second,
+		},
+		DB: DB{
+			Host:           "localhost",
+			Port:           5432,
+			SSLMode:        "disable",
+			ConnectTimeout: 10 * time.Second,
+			MaxOpenConns:   25,
+			MaxIdleConns:   5,
+			AutoMigrate:    true,
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{"*"},
+			MaxAge:         300,
+		},
+		Modules: Modules{
+			HeartbeatInterval: 10 * time.Second,
+			StaleAfter:        3,
+			OfflineAfter:      6,
+			InstanceTTL:       time.Hour,
+			ProbeInterval:     30 * time.Second,
+			ProbeTimeout:      5 * time.Second,
+			ProbeMode:         "grpc",
+			ProbeHTTPPath:     "/healthz",
+			ProbeConcurrency:  8,
+			ProbeRetention:    24 * time.Hour,
+			ProxyTimeout:      30 * time.Second,
+			WatchInterval:     5 * time.Second,
+		},
+		Auth: Auth{
+			RequireModuleAuth: true,
+			JoinTokenTTL:      24 * time.Hour,
+			SessionTTL:        12 * time.Hour,
+			CookieSecure:      true,
+		},
+		OIDC: OIDC{
+			Scopes:            []string{"openid", "profile", "email"},
+			UsernameClaim:     "preferred_username",
+			RoleClaim:         "groups",
+			PostLoginRedirect: "/",
+		},
+		CA: CA{
+			Dir:               "data/ca",
+			CommonName:        "OpenPlatform Module CA",
+			CertTTL:           24 * time.Hour,
+			RevocationRefresh: 30 * time.Second,
+		},
+		Query: Query{
+			Timeout:  10 * time.Second,
+			MaxRows:  1000,
+			MaxBytes: 10 << 20,
+		},
+		Shutdown: Shutdown{
+			DrainDelay: 5 * time.Second,
+			Timeout:    30 * time.Second,
+		},
+	}
+}
+
+// Validate reports the first invalid setting.
+func (c *Config) Validate() error {
+	if c.HTTP.Addr == "" {
+		return fmt.Errorf("http.addr cannot be empty")
+	}
+
+	if c.GRPC.Addr == "" {
+		return fmt.Errorf("grpc.addr cannot be empty")
+	}
+
+	if err := c.GRPC.validate(c.CA.Enabled); err != nil {
+		return err
+	}
+
+	if err := c.DB.validate(); err != nil {
+		return err
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		return fmt.Errorf("cors.allowed_origins cannot be empty")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" && c.CORS.AllowCredentials {
+			return fmt.Errorf("cors.allow_credentials cannot be combined with the '*' origin")
+		}
+	}
+
+	if err := c.Modules.validate(); err != nil {
+		return err
+	}
+
+	if c.Auth.JoinTokenTTL <= 0 || c.Auth.SessionTTL <= 0 {
+		return fmt.Errorf("auth.join_token_ttl and auth.session_ttl must be positive")
+	}
+
+	if err := c.OIDC.validate(); err != nil {
+		return err
+	}
+
+	if err := c.CA.validate(&c.GRPC, &c.Auth); err != nil {
+		return err
+	}
+
+	if c.Query.Timeout <= 0 || c.Query.MaxRows <= 0 || c.Query.MaxBytes <= 0 {
+		return fmt.Errorf("query.timeout, query.max_rows and query.max_bytes must be positive")
+	}
+
+	if c.Shutdown.DrainDelay < 0 || c.Shutdown.Timeout <= 0 {
+		return fmt.Errorf("shutdown.drain_delay cannot be negative and shutdown.timeout must be positive")
+	}
+
+	return nil
+}
+
+// validate checks the gRPC settings. With the internal CA enabled, client
+// certificates can be verified without a separate client CA bundle.
+func (g *GRPC) validate(internalCA bool) error {
+	if g.MaxRecvMsgBytes <= 0 {
+		return fmt.Errorf("grpc.max_recv_msg_bytes must be positive")
+	}
+
+	if !g.TLSEnabled() {
+		if g.TLSKey != "" || g.TLSClientCA != "" {
+			return fmt.Errorf("grpc.tls_key and grpc.tls_client_ca require grpc.tls_cert")
+		}
+		return nil
+	}
+
+	if g.TLSKey == "" {
+		return fmt.Errorf("grpc.tls_key is required with grpc.tls_cert")
+	}
+
+	switch g.TLSClientAuth {
+	case "none":
+	case "optional", "require":
+		if g.TLSClientCA == "" && !internalCA {
+			return fmt.Errorf("grpc.tls_client_ca is required when grpc.tls_client_auth is %s", g.TLSClientAuth)
+		}
+	default:
+		return fmt.Errorf("invalid grpc.tls_client_auth %q (expected none, optional or require)", g.TLSClientAuth)
+	}
+
+	if g.TLSReloadInterval <= 0 {
+		return fmt.Errorf("grpc.tls_reload_interval must be positive")
+	}
+
+	return nil
+}
+
+func (o *OIDC) validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+
+	if o.ClientID == "" || o.RedirectURL == "" {
+		return fmt.Errorf("oidc.client_id and oidc.redirect_url are required with oidc.issuer_url")
+	}
+
+	if o.UsernameClaim == "" {
+		return fmt.Errorf("oidc.username_claim cannot be empty")
+	}
+
+	for _, mapping := range o.RoleMapping {
+		value, role, ok := strings.Cut(mapping, "=")
+		if !ok || value == "" || !validRole(role) {
+			return fmt.Errorf("invalid oidc.role_mapping entry %q (expected value=viewer|operator|admin)", mapping)
+		}
+	}
+
+	if o.DefaultRole != "" && !validRole(o.DefaultRole) {
+		return fmt.Errorf("invalid oidc.default_role %q (expected viewer, operator or admin)", o.DefaultRole)
+	}
+
+	return nil
+}
+
+func validRole(role string) bool {
+	return role == "viewer" || role == "operator" || role == "admin"
+}
+
+func (a *CA) validate(g *GRPC, auth *Auth) error {
+	if !a.Enabled {
+		return nil
+	}
+
+	if !g.TLSEnabled() || g.TLSClientAuth == "none" {
+		return fmt.Errorf("ca.enabled requires grpc.tls_cert and a grpc.tls_client_auth other than none")
+	}
+
+	// Otherwise callers without a certificate or credential could obtain
+	// certificates for modules they do not own
+	if g.TLSClientAuth != "require" && !auth.RequireModuleAuth {
+		return fmt.Errorf("ca.enabled requires grpc.tls_client_auth=require or auth.require_module_auth")
+	}
+
+	if a.Dir == "" || a.CommonName == "" {
+		return fmt.Errorf("ca.dir and ca.common_name cannot be empty")
+	}
+
+	if a.CertTTL <= 0 || a.RevocationRefresh <= 0 {
+		return fmt.Errorf("ca.cert_ttl and ca.revocation_refresh must be positive")
+	}
+
+	return nil
+}
+
+func (d *DB) validate() error {
+	if d.DSN == "" {
+		if d.Host == "" || d.Name == "" || d.User == "" {
+			return fmt.Errorf("db.host, db.name and db.user are required unless db.dsn is set")
+		}
+
+		if d.Port <= 0 || d.Port > 65535 {
+			return fmt.Errorf("invalid db.port: %d", d.Port)
+		}
+	}
+
+	switch d.SSLMode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("invalid db.sslmode %q (expected disable, require, verify-ca or verify-full)", d.SSLMode)
+	}
+
+	if (d.SSLCert == "") != (d.SSLKey == "") {
+		return fmt.Errorf("db.sslcert and db.sslkey must be set together")
+	}
+
+	if d.MaxOpenConns < 0 || d.MaxIdleConns < 0 {
+		return fmt.Errorf("database pool sizes cannot be negative")
+	}
+
+	if d.MaxOpenConns > 0 && d.MaxIdleConns > d.MaxOpenConns {
+		return fmt.Errorf("db.max_idle_conns (%d) cannot exceed db.max_open_conns (%d)", d.MaxIdleConns, d.MaxOpenConns)
+	}
+
+	return nil
+}
+
+func (m *Modules) validate() error {
+	if m.HeartbeatInterval <= 0 {
+		return fmt.Errorf("modules.heartbeat_interval must be positive")
+	}
+
+	if m.StaleAfter <= 0 || m.OfflineAfter <= m.StaleAfter {
+		return fmt.Errorf("modules.offline_after (%d) must be greater than modules.stale_after (%d), which must be positive", m.OfflineAfter, m.StaleAfter)
+	}
+
+	if m.InstanceTTL < 0 {
+		return fmt.Errorf("modules.instance_ttl cannot be negative")
+	}
+
+	if m.ProbeInterval <= 0 || m.ProbeTimeout <= 0 {
+		return fmt.Errorf("modules.probe_interval and modules.probe_timeout must be positive")
+	}
+
+	if m.ProbeMode != "grpc" && m.ProbeMode != "http" {
+		return fmt.Errorf("invalid modules.probe_mode %q (expected grpc or http)", m.ProbeMode)
+	}
+
+	if !strings.HasPrefix(m.ProbeHTTPPath, "/") {
+		return fmt.Errorf("modules.probe_http_path must start with '/'")
+	}
+
+	if m.ProbeConcurrency <= 0 {
+		return fmt.Errorf("modules.probe_concurrency must be positive")
+	}
+
+	if m.ProxyTimeout <= 0 {
+		return fmt.Errorf("modules.proxy_timeout must be positive")
+	}
+
+	if m.WatchInterval <= 0 {
+		return fmt.Errorf("modules.watch_interval must be positive")
+	}
+
+	return nil
+}
+
+// ConnString returns the lib/pq connection string for the database.
+func (d *DB) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+
+	params := []struct{ key, value string }{
+		{"host", d.Host},
+		{"port", fmt.Sprint(d.Port)},
+		{"user", d.User},
+		{"password", d.Password},
+		{"dbname", d.Name},
+		{"sslmode", d.SSLMode},
+		{"sslrootcert", d.SSLRootCert},
+		{"sslcert", d.SSLCert},
+		{"sslkey", d.SSLKey},
+	}
+	if d.ConnectTimeout > 0 {
+		params = append(params, struct{ key, value string }{"connect_timeout", fmt.Sprint(int(d.ConnectTimeout.Seconds()))})
+	}
+
+	var parts []string
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteConnValue(p.value))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// quoteConnValue quotes a key/value connection string value as libpq expects.
+func quoteConnValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := Bind(fs)
+	require.NoError(t, fs.Parse(args))
+	return loader.Load()
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+http:
+  addr: ":4000"
+db:
+  user: file-user
+  name: platform
+  max_open_conns: 10
+cors:
+  allowed_origins: [https://file.example]
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_USER", "env-user")
+	t.Setenv("HTTP_ADDR", ":5000")
+	t.Setenv("MODULE_PROBE_TIMEOUT", "2s")
+
+	cfg, err := load(t, "-http-addr", ":6000", "-db-auto-migrate=false")
+	require.NoError(t, err)
+
+	assert.Equal(t, ":6000", cfg.HTTP.Addr)
+	assert.Equal(t, "env-user", cfg.DB.User)
+	assert.Equal(t, "platform", cfg.DB.Name)
+	assert.Equal(t, 10, cfg.DB.MaxOpenConns)
+	assert.False(t, cfg.DB.AutoMigrate)
+	assert.Equal(t, []string{"https://file.example"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, 2*time.Second, cfg.Modules.ProbeTimeout)
+	assert.Equal(t, ":50051", cfg.GRPC.Addr)
+	assert.True(t, cfg.Auth.CookieSecure)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("DB_USER", "u")
+	t.Setenv("DB_NAME", "n")
+
+	_, err := load(t, "-config", writeFile(t, "http:\n  adr: \":1\"\n"))
+	assert.ErrorContains(t, err, "adr")
+
+	t.Setenv("MODULE_STALE_AFTER", "many")
+	_, err = load(t)
+	assert.ErrorContains(t, err, "MODULE_STALE_AFTER")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	Bind(fs)
+	assert.Error(t, fs.Parse([]string{"-db-port", "x"}))
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default()
+	valid.DB.User, valid.DB.Name = "u", "n"
+	require.NoError(t, valid.Validate())
+
+	for name, mutate := range map[string]func(*Config){
+		"missing db user":        func(c *Config) { c.DB.User = "" },
+		"bad sslmode":            func(c *Config) { c.DB.SSLMode = "prefer" },
+		"cert without key":       func(c *Config) { c.DB.SSLCert = "client.crt" },
+		"idle above open":        func(c *Config) { c.DB.MaxIdleConns = 50 },
+		"wildcard credentials":   func(c *Config) { c.CORS.AllowCredentials = true },
+		"no origins":             func(c *Config) { c.CORS.AllowedOrigins = nil },
+		"offline before stale":   func(c *Config) { c.Modules.OfflineAfter = 2 },
+		"unknown probe mode":     func(c *Config) { c.Modules.ProbeMode = "tcp" },
+		"empty grpc addr":        func(c *Config) { c.GRPC.Addr = "" },
+		"non-positive proxy ttl": func(c *Config) { c.Modules.ProxyTimeout = 0 },
+		"tls key without cert":   func(c *Config) { c.GRPC.TLSKey = "server.key" },
+		"mtls without client ca": func(c *Config) { c.GRPC.TLSCert, c.GRPC.TLSKey = "server.crt", "server.key" },
+		"ca without tls":         func(c *Config) { c.CA.Enabled = true },
+		"no query row limit":     func(c *Config) { c.Query.MaxRows = 0 },
+		"ca without client auth": func(c *Config) {
+			c.GRPC.TLSCert, c.GRPC.TLSKey, c.GRPC.TLSClientAuth = "server.crt", "server.key", "none"
+			c.CA.Enabled = true
+		},
+		"ca with optional client auth and no module auth": func(c *Config) {
+			c.GRPC.TLSCert, c.GRPC.TLSKey, c.GRPC.TLSClientAuth = "server.crt", "server.key", "optional"
+			c.Auth.RequireModuleAuth = false
+			c.CA.Enabled = true
+		},
+		"oidc without client id": func(c *Config) { c.OIDC.IssuerURL, c.OIDC.RedirectURL = "https://idp", "https://p/cb" },
+		"oidc bad role mapping": func(c *Config) {
+			c.OIDC.IssuerURL, c.OIDC.ClientID, c.OIDC.RedirectURL = "https://idp", "platform", "https://p/cb"
+			c.OIDC.RoleMapping = []string{"devs=root"}
+		},
+		"unknown client auth": func(c *Config) {
+			c.GRPC.TLSCert, c.GRPC.TLSKey, c.GRPC.TLSClientAuth = "server.crt", "server.key", "maybe"
+		},
+	} {
+		cfg := valid
+		cfg.CORS.AllowedOrigins = append([]string(nil), valid.CORS.AllowedOrigins...)
+		mutate(&cfg)
+		assert.Error(t, cfg.Validate(), name)
+	}
+
+	internalCA := valid
+	internalCA.GRPC.TLSCert, internalCA.GRPC.TLSKey = "server.crt", "server.key"
+	internalCA.CA.Enabled = true
+	assert.NoError(t, internalCA.Validate())
+
+	withOIDC := valid
+	withOIDC.OIDC.IssuerURL, withOIDC.OIDC.ClientID, withOIDC.OIDC.RedirectURL = "https://idp", "platform", "https://p/cb"
+	withOIDC.OIDC.RoleMapping = []string{"devs=operator"}
+	assert.NoError(t, withOIDC.Validate())
+
+	dsnOnly := Default()
+	dsnOnly.DB.DSN = "postgres://u@db/n"
+	assert.NoError(t, dsnOnly.Validate())
+}
+
+func TestConnString(t *testing.T) {
+	d := Default().DB
+	d.User, d.Name, d.Password = "platform", "openplatform", `it's a \secret`
+	d.SSLMode, d.SSLRootCert = "verify-full", "/etc/ca.pem"
+
+	assert.Equal(t,
+		`host='localhost' port='5432' user='platform' password='it\'s a \\secret' dbname='openplatform' sslmode='verify-full' sslrootcert='/etc/ca.pem' connect_timeout='10'`,
+		d.ConnString())
+
+	d.DSN = "postgres://x"
+	assert.Equal(t, "postgres://x", d.ConnString())
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.DB.Password = "hunter2"
+	cfg.DB.DSN = "postgres://u:hunter2@db/n"
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.Contains(t, out.String(), "password: "+redacted)
+	assert.Contains(t, out.String(), "heartbeat_interval: 10s")
+	assert.Contains(t, out.String(), "addr: :3000")
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Loader binds the configuration flags to a flag set and resolves the final
+// configuration once the flags have been parsed.
+type Loader struct {
+	file        *string
+	printConfig *bool
+	flags       map[string]string
+}
+
+// Bind registers a flag for every setting, plus -config and -print-config, on fs.
+// Callers may register their own flags on the same set before parsing it.
+func Bind(fs *flag.FlagSet) *Loader {
+	l := &Loader{
+		flags: make(map[string]string),
+	}
+
+	l.file = fs.String("config", "", "path to a YAML or JSON config file (env CONFIG_FILE)")
+	l.printConfig = fs.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	defaults := Default()
+	walk(reflect.ValueOf(&defaults).Elem(), "", func(f field) {
+		name := f.tag.Get("flag")
+		usage := f.tag.Get("usage")
+		if env := f.tag.Get("env"); env != "" {
+			usage += " (env " + env + ")"
+		}
+		fs.Var(&flagValue{name: name, kind: f.value.Type(), flags: l.flags}, name, usage)
+	})
+
+	return l
+}
+
+// PrintConfig reports whether -print-config was given.
+func (l *Loader) PrintConfig() bool {
+	return *l.printConfig
+}
+
+// Load resolves defaults, the config file, environment variables and flags,
+// in that order, and validates the result. The flag set must already be parsed.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+
+	path := *l.file
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	if path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	walk(reflect.ValueOf(&cfg).Elem(), "", func(f field) {
+		if err != nil {
+			return
+		}
+
+		if env := f.tag.Get("env"); env != "" {
+			if v, ok := os.LookupEnv(env); ok {
+				if setErr := setValue(f.value, v); setErr != nil {
+					err = fmt.Errorf("invalid %s: %w", env, setErr)
+					return
+				}
+			}
+		}
+
+		if v, ok := l.flags[f.tag.Get("flag")]; ok {
+			if setErr := setValue(f.value, v); setErr != nil {
+				err = fmt.Errorf("invalid -%s: %w", f.tag.Get("flag"), setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// loadFile overlays the settings of a YAML or JSON file onto cfg.
+// Unknown keys are rejected so typos are not silently ignored.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// flagValue records the raw value of a setting flag so it can be applied
+// after the config file and environment, whatever the order of parsing.
+type flagValue struct {
+	name  string
+	kind  reflect.Type
+	flags map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.flags == nil {
+		return ""
+	}
+	return f.flags[f.name]
+}
+
+// Set validates v eagerly so errors are reported by the flag package.
+func (f *flagValue) Set(v string) error {
+	if err := setValue(reflect.New(f.kind).Elem(), v); err != nil {
+		return err
+	}
+	f.flags[f.name] = v
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given without a value, as in -db-auto-migrate.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.kind.Kind() == reflect.Bool
+}
+
+// field is a leaf setting found while walking the configuration struct.
+type field struct {
+	path  string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// walk calls fn for every leaf setting of v, depth first in declaration order.
+func walk(v reflect.Value, prefix string, fn func(field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		path := sf.Tag.Get("yaml")
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), path, fn)
+			continue
+		}
+
+		fn(field{path: path, tag: sf.Tag, value: v.Field(i)})
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses s into the setting v.
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of secret settings in printed configurations.
+const redacted = "REDACTED"
+
+// Print writes the configuration to w as YAML in the config file format.
+// Settings tagged as secret are redacted when set.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := make(map[string]*yaml.Node)
+
+	walk(reflect.ValueOf(c).Elem(), "", func(f field) {
+		section, key, _ := strings.Cut(f.path, ".")
+		node, ok := sections[section]
+		if !ok {
+			node = &yaml.Node{Kind: yaml.MappingNode}
+			sections[section] = node
+			root.Content = append(root.Content, scalar(section), node)
+		}
+
+		node.Content = append(node.Content, scalar(key), valueNode(f))
+	})
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return fmt.Errorf("failed to print configuration: %w", err)
+	}
+	return enc.Close()
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+func valueNode(f field) *yaml.Node {
+	v := f.value
+
+	if f.tag.Get("secret") == "true" && v.String() != "" {
+		return scalar(redacted)
+	}
+
+	if v.Type() == durationType {
+		return scalar(time.Duration(v.Int()).String())
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(v.Interface()); err != nil {
+		return scalar(fmt.Sprint(v.Interface()))
+	}
+	if node.Kind == yaml.SequenceNode {
+		node.Style = yaml.FlowStyle
+	}
+	return node
+}
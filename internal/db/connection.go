@@ -1,35 +1,62 @@
 package db
 
 import (
-	"fmt"
+	"context"
 	"log"
-	"os"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+
+	"github.com/The-OpenPlatform/backend/internal/config"
+	"github.com/The-OpenPlatform/backend/internal/db/migrations"
 )
 
 var DB *sqlx.DB
 
-func MustConnect() {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"))
+// MustConnect connects to the database and applies pending schema migrations.
+// Automatic migration can be disabled with the db.auto_migrate setting, in
+// which case the schema is managed with the migrate subcommand instead.
+func MustConnect(cfg config.DB) {
+	MustConnectWithoutMigrations(cfg)
+
+	if !cfg.AutoMigrate {
+		return
+	}
+
+	if err := Migrate(context.Background()); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+}
 
+// MustConnectWithoutMigrations connects to the database without touching the schema.
+func MustConnectWithoutMigrations(cfg config.DB) {
 	var err error
-	DB, err = sqlx.Connect("postgres", connStr)
+	DB, err = sqlx.Connect("postgres", cfg.ConnString())
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
 	if err := DB.Ping(); err != nil {
 		log.Fatal("Failed to ping database:", err)
 	}
 }
 
+// Migrate applies all pending schema migrations to DB.
+func Migrate(ctx context.Context) error {
+	migrator, err := migrations.New(DB)
+	if err != nil {
+		return err
+	}
+
+	_, err = migrator.Up(ctx)
+	return err
+}
+
 func Close() {
 	if DB != nil {
 		DB.Close()
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS images;
+DROP TABLE IF EXISTS modules;
@@ -0,0 +1,18 @@
+-- Base tables for registered modules and their images.
+-- IF NOT EXISTS lets databases that were created by hand adopt the migrations.
+CREATE TABLE IF NOT EXISTS modules (
+    module_id  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name       VARCHAR(255) NOT NULL UNIQUE,
+    ip_port    TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS images (
+    module_id  UUID PRIMARY KEY REFERENCES modules (module_id) ON DELETE CASCADE,
+    image      BYTEA NOT NULL,
+    fileformat TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
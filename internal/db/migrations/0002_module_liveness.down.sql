@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS modules_status_last_seen_idx;
+ALTER TABLE modules DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE modules DROP COLUMN IF EXISTS status;
@@ -0,0 +1,6 @@
+-- Heartbeat tracking. Modules that never sent a heartbeat start out OFFLINE.
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'OFFLINE'
+    CHECK (status IN ('ONLINE', 'STALE', 'OFFLINE'));
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS modules_status_last_seen_idx ON modules (status, last_seen_at);
@@ -0,0 +1,13 @@
+-- History of active health probes against registered modules.
+CREATE TABLE IF NOT EXISTS module_probes (
+    probe_id   BIGSERIAL PRIMARY KEY,
+    module_id  UUID NOT NULL REFERENCES modules (module_id) ON DELETE CASCADE,
+    kind       TEXT NOT NULL,
+    healthy    BOOLEAN NOT NULL,
+    latency_ms BIGINT NOT NULL,
+    error      TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS module_probes_module_checked_idx ON module_probes (module_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS module_probes_checked_idx ON module_probes (checked_at);
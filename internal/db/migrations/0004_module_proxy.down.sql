@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS modules_created_at_idx;
+DROP INDEX IF EXISTS modules_name_pattern_idx;
+ALTER TABLE modules DROP COLUMN IF EXISTS proxy_timeout_ms;
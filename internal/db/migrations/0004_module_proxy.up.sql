@@ -0,0 +1,5 @@
+-- Per-module reverse proxy settings and list endpoint indexes.
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS proxy_timeout_ms BIGINT CHECK (proxy_timeout_ms > 0);
+
+CREATE INDEX IF NOT EXISTS modules_name_pattern_idx ON modules (name text_pattern_ops);
+CREATE INDEX IF NOT EXISTS modules_created_at_idx ON modules (created_at, module_id);
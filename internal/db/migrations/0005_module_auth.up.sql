@@ -0,0 +1,19 @@
+-- Join tokens admit new modules; module credentials authenticate them afterwards.
+-- Only SHA-256 hashes of the secrets are stored.
+CREATE TABLE IF NOT EXISTS join_tokens (
+    token_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token_hash  BYTEA NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    module_name TEXT,
+    max_uses    INTEGER CHECK (max_uses > 0),
+    uses        INTEGER NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    revoked_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS module_credentials (
+    module_id   UUID PRIMARY KEY REFERENCES modules (module_id) ON DELETE CASCADE,
+    secret_hash BYTEA NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
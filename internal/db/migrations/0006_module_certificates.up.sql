@@ -0,0 +1,11 @@
+-- Certificates issued by the internal CA. Rows outlive their module so that
+-- certificates of deleted modules can still be recognised and rejected.
+CREATE TABLE IF NOT EXISTS module_certificates (
+    serial     TEXT PRIMARY KEY,
+    module_id  UUID NOT NULL,
+    not_after  TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS module_certificates_module_id_idx ON module_certificates (module_id);
@@ -0,0 +1,3 @@
+DELETE FROM users WHERE password_hash IS NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
@@ -0,0 +1,4 @@
+-- Users signing in through an identity provider have no local password and
+-- are identified by their issuer and subject.
+ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id TEXT UNIQUE;
@@ -0,0 +1,13 @@
+-- API keys authenticate machine clients with a fixed set of scopes.
+-- Only SHA-256 hashes of the secrets are stored.
+CREATE TABLE IF NOT EXISTS api_keys (
+    key_id       UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    key_hash     BYTEA NOT NULL UNIQUE,
+    name         TEXT NOT NULL,
+    scopes       TEXT[] NOT NULL CHECK (cardinality(scopes) > 0),
+    created_by   TEXT NOT NULL DEFAULT '',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS query_audit;
+DROP TABLE IF EXISTS saved_queries;
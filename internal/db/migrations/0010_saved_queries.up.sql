@@ -0,0 +1,28 @@
+-- Named SQL statements operators run repeatedly, with default parameters.
+CREATE TABLE IF NOT EXISTS saved_queries (
+    query_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    sql         TEXT NOT NULL,
+    params      JSONB NOT NULL DEFAULT '[]',
+    created_by  TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every statement run through POST /api/query or a saved query.
+CREATE TABLE IF NOT EXISTS query_audit (
+    audit_id    BIGSERIAL PRIMARY KEY,
+    actor       TEXT NOT NULL DEFAULT '',
+    query_name  TEXT,
+    sql         TEXT NOT NULL,
+    params      JSONB NOT NULL DEFAULT '[]',
+    read_only   BOOLEAN NOT NULL,
+    started_at  TIMESTAMPTZ NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    rows        BIGINT NOT NULL DEFAULT 0,
+    success     BOOLEAN NOT NULL,
+    error       TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS query_audit_actor_idx ON query_audit (actor, audit_id);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS modules_tags_idx;
+
+ALTER TABLE modules
+    DROP COLUMN IF EXISTS capabilities,
+    DROP COLUMN IF EXISTS homepage,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS author,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS description;
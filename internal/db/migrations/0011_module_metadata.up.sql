@@ -0,0 +1,11 @@
+-- Descriptive metadata sent by modules when they register.
+ALTER TABLE modules
+    ADD COLUMN IF NOT EXISTS description  TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS version      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS author       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags         TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS homepage     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS capabilities TEXT[] NOT NULL DEFAULT '{}';
+
+-- GET /api/modules?tag= matches with tags @> $1
+CREATE INDEX IF NOT EXISTS modules_tags_idx ON modules USING GIN (tags);
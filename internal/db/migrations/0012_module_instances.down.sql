@@ -0,0 +1,5 @@
+ALTER TABLE module_probes DROP COLUMN IF EXISTS instance_id;
+
+ALTER TABLE modules DROP COLUMN IF EXISTS lb_policy;
+
+DROP TABLE IF EXISTS module_instances;
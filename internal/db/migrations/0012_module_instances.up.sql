@@ -0,0 +1,27 @@
+-- Replicas of a module. The address in modules.ip_port is the module's
+-- primary instance; more instances are added by replicas that register.
+CREATE TABLE IF NOT EXISTS module_instances (
+    instance_id  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    module_id    UUID NOT NULL REFERENCES modules (module_id) ON DELETE CASCADE,
+    ip_port      TEXT NOT NULL,
+    weight       INTEGER NOT NULL DEFAULT 1 CHECK (weight BETWEEN 1 AND 1000),
+    zone         TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL DEFAULT 'OFFLINE' CHECK (status IN ('ONLINE', 'STALE', 'OFFLINE')),
+    -- Latest probe result, NULL until the instance was probed
+    healthy      BOOLEAN,
+    last_seen_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (module_id, ip_port)
+);
+
+CREATE INDEX IF NOT EXISTS module_instances_status_last_seen_idx ON module_instances (status, last_seen_at);
+
+INSERT INTO module_instances (module_id, ip_port, status, last_seen_at)
+    SELECT module_id, ip_port, status, last_seen_at FROM modules
+    ON CONFLICT (module_id, ip_port) DO NOTHING;
+
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS lb_policy TEXT NOT NULL DEFAULT 'round_robin'
+    CHECK (lb_policy IN ('round_robin', 'least_connections', 'weighted'));
+
+ALTER TABLE module_probes ADD COLUMN IF NOT EXISTS instance_id UUID
+    REFERENCES module_instances (instance_id) ON DELETE CASCADE;
@@ -0,0 +1,26 @@
+ALTER TABLE module_instances
+    DROP CONSTRAINT IF EXISTS module_instances_module_id_host_port_key,
+    DROP COLUMN ip_port;
+
+ALTER TABLE module_instances ADD COLUMN ip_port TEXT;
+
+UPDATE module_instances SET
+    ip_port = CASE WHEN strpos(host, ':') > 0 THEN '[' || host || ']' ELSE host END || ':' || port::TEXT;
+
+ALTER TABLE module_instances
+    ALTER COLUMN ip_port SET NOT NULL,
+    DROP COLUMN host,
+    DROP COLUMN port,
+    ADD CONSTRAINT module_instances_module_id_ip_port_key UNIQUE (module_id, ip_port);
+
+ALTER TABLE modules DROP COLUMN ip_port;
+
+ALTER TABLE modules ADD COLUMN ip_port TEXT;
+
+UPDATE modules SET
+    ip_port = CASE WHEN strpos(host, ':') > 0 THEN '[' || host || ']' ELSE host END || ':' || port::TEXT;
+
+ALTER TABLE modules
+    ALTER COLUMN ip_port SET NOT NULL,
+    DROP COLUMN host,
+    DROP COLUMN port;
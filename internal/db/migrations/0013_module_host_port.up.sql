@@ -0,0 +1,36 @@
+-- Addresses are stored as host and port. The host is an IP address, without
+-- brackets for IPv6, or a DNS name. ip_port is derived from them for readers
+-- of the old column, with IPv6 hosts in brackets like net.JoinHostPort.
+ALTER TABLE modules
+    ADD COLUMN IF NOT EXISTS host TEXT,
+    ADD COLUMN IF NOT EXISTS port INTEGER CHECK (port BETWEEN 1 AND 65535);
+
+UPDATE modules SET
+    host = regexp_replace(regexp_replace(ip_port, ':[0-9]+$', ''), '^\[(.*)\]$', '\1'),
+    port = substring(ip_port FROM ':([0-9]+)$')::INTEGER;
+
+ALTER TABLE modules
+    ALTER COLUMN host SET NOT NULL,
+    ALTER COLUMN port SET NOT NULL,
+    DROP COLUMN ip_port,
+    ADD COLUMN ip_port TEXT GENERATED ALWAYS AS (
+        CASE WHEN strpos(host, ':') > 0 THEN '[' || host || ']' ELSE host END || ':' || port::TEXT
+    ) STORED;
+
+ALTER TABLE module_instances
+    ADD COLUMN IF NOT EXISTS host TEXT,
+    ADD COLUMN IF NOT EXISTS port INTEGER CHECK (port BETWEEN 1 AND 65535);
+
+UPDATE module_instances SET
+    host = regexp_replace(regexp_replace(ip_port, ':[0-9]+$', ''), '^\[(.*)\]$', '\1'),
+    port = substring(ip_port FROM ':([0-9]+)$')::INTEGER;
+
+-- Dropping ip_port also drops the unique constraint on (module_id, ip_port)
+ALTER TABLE module_instances
+    ALTER COLUMN host SET NOT NULL,
+    ALTER COLUMN port SET NOT NULL,
+    DROP COLUMN ip_port,
+    ADD COLUMN ip_port TEXT GENERATED ALWAYS AS (
+        CASE WHEN strpos(host, ':') > 0 THEN '[' || host || ']' ELSE host END || ':' || port::TEXT
+    ) STORED,
+    ADD CONSTRAINT module_instances_module_id_host_port_key UNIQUE (module_id, host, port);
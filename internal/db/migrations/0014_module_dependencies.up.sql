@@ -0,0 +1,11 @@
+-- Modules a module needs, by name. A dependency may name a module that is
+-- not registered yet. version_range is a semantic version constraint such
+-- as ">= 1.2, < 2", empty for any version.
+CREATE TABLE IF NOT EXISTS module_dependencies (
+    module_id     UUID NOT NULL REFERENCES modules (module_id) ON DELETE CASCADE,
+    name          VARCHAR(255) NOT NULL,
+    version_range TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (module_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS module_dependencies_name_idx ON module_dependencies (name);
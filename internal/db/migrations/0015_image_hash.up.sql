@@ -0,0 +1,4 @@
+-- SHA-256 of the image, kept up to date by the database so ETags and
+-- cache-busting URLs don't have to hash the image on every request.
+ALTER TABLE images ADD COLUMN IF NOT EXISTS hash TEXT
+    GENERATED ALWAYS AS (encode(sha256(image), 'hex')) STORED;
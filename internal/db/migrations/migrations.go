@@ -0,0 +1,291 @@
+// Package migrations contains the versioned SQL schema of the backend and
+// applies it to a database.
+//
+// Migrations are embedded into the binary as pairs of files named
+// NNNN_description.up.sql and NNNN_description.down.sql. Applied versions are
+// recorded in the schema_version table, and a PostgreSQL advisory lock ensures
+// that only one replica migrates at a time.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockID is the advisory lock key held while migrating.
+const lockID int64 = 0x6f70656e706c6174 // "openplat"
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load parses the migrations contained in fsys, ordered by version.
+// Every version must have both an up and a down script, and versions must be
+// contiguous starting at 1.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) needs both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous: expected %d, found %d", i+1, m.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+
+	// DryRun prints the SQL that would be executed instead of running it.
+	DryRun bool
+	// Out receives dry-run output. It defaults to the standard logger.
+	Out io.Writer
+}
+
+// New creates a migrator for the embedded migrations.
+func New(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest returns the newest version known to this binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the newest applied version, or 0 for an empty database.
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	var current int
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		var err error
+		current, err = currentVersion(ctx, conn)
+		return err
+	})
+	return current, err
+}
+
+// Up applies all pending migrations and returns the ones that were applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if current > m.Latest() {
+			return fmt.Errorf("database schema version %d is newer than this binary supports (%d)", current, m.Latest())
+		}
+
+		for _, migration := range m.migrations[current:] {
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the given number of most recently applied migrations and
+// returns the ones that were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *sqlx.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if current > m.Latest() {
+			return fmt.Errorf("database schema version %d is newer than this binary supports (%d)", current, m.Latest())
+		}
+
+		for v := current; v > 0 && len(reverted) < steps; v-- {
+			migration := m.migrations[v-1]
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// apply runs one migration in its own transaction and records the new version.
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, migration Migration, up bool) error {
+	script, direction := migration.Up, "up"
+	if !up {
+		script, direction = migration.Down, "down"
+	}
+
+	if m.DryRun {
+		m.printf("-- %04d_%s (%s)\n%s\n", migration.Version, migration.Name, direction, script)
+		return nil
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d (%s) %s failed: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_version (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_version WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record schema version %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	log.Printf("migration %04d_%s %s applied", migration.Version, migration.Name, direction)
+	return nil
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock.
+// Session-level advisory locks belong to a connection, so the lock, the
+// migrations and the unlock must all use the same one.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	// A dry run must not change the database, not even by creating schema_version
+	if !m.DryRun {
+		if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+			return fmt.Errorf("failed to create schema_version table: %w", err)
+		}
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) printf(format string, args ...interface{}) {
+	if m.Out != nil {
+		fmt.Fprintf(m.Out, format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// currentVersion returns the highest applied version, or 0 when the
+// schema_version table does not exist yet.
+func currentVersion(ctx context.Context, conn *sqlx.Conn) (int, error) {
+	var exists bool
+	if err := conn.GetContext(ctx, &exists, `SELECT to_regclass('schema_version') IS NOT NULL`); err != nil {
+		return 0, fmt.Errorf("failed to check for schema_version table: %w", err)
+	}
+
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	if err := conn.GetContext(ctx, &version, `SELECT COALESCE(MAX(version), 0) FROM schema_version`); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
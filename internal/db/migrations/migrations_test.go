@@ -0,0 +1,140 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := Load(files)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, m.Up, m.Name)
+		assert.NotEmpty(t, m.Down, m.Name)
+	}
+}
+
+func TestLoadRejectsInvalidSets(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("SELECT 1")},
+	})
+	assert.ErrorContains(t, err, "needs both up and down")
+
+	_, err = Load(fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("SELECT 1")},
+		"0001_a.down.sql": {Data: []byte("SELECT 1")},
+		"0003_c.up.sql":   {Data: []byte("SELECT 1")},
+		"0003_c.down.sql": {Data: []byte("SELECT 1")},
+	})
+	assert.ErrorContains(t, err, "contiguous")
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	migrations, err := Load(fstest.MapFS{
+		"0001_first.up.sql":    {Data: []byte("CREATE TABLE first ()")},
+		"0001_first.down.sql":  {Data: []byte("DROP TABLE first")},
+		"0002_second.up.sql":   {Data: []byte("CREATE TABLE second ()")},
+		"0002_second.down.sql": {Data: []byte("DROP TABLE second")},
+	})
+	require.NoError(t, err)
+
+	return &Migrator{db: sqlx.NewDb(mockDB, "sqlmock"), migrations: migrations}, mock
+}
+
+func expectVersion(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version))
+}
+
+func TestUpAppliesPendingMigrations(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_version`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectVersion(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE second`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_version`).WithArgs(2, "second").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "second", applied[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownRevertsMigrations(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_version`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectVersion(mock, 2)
+	for _, m := range []struct {
+		script  string
+		version int
+	}{{"DROP TABLE second", 2}, {"DROP TABLE first", 1}} {
+		mock.ExpectBegin()
+		mock.ExpectExec(m.script).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_version`).WithArgs(m.version).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := migrator.Down(context.Background(), 5)
+
+	require.NoError(t, err)
+	assert.Len(t, reverted, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDryRunExecutesNothing(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+	var out bytes.Buffer
+	migrator.DryRun = true
+	migrator.Out = &out
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.Contains(t, out.String(), "CREATE TABLE first ()")
+	assert.Contains(t, out.String(), "CREATE TABLE second ()")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpRejectsNewerSchema(t *testing.T) {
+	migrator, mock := newTestMigrator(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_version`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectVersion(mock, 7)
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := migrator.Up(context.Background())
+
+	assert.ErrorContains(t, err, "newer than this binary supports")
+}
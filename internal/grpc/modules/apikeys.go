@@ -0,0 +1,100 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+)
+
+// APIKeyMetadataKey carries the API key of a machine client.
+const APIKeyMetadataKey = "x-openplatform-api-key"
+
+// methodScopes lists the ModulesService methods that accept an API key and
+// the scope each requires. Heartbeats and certificate renewal are left to
+// the modules themselves.
+var methodScopes = map[string]apikeys.Scope{
+	ModulesService_HealthCheck_FullMethodName:  apikeys.ScopeModulesRead,
+	ModulesService_Register_FullMethodName:     apikeys.ScopeModulesWrite,
+	ModulesService_Reregister_FullMethodName:   apikeys.ScopeModulesWrite,
+	ModulesService_Setup_FullMethodName:        apikeys.ScopeModulesWrite,
+	ModulesService_Delete_FullMethodName:       apikeys.ScopeModulesWrite,
+	ModulesService_ListModules_FullMethodName:  apikeys.ScopeModulesRead,
+	ModulesService_GetModule_FullMethodName:    apikeys.ScopeModulesRead,
+	ModulesService_WatchModules_FullMethodName: apikeys.ScopeModulesRead,
+}
+
+// keyVerifier checks API keys.
+type keyVerifier interface {
+	Verify(ctx context.Context, token string) (*apikeys.Key, error)
+}
+
+// UnaryAPIKeyInterceptor authenticates ModulesService calls that carry an
+// API key and checks the key's scope for the method. The key is stored in
+// the context, where it stands in for the module credential and join token
+// that UnaryAuthInterceptor and Register would otherwise require. Calls
+// without a key and calls to other services pass through unchanged.
+func UnaryAPIKeyInterceptor(verifier keyVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		token := metadataValue(ctx, APIKeyMetadataKey)
+		if token == "" || !strings.HasPrefix(info.FullMethod, servicePrefix) {
+			return handler(ctx, req)
+		}
+
+		key, err := authenticateKey(ctx, verifier, token, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(apikeys.WithKey(ctx, key), req)
+	}
+}
+
+// StreamAPIKeyInterceptor is the streaming counterpart of
+// UnaryAPIKeyInterceptor.
+func StreamAPIKeyInterceptor(verifier keyVerifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		token := metadataValue(ss.Context(), APIKeyMetadataKey)
+		if token == "" || !strings.HasPrefix(info.FullMethod, servicePrefix) {
+			return handler(srv, ss)
+		}
+
+		key, err := authenticateKey(ss.Context(), verifier, token, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &checkedStream{
+			ServerStream: ss,
+			ctx:          apikeys.WithKey(ss.Context(), key),
+			check:        func(interface{}) error { return nil },
+		})
+	}
+}
+
+// authenticateKey verifies an API key and checks that it may call fullMethod.
+func authenticateKey(ctx context.Context, verifier keyVerifier, token, fullMethod string) (*apikeys.Key, error) {
+	key, err := verifier.Verify(ctx, token)
+	switch {
+	case errors.Is(err, apikeys.ErrInvalidKey):
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "failed to verify API key")
+	}
+
+	scope, ok := methodScopes[fullMethod]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "API keys cannot call this method")
+	}
+
+	if !key.Allows(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key requires the %s scope", scope)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,71 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+)
+
+// fakeKeys accepts a single API key.
+type fakeKeys struct {
+	token string
+	key   *apikeys.Key
+}
+
+func (f fakeKeys) Verify(_ context.Context, token string) (*apikeys.Key, error) {
+	if token != f.token {
+		return nil, apikeys.ErrInvalidKey
+	}
+	return f.key, nil
+}
+
+func TestUnaryAPIKeyInterceptor(t *testing.T) {
+	keys := fakeKeys{token: "opk_good", key: &apikeys.Key{Name: "ci", Scopes: pq.StringArray{"modules:write"}}}
+
+	// unary runs a call through both interceptors in the order the server
+	// chains them
+	unary := func(ctx context.Context, req interface{}, method string) error {
+		chained := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return UnaryAuthInterceptor(fakeVerifier{credential: "good", moduleID: "00000000-0000-4000-a000-000000000001"})(ctx, req, &grpc.UnaryServerInfo{FullMethod: method},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					_, byKey := apikeys.FromContext(ctx)
+					return byKey, nil
+				})
+		}
+		_, err := UnaryAPIKeyInterceptor(keys)(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, chained)
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"key stands in for credential", withMetadata(APIKeyMetadataKey, "opk_good"), ModulesService_Delete_FullMethodName, &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.OK},
+		{"invalid key", withMetadata(APIKeyMetadataKey, "opk_bad"), ModulesService_Setup_FullMethodName, &SetupRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.Unauthenticated},
+		{"method reserved for modules", withMetadata(APIKeyMetadataKey, "opk_good"), ModulesService_Heartbeat_FullMethodName, &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.PermissionDenied},
+		{"no key needs credential", withMetadata(CredentialMetadataKey, "bad"), ModulesService_Setup_FullMethodName, &SetupRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.Unauthenticated},
+		{"other service", withMetadata(APIKeyMetadataKey, "opk_bad"), "/grpc.health.v1.Health/Check", nil, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, status.Code(unary(tt.ctx, tt.req, tt.method)))
+		})
+	}
+
+	readOnly := fakeKeys{token: "opk_read", key: &apikeys.Key{Name: "ci", Scopes: pq.StringArray{"modules:read"}}}
+	_, err := UnaryAPIKeyInterceptor(readOnly)(withMetadata(APIKeyMetadataKey, "opk_read"), &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000001"},
+		&grpc.UnaryServerInfo{FullMethod: ModulesService_Delete_FullMethodName},
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,171 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/auth"
+)
+
+// Metadata keys carrying module authentication.
+const (
+	// JoinTokenMetadataKey carries the join token presented to Register.
+	JoinTokenMetadataKey = "x-openplatform-join-token"
+	// CredentialMetadataKey carries the credential returned by Register.
+	CredentialMetadataKey = "x-openplatform-module-credential"
+)
+
+// servicePrefix is the prefix of every full method name of ModulesService.
+var servicePrefix = "/" + ModulesService_ServiceDesc.ServiceName + "/"
+
+// publicMethods can be called without a module credential. Register
+// authenticates with a join token inside the handler instead, because the
+// token is scoped to the name being registered.
+var publicMethods = map[string]bool{
+	ModulesService_HealthCheck_FullMethodName: true,
+	ModulesService_Register_FullMethodName:    true,
+}
+
+// moduleScoped is implemented by requests that act on a single module.
+type moduleScoped interface {
+	GetModuleId() string
+}
+
+type moduleIDKey struct{}
+
+// AuthenticatedModule returns the ID of the module whose credential
+// authenticated the call, if any.
+func AuthenticatedModule(ctx context.Context) (string, bool) {
+	moduleID, ok := ctx.Value(moduleIDKey{}).(string)
+	return moduleID, ok
+}
+
+// credentialVerifier checks module credentials.
+type credentialVerifier interface {
+	VerifyCredential(ctx context.Context, credential string) (string, error)
+}
+
+// UnaryAuthInterceptor requires a valid module credential on every
+// ModulesService call except the public ones, and rejects requests that act
+// on a different module than the one the credential belongs to. Calls to
+// other services and calls authenticated by UnaryAPIKeyInterceptor pass
+// through unchanged.
+func UnaryAuthInterceptor(verifier credentialVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !requiresCredential(ctx, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		moduleID, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkScope(moduleID, req); err != nil {
+			return nil, err
+		}
+
+		return handler(context.WithValue(ctx, moduleIDKey{}, moduleID), req)
+	}
+}
+
+// StreamAuthInterceptor is the streaming counterpart of UnaryAuthInterceptor.
+// The credential is checked when the stream opens and every received message
+// is checked against the authenticated module.
+func StreamAuthInterceptor(verifier credentialVerifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !requiresCredential(ss.Context(), info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		moduleID, err := authenticate(ss.Context(), verifier)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &checkedStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), moduleIDKey{}, moduleID),
+			check: func(m interface{}) error {
+				return checkScope(moduleID, m)
+			},
+		})
+	}
+}
+
+// checkedStream runs check on every message received on a stream.
+type checkedStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	check func(m interface{}) error
+}
+
+func (s *checkedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *checkedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.check(m)
+}
+
+func requiresCredential(ctx context.Context, fullMethod string) bool {
+	if _, byKey := apikeys.FromContext(ctx); byKey {
+		return false
+	}
+	return strings.HasPrefix(fullMethod, servicePrefix) && !publicMethods[fullMethod]
+}
+
+// authenticate verifies the credential in the incoming metadata.
+func authenticate(ctx context.Context, verifier credentialVerifier) (string, error) {
+	credential := metadataValue(ctx, CredentialMetadataKey)
+	if credential == "" {
+		return "", status.Error(codes.Unauthenticated, "module credential required")
+	}
+
+	moduleID, err := verifier.VerifyCredential(ctx, credential)
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredential):
+		return "", status.Error(codes.Unauthenticated, "invalid module credential")
+	case err != nil:
+		return "", status.Error(codes.Internal, "failed to verify module credential")
+	}
+
+	return moduleID, nil
+}
+
+// checkScope rejects requests for modules other than the authenticated one.
+func checkScope(moduleID string, req interface{}) error {
+	if _, ok := req.(*GetModuleRequest); ok {
+		// GetModule looks up other modules for discovery
+		return nil
+	}
+
+	scoped, ok := req.(moduleScoped)
+	if ok && !strings.EqualFold(scoped.GetModuleId(), moduleID) {
+		return status.Error(codes.PermissionDenied, "credential does not belong to the requested module")
+	}
+	return nil
+}
+
+// metadataValue returns the first value of key in the incoming metadata.
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,285 @@
+package modules
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/auth"
+	"github.com/The-OpenPlatform/backend/internal/db"
+)
+
+// fakeVerifier accepts a single credential.
+type fakeVerifier struct {
+	credential, moduleID string
+}
+
+func (f fakeVerifier) VerifyCredential(_ context.Context, credential string) (string, error) {
+	if credential != f.credential {
+		return "", auth.ErrInvalidCredential
+	}
+	return f.moduleID, nil
+}
+
+func withMetadata(key, value string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(key, value))
+}
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	interceptor := UnaryAuthInterceptor(fakeVerifier{credential: "good", moduleID: "00000000-0000-4000-a000-000000000001"})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		moduleID, _ := AuthenticatedModule(ctx)
+		return moduleID, nil
+	}
+	info := func(method string) *grpc.UnaryServerInfo { return &grpc.UnaryServerInfo{FullMethod: method} }
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"public method", context.Background(), ModulesService_HealthCheck_FullMethodName, &HealthCheckRequest{}, codes.OK},
+		{"register uses join token", context.Background(), ModulesService_Register_FullMethodName, &RegisterRequest{}, codes.OK},
+		{"other service", context.Background(), "/grpc.health.v1.Health/Check", nil, codes.OK},
+		{"missing credential", context.Background(), ModulesService_Setup_FullMethodName, &SetupRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.Unauthenticated},
+		{"wrong credential", withMetadata(CredentialMetadataKey, "bad"), ModulesService_Delete_FullMethodName, &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.Unauthenticated},
+		{"other module", withMetadata(CredentialMetadataKey, "good"), ModulesService_Delete_FullMethodName, &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.PermissionDenied},
+		{"own module", withMetadata(CredentialMetadataKey, "good"), ModulesService_Heartbeat_FullMethodName, &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.OK},
+		{"look up other module", withMetadata(CredentialMetadataKey, "good"), ModulesService_GetModule_FullMethodName, &GetModuleRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := interceptor(tt.ctx, tt.req, info(tt.method), handler)
+			assert.Equal(t, tt.code, status.Code(err))
+			if tt.code == codes.OK && tt.method == ModulesService_Heartbeat_FullMethodName {
+				assert.Equal(t, "00000000-0000-4000-a000-000000000001", resp)
+			}
+		})
+	}
+}
+
+// recvStream is a server stream that receives a fixed message.
+type recvStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *HeartbeatRequest
+}
+
+func (s *recvStream) Context() context.Context { return s.ctx }
+
+func (s *recvStream) RecvMsg(m interface{}) error {
+	m.(*HeartbeatRequest).ModuleId = s.req.ModuleId
+	return nil
+}
+
+func TestStreamAuthInterceptor(t *testing.T) {
+	interceptor := StreamAuthInterceptor(fakeVerifier{credential: "good", moduleID: "00000000-0000-4000-a000-000000000001"})
+	info := &grpc.StreamServerInfo{FullMethod: ModulesService_HeartbeatStream_FullMethodName}
+	recv := func(_ interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&HeartbeatRequest{})
+	}
+
+	err := interceptor(nil, &recvStream{ctx: context.Background(), req: &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}}, info, recv)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := withMetadata(CredentialMetadataKey, "good")
+	err = interceptor(nil, &recvStream{ctx: ctx, req: &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}}, info, recv)
+	assert.NoError(t, err)
+
+	err = interceptor(nil, &recvStream{ctx: ctx, req: &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}}, info, recv)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestRegisterWithJoinToken(t *testing.T) {
+	t.Run("rejects missing token", func(t *testing.T) {
+		mock := setupMockDB(t)
+		s := &Server{Auth: auth.NewStore(db.DB)}
+
+		_, err := s.Register(context.Background(), &RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("issues credential", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectQuery(`UPDATE join_tokens SET uses = uses \+ 1`).
+			WithArgs(sqlmock.AnyArg(), "dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"token_id"}).AddRow("t1"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+		mock.ExpectExec(`INSERT INTO module_credentials`).WithArgs("00000000-0000-4000-a000-000000000001", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		resp, err := s.Register(withMetadata(JoinTokenMetadataKey, "opj_abc"),
+			&RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080})
+
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Regexp(t, `^opm_00000000-0000-4000-a000-000000000001_[0-9a-f]{64}$`, resp.Credential)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("accepts API key instead", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+		mock.ExpectExec(`INSERT INTO module_credentials`).WithArgs("00000000-0000-4000-a000-000000000001", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		ctx := apikeys.WithKey(context.Background(), &apikeys.Key{Name: "ci"})
+		resp, err := s.Register(ctx, &RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080})
+
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.NotEmpty(t, resp.Credential)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("releases token when registration fails", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectQuery(`UPDATE join_tokens SET uses = uses \+ 1`).
+			WillReturnRows(sqlmock.NewRows([]string{"token_id"}).AddRow("t1"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+		mock.ExpectExec(`UPDATE join_tokens SET uses = uses - 1`).WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		resp, err := s.Register(withMetadata(JoinTokenMetadataKey, "opj_abc"),
+			&RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080})
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Empty(t, resp.Credential)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRegisterIdempotent(t *testing.T) {
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"description", "version", "author", "tags", "homepage", "capabilities"}
+	existing := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT module_id, name, host.* WHERE name = \$1`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "OFFLINE", nil, nil, "", "", "", "{}", "", "{}"))
+	}
+	reregistered := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT module_id, name, host.* WHERE module_id = \$1`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "OFFLINE", nil, nil, "", "", "", "{}", "", "{}"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM module_instances`).WithArgs("00000000-0000-4000-a000-000000000001", "10.0.0.9", int32(8080)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`UPDATE modules SET host`).WithArgs("10.0.0.9", int32(8080), "", "", "", sqlmock.AnyArg(), "", sqlmock.AnyArg(), "00000000-0000-4000-a000-000000000001").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO module_dependencies`).WithArgs("00000000-0000-4000-a000-000000000001", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT module_id, name, host.* WHERE module_id = \$1`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.9", 8080, "10.0.0.9:8080", "ONLINE", nil, nil, "", "", "", "{}", "", "{}"))
+	}
+	req := &RegisterRequest{Name: "dashboard", Ip: "10.0.0.9", Port: 8080, Idempotent: true}
+
+	t.Run("returns existing module to its owner", func(t *testing.T) {
+		mock := setupMockDB(t)
+		existing(mock)
+		secret := sha256.Sum256([]byte("secret"))
+		mock.ExpectQuery(`SELECT secret_hash FROM module_credentials`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"secret_hash"}).AddRow(secret[:]))
+		reregistered(mock)
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		resp, err := s.Register(withMetadata(CredentialMetadataKey, "opm_00000000-0000-4000-a000-000000000001_secret"), req)
+
+		require.NoError(t, err)
+		assert.True(t, resp.Success, resp.Message)
+		assert.True(t, resp.Existing)
+		assert.Equal(t, "00000000-0000-4000-a000-000000000001", resp.ModuleId)
+		assert.Empty(t, resp.Credential)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("issues new credential to API key", func(t *testing.T) {
+		mock := setupMockDB(t)
+		existing(mock)
+		reregistered(mock)
+		mock.ExpectExec(`INSERT INTO module_credentials`).WithArgs("00000000-0000-4000-a000-000000000001", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		ctx := apikeys.WithKey(context.Background(), &apikeys.Key{Name: "ci"})
+		resp, err := s.Register(ctx, req)
+
+		require.NoError(t, err)
+		assert.True(t, resp.Existing)
+		assert.Regexp(t, `^opm_00000000-0000-4000-a000-000000000001_`, resp.Credential)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects callers without proof", func(t *testing.T) {
+		mock := setupMockDB(t)
+		existing(mock)
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		resp, err := s.Register(withMetadata(JoinTokenMetadataKey, "opj_abc"), req)
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Module with the same name already exists", resp.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects invalid credential", func(t *testing.T) {
+		mock := setupMockDB(t)
+		existing(mock)
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		_, err := s.Register(withMetadata(CredentialMetadataKey, "bogus"), req)
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("registers free name as usual", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectQuery(`SELECT module_id, name, host.* WHERE name = \$1`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(`UPDATE join_tokens SET uses = uses \+ 1`).
+			WillReturnRows(sqlmock.NewRows([]string{"token_id"}).AddRow("t1"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000002"))
+		mock.ExpectCommit()
+		mock.ExpectExec(`INSERT INTO module_credentials`).WithArgs("00000000-0000-4000-a000-000000000002", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{Auth: auth.NewStore(db.DB)}
+		resp, err := s.Register(withMetadata(JoinTokenMetadataKey, "opj_abc"), req)
+
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.False(t, resp.Existing)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
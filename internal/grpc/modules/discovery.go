@@ -0,0 +1,226 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// Types of the events sent by WatchModules.
+const (
+	EventAdded   = "ADDED"
+	EventUpdated = "UPDATED"
+	EventRemoved = "REMOVED"
+)
+
+// DefaultWatchInterval is how often WatchModules looks for changes when the
+// server was constructed without a WatchInterval.
+const DefaultWatchInterval = 5 * time.Second
+
+// ListModules returns the registered modules, sorted by name, that carry
+// all of the requested tags and capabilities.
+func (s *Server) ListModules(ctx context.Context, req *ListModulesRequest) (*ListModulesResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("list modules request cannot be nil")
+	}
+
+	modules, err := s.discover(ctx, service.ListFilter{Tags: req.Tags, Capabilities: req.Capabilities})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListModulesResponse{Modules: modules}, nil
+}
+
+// GetModule looks up a single module by ID or, when no ID is given, by name.
+// Unlike the other module-scoped RPCs, any module may look up any other.
+func (s *Server) GetModule(ctx context.Context, req *GetModuleRequest) (*GetModuleResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("get module request cannot be nil")
+	}
+
+	var module *service.Module
+	var err error
+	switch {
+	case req.ModuleId != "":
+		module, err = s.service().Get(ctx, req.ModuleId)
+	case req.Name != "":
+		module, err = s.service().FindByName(ctx, req.Name)
+	default:
+		return &GetModuleResponse{
+			Success: false,
+			Message: "Validation failed: module_id or name is required",
+		}, nil
+	}
+
+	// A module deleted meanwhile has no instances left, which is reported
+	// as not found as well
+	var instances []service.Instance
+	if err == nil {
+		instances, err = s.service().Instances(ctx, module.ModuleID)
+	}
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return &GetModuleResponse{
+			Success: false,
+			Message: "Module not found",
+		}, nil
+	case err != nil:
+		return &GetModuleResponse{
+			Success: false,
+			Message: "Module lookup failed",
+		}, fmt.Errorf("failed to look up module: %w", err)
+	}
+
+	return &GetModuleResponse{
+		Success: true,
+		Message: "Module found",
+		Module:  moduleInfo(*module, instances),
+	}, nil
+}
+
+// WatchModules streams changes to the modules matching the request's tags
+// and capabilities. It first sends an ADDED event for every matching module,
+// then polls the registry and sends ADDED, UPDATED and REMOVED events as
+// modules appear, change or stop matching, until the client goes away.
+func (s *Server) WatchModules(req *WatchModulesRequest, stream ModulesService_WatchModulesServer) error {
+	if req == nil {
+		return fmt.Errorf("watch modules request cannot be nil")
+	}
+
+	ctx := stream.Context()
+	filter := service.ListFilter{Tags: req.Tags, Capabilities: req.Capabilities}
+	ticker := time.NewTicker(s.watchInterval())
+	defer ticker.Stop()
+
+	known := make(map[string]*ModuleInfo)
+	for {
+		modules, err := s.discover(ctx, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for _, event := range diffModules(known, modules) {
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// discover loads the modules matching filter together with their instances.
+func (s *Server) discover(ctx context.Context, filter service.ListFilter) ([]*ModuleInfo, error) {
+	modules, err := s.service().List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	ids := make([]string, 0, len(modules))
+	for _, module := range modules {
+		ids = append(ids, module.ModuleID)
+	}
+
+	instances, err := s.service().InstancesOf(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	infos := make([]*ModuleInfo, 0, len(modules))
+	for _, module := range modules {
+		infos = append(infos, moduleInfo(module, instances[module.ModuleID]))
+	}
+
+	return infos, nil
+}
+
+// diffModules returns the events that turn known into current and updates
+// known to match. Removed modules are reported last, sorted by name.
+func diffModules(known map[string]*ModuleInfo, current []*ModuleInfo) []*ModuleEvent {
+	var events []*ModuleEvent
+	seen := make(map[string]bool, len(current))
+	for _, module := range current {
+		seen[module.ModuleId] = true
+
+		previous, ok := known[module.ModuleId]
+		switch {
+		case !ok:
+			events = append(events, &ModuleEvent{Type: EventAdded, Module: module})
+		case !proto.Equal(previous, module):
+			events = append(events, &ModuleEvent{Type: EventUpdated, Module: module})
+		default:
+			continue
+		}
+		known[module.ModuleId] = module
+	}
+
+	var removed []*ModuleInfo
+	for id, module := range known {
+		if !seen[id] {
+			removed = append(removed, module)
+			delete(known, id)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Name < removed[j].Name })
+	for _, module := range removed {
+		events = append(events, &ModuleEvent{Type: EventRemoved, Module: module})
+	}
+
+	return events
+}
+
+// moduleInfo converts a module and its instances to their wire form.
+func moduleInfo(module service.Module, instances []service.Instance) *ModuleInfo {
+	info := &ModuleInfo{
+		ModuleId:     module.ModuleID,
+		Name:         module.Name,
+		Host:         module.Host,
+		Port:         module.Port,
+		Status:       module.Status,
+		Description:  module.Description,
+		Version:      module.Version,
+		Author:       module.Author,
+		Tags:         module.Tags,
+		Homepage:     module.Homepage,
+		Capabilities: module.Capabilities,
+		LbPolicy:     module.LBPolicy,
+	}
+
+	for _, instance := range instances {
+		info.Instances = append(info.Instances, &InstanceInfo{
+			InstanceId: instance.InstanceID,
+			Host:       instance.Host,
+			Port:       instance.Port,
+			Weight:     instance.Weight,
+			Zone:       instance.Zone,
+			Status:     instance.Status,
+			Healthy:    instance.Healthy == nil || *instance.Healthy,
+			Primary:    instance.Primary,
+		})
+	}
+
+	return info
+}
+
+// watchInterval returns the configured polling interval of WatchModules, or
+// DefaultWatchInterval when none is set.
+func (s *Server) watchInterval() time.Duration {
+	if s.WatchInterval <= 0 {
+		return DefaultWatchInterval
+	}
+	return s.WatchInterval
+}
@@ -0,0 +1,143 @@
+package modules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+var (
+	discoveryModuleColumns = []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"lb_policy", "description", "version", "author", "tags", "homepage", "capabilities"}
+	discoveryInstanceColumns = []string{"instance_id", "module_id", "host", "port", "ip_port", "weight", "zone", "status",
+		"healthy", "last_seen_at", "is_primary"}
+)
+
+func TestListModules(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT module_id, name, host.* FROM modules WHERE tags @> \$1 AND capabilities @> \$2`).
+		WithArgs(pq.StringArray{"auth"}, pq.StringArray{}).
+		WillReturnRows(sqlmock.NewRows(discoveryModuleColumns).
+			AddRow("00000000-0000-4000-a000-000000000001", "auth", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "round_robin", "", "1.4.0", "", "{auth}", "", "{auth.login}"))
+	mock.ExpectQuery(`FROM module_instances i`).WithArgs(pq.StringArray{"00000000-0000-4000-a000-000000000001"}).
+		WillReturnRows(sqlmock.NewRows(discoveryInstanceColumns).
+			AddRow("00000000-0000-4000-9000-000000000001", "00000000-0000-4000-a000-000000000001", "10.0.0.1", 8080, "10.0.0.1:8080", 1, "", "ONLINE", nil, nil, true).
+			AddRow("00000000-0000-4000-9000-000000000002", "00000000-0000-4000-a000-000000000001", "10.0.0.2", 8080, "10.0.0.2:8080", 2, "eu", "ONLINE", false, nil, false))
+
+	resp, err := (&Server{}).ListModules(context.Background(), &ListModulesRequest{Tags: []string{" Auth "}})
+	require.NoError(t, err)
+
+	require.Len(t, resp.Modules, 1)
+	module := resp.Modules[0]
+	assert.Equal(t, "auth", module.Name)
+	assert.Equal(t, []string{"auth.login"}, module.Capabilities)
+	require.Len(t, module.Instances, 2)
+	assert.True(t, module.Instances[0].Healthy)
+	assert.True(t, module.Instances[0].Primary)
+	assert.False(t, module.Instances[1].Healthy)
+	assert.Equal(t, "eu", module.Instances[1].Zone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetModule(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT module_id, name, host.* WHERE name = \$1`).WithArgs("auth").
+		WillReturnRows(sqlmock.NewRows(discoveryModuleColumns).
+			AddRow("00000000-0000-4000-a000-000000000001", "auth", "auth.platform.svc", 8080, "auth.platform.svc:8080", "ONLINE", nil, nil, "round_robin", "", "", "", "{}", "", "{}"))
+	mock.ExpectQuery(`FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(discoveryInstanceColumns).
+			AddRow("00000000-0000-4000-9000-000000000001", "00000000-0000-4000-a000-000000000001", "auth.platform.svc", 8080, "auth.platform.svc:8080", 1, "", "ONLINE", true, nil, true))
+	mock.ExpectQuery(`SELECT module_id, name, host.* WHERE module_id = \$1`).WithArgs("00000000-0000-4000-a000-000000000009").
+		WillReturnRows(sqlmock.NewRows(discoveryModuleColumns))
+
+	s := &Server{}
+	resp, err := s.GetModule(context.Background(), &GetModuleRequest{Name: "auth"})
+	require.NoError(t, err)
+	assert.True(t, resp.Success, resp.Message)
+	assert.Equal(t, "auth.platform.svc", resp.Module.Host)
+	assert.Len(t, resp.Module.Instances, 1)
+
+	resp, err = s.GetModule(context.Background(), &GetModuleRequest{ModuleId: "00000000-0000-4000-a000-000000000009"})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "Module not found", resp.Message)
+
+	resp, err = s.GetModule(context.Background(), &GetModuleRequest{})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDiffModules(t *testing.T) {
+	known := make(map[string]*ModuleInfo)
+
+	events := diffModules(known, []*ModuleInfo{{ModuleId: "00000000-0000-4000-a000-000000000001", Name: "auth"}, {ModuleId: "00000000-0000-4000-a000-000000000002", Name: "storage"}})
+	require.Len(t, events, 2)
+	assert.Equal(t, EventAdded, events[0].Type)
+	assert.Equal(t, EventAdded, events[1].Type)
+
+	assert.Empty(t, diffModules(known, []*ModuleInfo{{ModuleId: "00000000-0000-4000-a000-000000000001", Name: "auth"}, {ModuleId: "00000000-0000-4000-a000-000000000002", Name: "storage"}}))
+
+	events = diffModules(known, []*ModuleInfo{{ModuleId: "00000000-0000-4000-a000-000000000001", Name: "auth", Status: "STALE"}, {ModuleId: "00000000-0000-4000-a000-000000000003", Name: "mailer"}})
+	require.Len(t, events, 3)
+	assert.Equal(t, EventUpdated, events[0].Type)
+	assert.Equal(t, "STALE", events[0].Module.Status)
+	assert.Equal(t, EventAdded, events[1].Type)
+	assert.Equal(t, EventRemoved, events[2].Type)
+	assert.Equal(t, "storage", events[2].Module.Name)
+	assert.Len(t, known, 2)
+}
+
+// watchStream collects the events sent by WatchModules and cancels the
+// stream once it has received enough.
+type watchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	want   int
+	events []*ModuleEvent
+}
+
+func (s *watchStream) Context() context.Context { return s.ctx }
+
+func (s *watchStream) Send(event *ModuleEvent) error {
+	s.events = append(s.events, event)
+	if len(s.events) == s.want {
+		s.cancel()
+	}
+	return nil
+}
+
+func TestWatchModules(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`FROM modules WHERE tags`).
+		WillReturnRows(sqlmock.NewRows(discoveryModuleColumns).
+			AddRow("00000000-0000-4000-a000-000000000001", "auth", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "round_robin", "", "", "", "{}", "", "{}").
+			AddRow("00000000-0000-4000-a000-000000000002", "storage", "10.0.0.2", 8080, "10.0.0.2:8080", "ONLINE", nil, nil, "round_robin", "", "", "", "{}", "", "{}"))
+	mock.ExpectQuery(`FROM module_instances i`).WillReturnRows(sqlmock.NewRows(discoveryInstanceColumns))
+	mock.ExpectQuery(`FROM modules WHERE tags`).
+		WillReturnRows(sqlmock.NewRows(discoveryModuleColumns).
+			AddRow("00000000-0000-4000-a000-000000000001", "auth", "10.0.0.9", 8080, "10.0.0.9:8080", "ONLINE", nil, nil, "round_robin", "", "", "", "{}", "", "{}"))
+	mock.ExpectQuery(`FROM module_instances i`).WillReturnRows(sqlmock.NewRows(discoveryInstanceColumns))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &watchStream{ctx: ctx, cancel: cancel, want: 4}
+
+	err := (&Server{WatchInterval: time.Millisecond}).WatchModules(&WatchModulesRequest{}, stream)
+	require.NoError(t, err)
+
+	require.Len(t, stream.events, 4)
+	var types []string
+	for _, event := range stream.events {
+		types = append(types, event.Type+" "+event.Module.Name)
+	}
+	assert.Equal(t, []string{"ADDED auth", "ADDED storage", "UPDATED auth", "REMOVED storage"}, types)
+	assert.Equal(t, "10.0.0.9", stream.events[2].Module.Host)
+}
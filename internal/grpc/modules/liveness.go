@@ -0,0 +1,148 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/The-OpenPlatform/backend/internal/db"
+)
+
+// Liveness states a module can be in.
+const (
+	StatusOnline  = "ONLINE"
+	StatusStale   = "STALE"
+	StatusOffline = "OFFLINE"
+)
+
+// LivenessConfig describes how often modules are expected to send heartbeats
+// and how many intervals they may miss before being downgraded.
+type LivenessConfig struct {
+	// Interval is the heartbeat interval advertised to modules and the
+	// period at which the reaper sweeps the modules table.
+	Interval time.Duration
+	// StaleAfter is the number of missed intervals after which an ONLINE
+	// module is marked STALE.
+	StaleAfter int
+	// OfflineAfter is the number of missed intervals after which a module
+	// is marked OFFLINE.
+	OfflineAfter int
+	// InstanceTTL is how long an instance other than a module's primary one
+	// may go without heartbeats before it is removed. Zero keeps them.
+	InstanceTTL time.Duration
+}
+
+// DefaultLivenessConfig returns the liveness settings used when none are configured.
+func DefaultLivenessConfig() LivenessConfig {
+	return LivenessConfig{
+		Interval:     10 * time.Second,
+		StaleAfter:   3,
+		OfflineAfter: 6,
+		InstanceTTL:  time.Hour,
+	}
+}
+
+// Validate checks that the thresholds are positive and ordered.
+func (c LivenessConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("heartbeat interval must be positive")
+	}
+
+	if c.StaleAfter <= 0 {
+		return fmt.Errorf("stale threshold must be positive")
+	}
+
+	if c.OfflineAfter <= c.StaleAfter {
+		return fmt.Errorf("offline threshold (%d) must be greater than stale threshold (%d)", c.OfflineAfter, c.StaleAfter)
+	}
+
+	if c.InstanceTTL < 0 {
+		return fmt.Errorf("instance TTL cannot be negative")
+	}
+
+	return nil
+}
+
+// Reaper periodically downgrades modules and instances that stopped sending
+// heartbeats and removes instances that have been gone for too long.
+type Reaper struct {
+	cfg LivenessConfig
+}
+
+// NewReaper creates a reaper using the given liveness settings.
+func NewReaper(cfg LivenessConfig) *Reaper {
+	return &Reaper{cfg: cfg}
+}
+
+// Run sweeps the modules and instances once per heartbeat interval until ctx is cancelled.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Sweep(ctx); err != nil {
+				log.Printf("liveness sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// Sweep marks modules and their instances STALE or OFFLINE based on their
+// last-seen timestamp and removes expired instances. It returns the number
+// of modules and instances that changed.
+func (r *Reaper) Sweep(ctx context.Context) (int64, error) {
+	var changed int64
+	for _, table := range []string{"modules", "module_instances"} {
+		offlineQuery := `UPDATE ` + table + ` SET status = 'OFFLINE'
+			WHERE status <> 'OFFLINE' AND last_seen_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'`
+		staleQuery := `UPDATE ` + table + ` SET status = 'STALE'
+			WHERE status = 'ONLINE' AND last_seen_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'`
+
+		offline, err := r.exec(ctx, offlineQuery, r.threshold(r.cfg.OfflineAfter))
+		changed += offline
+		if err != nil {
+			return changed, fmt.Errorf("failed to mark %s offline: %w", table, err)
+		}
+
+		stale, err := r.exec(ctx, staleQuery, r.threshold(r.cfg.StaleAfter))
+		changed += stale
+		if err != nil {
+			return changed, fmt.Errorf("failed to mark %s stale: %w", table, err)
+		}
+	}
+
+	if r.cfg.InstanceTTL <= 0 {
+		return changed, nil
+	}
+
+	expireQuery := `DELETE FROM module_instances i USING modules m
+		WHERE m.module_id = i.module_id AND (i.host, i.port) <> (m.host, m.port)
+			AND COALESCE(i.last_seen_at, i.created_at) < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'`
+
+	expired, err := r.exec(ctx, expireQuery, r.cfg.InstanceTTL.Seconds())
+	changed += expired
+	if err != nil {
+		return changed, fmt.Errorf("failed to remove expired instances: %w", err)
+	}
+
+	return changed, nil
+}
+
+// threshold converts a number of missed intervals into seconds.
+func (r *Reaper) threshold(missed int) float64 {
+	return (time.Duration(missed) * r.cfg.Interval).Seconds()
+}
+
+func (r *Reaper) exec(ctx context.Context, query string, seconds float64) (int64, error) {
+	result, err := db.DB.ExecContext(ctx, query, seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -1,30 +1,76 @@
 // Package modules provides gRPC service implementation for module management.
-// It handles module registration, setup, deletion, and health checking operations
-// with comprehensive input validation and error handling.
+// It handles module registration, setup, deletion, heartbeats, health checking and discovery
+// operations with comprehensive input validation and error handling.
 package modules
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net"
 	"strings"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/The-OpenPlatform/backend/internal/apikeys"
+	"github.com/The-OpenPlatform/backend/internal/auth"
+	"github.com/The-OpenPlatform/backend/internal/ca"
 	"github.com/The-OpenPlatform/backend/internal/db"
+	"github.com/The-OpenPlatform/backend/internal/probe"
+	"github.com/The-OpenPlatform/backend/internal/service"
 )
 
 // Server implements the ModulesServiceServer interface and provides
 // methods for managing modules in the system.
 type Server struct {
 	UnimplementedModulesServiceServer
+
+	// Liveness controls the heartbeat interval advertised to modules.
+	// The zero value falls back to DefaultLivenessConfig.
+	Liveness LivenessConfig
+
+	// Modules is the shared module service. When nil, a service backed by
+	// db.DB is used.
+	Modules *service.Modules
+
+	// Auth, when set, makes Register require a join token and issue a
+	// module credential. Credentials are enforced by UnaryAuthInterceptor
+	// and StreamAuthInterceptor.
+	Auth *auth.Store
+
+	// CA, when set, signs client certificates for modules that send a CSR
+	// with Register or RenewCertificate.
+	CA *ca.Issuer
+
+	// InferAddress lets modules omit their IP in Register and Reregister.
+	// The host they connect from is used instead, so it only fits
+	// deployments without NAT or proxies between modules and the backend.
+	InferAddress bool
+
+	// WatchInterval is how often WatchModules polls for changes. The zero
+	// value falls back to DefaultWatchInterval.
+	WatchInterval time.Duration
 }
 
 // HealthCheck returns the health status of the modules service.
-// It performs basic validation and tests database connectivity.
+// It performs basic validation and tests database connectivity. Detailed
+// requests additionally aggregate the latest probe result of every module.
 func (s *Server) HealthCheck(ctx context.Context, req *HealthCheckRequest) (*HealthCheckResponse, error) {
 	if req == nil {
 		return nil, fmt.Errorf("health check request cannot be nil")
 	}
 
+	if req.Detailed {
+		return s.platformHealth(ctx), nil
+	}
+
 	// Test database connectivity
 	if err := db.DB.PingContext(ctx); err != nil {
 		return &HealthCheckResponse{Status: "UNHEALTHY"}, fmt.Errorf("database connection failed: %w", err)
@@ -33,55 +79,453 @@ func (s *Server) HealthCheck(ctx context.Context, req *HealthCheckRequest) (*Hea
 	return &HealthCheckResponse{Status: "OK"}, nil
 }
 
+// platformHealth reports database status and per-module probe results.
+// The overall status is UNHEALTHY when the database is unreachable, DEGRADED
+// when any module is unhealthy or not probed yet, and OK otherwise.
+func (s *Server) platformHealth(ctx context.Context) *HealthCheckResponse {
+	if err := db.DB.PingContext(ctx); err != nil {
+		return &HealthCheckResponse{Status: "UNHEALTHY", Database: "UNHEALTHY"}
+	}
+
+	resp := &HealthCheckResponse{Status: "OK", Database: "OK"}
+
+	statuses, err := probe.Latest(ctx)
+	if err != nil {
+		resp.Status = "DEGRADED"
+		return resp
+	}
+
+	for _, st := range statuses {
+		health := &ModuleHealth{
+			ModuleId:  st.ModuleID,
+			Name:      st.Name,
+			Status:    st.Status,
+			Healthy:   st.Healthy.Bool,
+			LatencyMs: st.LatencyMS.Int64,
+			Error:     st.Error.String,
+		}
+		if st.CheckedAt != nil {
+			health.CheckedAt = timestamppb.New(*st.CheckedAt)
+		}
+
+		if !health.Healthy {
+			resp.Status = "DEGRADED"
+		}
+
+		resp.Modules = append(resp.Modules, health)
+	}
+
+	return resp
+}
+
 // Register creates a new module with the given name and IP:port combination.
 // It validates input parameters, checks for name conflicts, and creates the module.
 // Returns an error if the module name already exists or if input validation fails.
+// When authentication is enabled, the caller must present a join token valid
+// for the module's name, or an API key with the modules:write scope, and the
+// response carries the new module's credential.
+//
+// With req.Idempotent, a module that restarts may register its name again:
+// if the caller proves it owns the existing module, that module is updated
+// and its ID returned. With req.AddInstance, the owner adds another instance
+// to the module instead.
 func (s *Server) Register(ctx context.Context, req *RegisterRequest) (*RegisterResponse, error) {
 	if req == nil {
 		return nil, fmt.Errorf("register request cannot be nil")
 	}
 
-	// Validate input parameters
-	if err := s.validateRegisterRequest(req); err != nil {
+	switch {
+	case req.AddInstance:
+		resp, handled, err := s.addInstance(ctx, req)
+		if handled {
+			return resp, err
+		}
+	case req.Idempotent:
+		resp, handled, err := s.registerExisting(ctx, req)
+		if handled {
+			return resp, err
+		}
+	}
+
+	var tokenID string
+	if _, byKey := apikeys.FromContext(ctx); s.Auth != nil && !byKey {
+		var err error
+		tokenID, err = s.Auth.ConsumeJoinToken(ctx, metadataValue(ctx, JoinTokenMetadataKey), req.Name)
+		switch {
+		case errors.Is(err, auth.ErrInvalidJoinToken):
+			return nil, status.Error(codes.Unauthenticated, "a valid join token is required to register")
+		case err != nil:
+			return nil, fmt.Errorf("failed to check join token: %w", err)
+		}
+	}
+
+	var csr *x509.CertificateRequest
+	if len(req.Csr) > 0 {
+		var message string
+		csr, message = s.parseCSR(req.Csr)
+		if csr == nil {
+			s.releaseJoinToken(ctx, tokenID)
+			return &RegisterResponse{
+				Success:  false,
+				ModuleId: "",
+				Message:  message,
+			}, nil
+		}
+	}
+
+	moduleID, err := s.service().Register(ctx, s.registerInput(ctx, req))
+	if err != nil {
+		s.releaseJoinToken(ctx, tokenID)
+	}
+	switch {
+	case errors.Is(err, service.ErrValidation):
 		return &RegisterResponse{
 			Success:  false,
 			ModuleId: "",
 			Message:  fmt.Sprintf("Validation failed: %s", err.Error()),
 		}, nil
+	case errors.Is(err, service.ErrConflict):
+		return &RegisterResponse{
+			Success:  false,
+			ModuleId: "",
+			Message:  "Module with the same name already exists",
+		}, nil
+	case err != nil:
+		return &RegisterResponse{
+			Success:  false,
+			ModuleId: "",
+			Message:  "Module creation failed",
+		}, fmt.Errorf("failed to create module: %w", err)
 	}
 
-	// Check if module with same name already exists
-	moduleExists, err := s.moduleNameExists(ctx, req.Name)
-	if err != nil {
+	resp := &RegisterResponse{
+		Success:  true,
+		ModuleId: moduleID,
+		Message:  "Module created successfully",
+	}
+
+	if s.Auth != nil {
+		resp.Credential, err = s.Auth.IssueCredential(ctx, moduleID)
+		if err != nil {
+			// A module nobody can authenticate as is useless, so undo the registration
+			s.abortRegistration(ctx, moduleID, tokenID)
+			return &RegisterResponse{
+				Success:  false,
+				ModuleId: "",
+				Message:  "Module creation failed",
+			}, fmt.Errorf("failed to issue module credential: %w", err)
+		}
+	}
+
+	if csr != nil {
+		_, resp.Certificate, err = s.CA.Issue(ctx, moduleID, csr)
+		if err != nil {
+			s.abortRegistration(ctx, moduleID, tokenID)
+			return &RegisterResponse{
+				Success:  false,
+				ModuleId: "",
+				Message:  "Module creation failed",
+			}, fmt.Errorf("failed to issue module certificate: %w", err)
+		}
+		resp.CaCertificate = s.CA.Authority().CertificatePEM()
+	}
+
+	return resp, nil
+}
+
+// registerExisting handles an idempotent Register of a name that is taken.
+// It reports false when no module has the name, and the registration goes
+// ahead as usual.
+func (s *Server) registerExisting(ctx context.Context, req *RegisterRequest) (*RegisterResponse, bool, error) {
+	return s.joinModule(ctx, req, func(module *service.Module, byCredential bool, resp *RegisterResponse) error {
+		if _, err := s.service().Reregister(ctx, module.ModuleID, s.registerInput(ctx, req)); err != nil {
+			return fmt.Errorf("failed to reregister module: %w", err)
+		}
+		resp.Message = "Module already registered, address and metadata updated"
+
+		// A caller that proved ownership otherwise may have lost the credential
+		if s.Auth != nil && !byCredential {
+			credential, err := s.Auth.IssueCredential(ctx, module.ModuleID)
+			if err != nil {
+				return fmt.Errorf("failed to issue module credential: %w", err)
+			}
+			resp.Credential = credential
+		}
+
+		return nil
+	})
+}
+
+// addInstance handles a Register that adds an instance to a module that
+// exists. It reports false when no module has the name, and the module is
+// registered with the address as its primary instance.
+func (s *Server) addInstance(ctx context.Context, req *RegisterRequest) (*RegisterResponse, bool, error) {
+	return s.joinModule(ctx, req, func(module *service.Module, _ bool, resp *RegisterResponse) error {
+		instance, err := s.service().AddInstance(ctx, module.ModuleID, service.InstanceInput{
+			IP:     s.moduleHost(ctx, req.Ip),
+			Port:   req.Port,
+			Weight: req.Weight,
+			Zone:   req.Zone,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add instance: %w", err)
+		}
+
+		resp.Message = "Instance added to existing module"
+		resp.InstanceId = instance.InstanceID
+		return nil
+	})
+}
+
+// joinModule handles a Register of a name that is taken by a module the
+// caller owns. join changes the module and fills in the response, which
+// also gets a certificate if the request carries a CSR. It reports false
+// when no module has the name, and the module is registered as usual.
+func (s *Server) joinModule(ctx context.Context, req *RegisterRequest, join func(module *service.Module, byCredential bool, resp *RegisterResponse) error) (*RegisterResponse, bool, error) {
+	module, err := s.service().FindByName(ctx, req.Name)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return nil, false, nil
+	case err != nil:
 		return &RegisterResponse{
 			Success:  false,
 			ModuleId: "",
-			Message:  "Failed to check module existence",
-		}, fmt.Errorf("failed to check module existence: %w", err)
+			Message:  "Module registration failed",
+		}, true, fmt.Errorf("failed to look up module: %w", err)
 	}
 
-	if moduleExists {
+	owned, byCredential, err := s.ownsModule(ctx, module)
+	if err != nil {
+		return nil, true, err
+	}
+	if !owned {
 		return &RegisterResponse{
 			Success:  false,
 			ModuleId: "",
 			Message:  "Module with the same name already exists",
-		}, nil
+		}, true, nil
+	}
+
+	var csr *x509.CertificateRequest
+	if len(req.Csr) > 0 {
+		var message string
+		csr, message = s.parseCSR(req.Csr)
+		if csr == nil {
+			return &RegisterResponse{
+				Success:  false,
+				ModuleId: "",
+				Message:  message,
+			}, true, nil
+		}
 	}
 
-	// Create new module
-	moduleID, err := s.createModule(ctx, req.Name, req.Ip, req.Port)
-	if err != nil {
+	resp := &RegisterResponse{
+		Success:  true,
+		ModuleId: module.ModuleID,
+		Existing: true,
+	}
+
+	err = join(module, byCredential, resp)
+	var invalid *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		// Deleted in the meantime, so the name is free again
+		return nil, false, nil
+	case errors.As(err, &invalid):
 		return &RegisterResponse{
 			Success:  false,
 			ModuleId: "",
-			Message:  "Module creation failed",
-		}, fmt.Errorf("failed to create module: %w", err)
+			Message:  fmt.Sprintf("Validation failed: %s", invalid.Error()),
+		}, true, nil
+	case err != nil:
+		return &RegisterResponse{
+			Success:  false,
+			ModuleId: "",
+			Message:  "Module registration failed",
+		}, true, err
+	}
+
+	if csr != nil {
+		_, resp.Certificate, err = s.CA.Issue(ctx, module.ModuleID, csr)
+		if err != nil {
+			return &RegisterResponse{
+				Success:  false,
+				ModuleId: "",
+				Message:  "Module registration failed",
+			}, true, fmt.Errorf("failed to issue module certificate: %w", err)
+		}
+		resp.CaCertificate = s.CA.Authority().CertificatePEM()
 	}
 
-	return &RegisterResponse{
-		Success:  true,
-		ModuleId: moduleID,
-		Message:  "Module created successfully",
+	return resp, true, nil
+}
+
+// ownsModule reports whether the caller proved that it owns module, and
+// whether it did so with the module's credential. An API key with the
+// modules:write scope or a client certificate for the module count as proof
+// too. Without authentication every caller owns every module.
+func (s *Server) ownsModule(ctx context.Context, module *service.Module) (owned, byCredential bool, err error) {
+	if credential := metadataValue(ctx, CredentialMetadataKey); credential != "" && s.Auth != nil {
+		moduleID, err := s.Auth.VerifyCredential(ctx, credential)
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredential):
+			return false, false, status.Error(codes.Unauthenticated, "invalid module credential")
+		case err != nil:
+			return false, false, status.Error(codes.Internal, "failed to verify module credential")
+		}
+		if strings.EqualFold(moduleID, module.ModuleID) {
+			return true, true, nil
+		}
+	}
+
+	if _, byKey := apikeys.FromContext(ctx); byKey {
+		return true, false, nil
+	}
+
+	if ids, ok := peerIdentities(ctx); ok && identifies(ids, module) {
+		return true, false, nil
+	}
+
+	return s.Auth == nil, false, nil
+}
+
+// provesIdentity reports whether the call was authenticated as module, by
+// its credential or by a client certificate that names it.
+func provesIdentity(ctx context.Context, module *service.Module) bool {
+	if moduleID, ok := AuthenticatedModule(ctx); ok && strings.EqualFold(moduleID, module.ModuleID) {
+		return true
+	}
+
+	ids, ok := peerIdentities(ctx)
+	return ok && identifies(ids, module)
+}
+
+// registerInput converts a registration request for the module service.
+func (s *Server) registerInput(ctx context.Context, req *RegisterRequest) service.RegisterInput {
+	return service.RegisterInput{
+		Name:   req.Name,
+		IP:     s.moduleHost(ctx, req.Ip),
+		Port:   req.Port,
+		Weight: req.Weight,
+		Zone:   req.Zone,
+		Metadata: service.Metadata{
+			Description:  req.Description,
+			Version:      req.Version,
+			Author:       req.Author,
+			Tags:         req.Tags,
+			Homepage:     req.Homepage,
+			Capabilities: req.Capabilities,
+		},
+		Dependencies: dependencies(req.Dependencies),
+	}
+}
+
+// dependencies converts declared dependencies for the module service.
+func dependencies(declared []*Dependency) []service.Dependency {
+	converted := make([]service.Dependency, 0, len(declared))
+	for _, d := range declared {
+		converted = append(converted, service.Dependency{Name: d.GetName(), Version: d.GetVersion()})
+	}
+	return converted
+}
+
+// moduleHost returns the host a module reported. When it reported none and
+// InferAddress is set, the host of the connection's peer is used.
+func (s *Server) moduleHost(ctx context.Context, host string) string {
+	if strings.TrimSpace(host) != "" || !s.InferAddress {
+		return host
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return host
+	}
+
+	peerHost, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return host
+	}
+
+	return peerHost
+}
+
+// parseCSR parses a CSR sent by a module. On failure it returns nil and
+// the message to send back.
+func (s *Server) parseCSR(pem []byte) (*x509.CertificateRequest, string) {
+	if s.CA == nil {
+		return nil, "Certificate issuance is not enabled"
+	}
+
+	csr, err := ca.ParseCSR(pem)
+	if err != nil {
+		return nil, fmt.Sprintf("Validation failed: %s", err.Error())
+	}
+
+	return csr, ""
+}
+
+// abortRegistration removes a module whose registration failed after it was
+// created and gives back its join token use.
+func (s *Server) abortRegistration(ctx context.Context, moduleID, tokenID string) {
+	if _, err := s.service().Delete(ctx, moduleID); err != nil {
+		log.Printf("failed to remove module %s after failed registration: %v", moduleID, err)
+	}
+	s.releaseJoinToken(ctx, tokenID)
+}
+
+// releaseJoinToken gives back the join token use of a failed registration.
+func (s *Server) releaseJoinToken(ctx context.Context, tokenID string) {
+	if tokenID == "" {
+		return
+	}
+
+	if err := s.Auth.ReleaseJoinToken(ctx, tokenID); err != nil {
+		log.Printf("failed to release join token %s: %v", tokenID, err)
+	}
+}
+
+// Reregister replaces the address and metadata of a module that restarted,
+// keeping its module ID. The caller authenticates with the module's
+// credential like for any other call.
+func (s *Server) Reregister(ctx context.Context, req *ReregisterRequest) (*ReregisterResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("reregister request cannot be nil")
+	}
+
+	_, err := s.service().Reregister(ctx, req.ModuleId, service.RegisterInput{
+		IP:   s.moduleHost(ctx, req.Ip),
+		Port: req.Port,
+		Metadata: service.Metadata{
+			Description:  req.Description,
+			Version:      req.Version,
+			Author:       req.Author,
+			Tags:         req.Tags,
+			Homepage:     req.Homepage,
+			Capabilities: req.Capabilities,
+		},
+		Dependencies: dependencies(req.Dependencies),
+	})
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		return &ReregisterResponse{
+			Success: false,
+			Message: fmt.Sprintf("Validation failed: %s", err.Error()),
+		}, nil
+	case errors.Is(err, service.ErrNotFound):
+		return &ReregisterResponse{
+			Success: false,
+			Message: "Module not found",
+		}, nil
+	case err != nil:
+		return &ReregisterResponse{
+			Success: false,
+			Message: "Module reregistration failed",
+		}, fmt.Errorf("failed to reregister module: %w", err)
+	}
+
+	return &ReregisterResponse{
+		Success: true,
+		Message: "Module reregistered successfully",
 	}, nil
 }
 
@@ -93,32 +537,23 @@ func (s *Server) Setup(ctx context.Context, req *SetupRequest) (*SetupResponse,
 		return nil, fmt.Errorf("setup request cannot be nil")
 	}
 
-	// Validate input parameters
-	if err := s.validateSetupRequest(req); err != nil {
+	err := s.service().SetupImage(ctx, service.ImageInput{
+		ModuleID:   req.ModuleId,
+		Image:      req.Image,
+		FileFormat: req.Fileformat,
+	})
+	switch {
+	case errors.Is(err, service.ErrValidation):
 		return &SetupResponse{
 			Success: false,
 			Message: fmt.Sprintf("Validation failed: %s", err.Error()),
 		}, nil
-	}
-
-	// Verify module exists before setup
-	moduleExists, err := s.moduleIDExists(ctx, req.ModuleId)
-	if err != nil {
-		return &SetupResponse{
-			Success: false,
-			Message: "Failed to verify module existence",
-		}, fmt.Errorf("failed to verify module existence: %w", err)
-	}
-
-	if !moduleExists {
+	case errors.Is(err, service.ErrNotFound):
 		return &SetupResponse{
 			Success: false,
 			Message: "Module not found",
 		}, nil
-	}
-
-	// Perform module setup
-	if err := s.setupModuleImage(ctx, req.ModuleId, req.Image, req.Fileformat); err != nil {
+	case err != nil:
 		return &SetupResponse{
 			Success: false,
 			Message: "Module setup failed",
@@ -139,17 +574,14 @@ func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteRespons
 		return nil, fmt.Errorf("delete request cannot be nil")
 	}
 
-	// Validate input parameters
-	if err := s.validateDeleteRequest(req); err != nil {
+	deleted, err := s.service().Delete(ctx, req.ModuleId)
+	switch {
+	case errors.Is(err, service.ErrValidation):
 		return &DeleteResponse{
 			Success: false,
 			Message: fmt.Sprintf("Validation failed: %s", err.Error()),
 		}, nil
-	}
-
-	// Delete module (cascading deletes should handle related records)
-	deleted, err := s.deleteModule(ctx, req.ModuleId)
-	if err != nil {
+	case err != nil:
 		return &DeleteResponse{
 			Success: false,
 			Message: "Module deletion failed",
@@ -169,152 +601,159 @@ func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteRespons
 	}, nil
 }
 
-// Helper methods for validation and database operations
-
-// validateRegisterRequest validates the register request parameters.
-// It checks for empty module names, name length limits, valid IP addresses,
-// and port number ranges (1-65535).
-func (s *Server) validateRegisterRequest(req *RegisterRequest) error {
-	if strings.TrimSpace(req.Name) == "" {
-		return fmt.Errorf("module name cannot be empty")
-	}
-
-	if len(req.Name) > 255 {
-		return fmt.Errorf("module name too long (max 255 characters)")
-	}
-
-	if net.ParseIP(req.Ip) == nil {
-		return fmt.Errorf("invalid IP address: %s", req.Ip)
-	}
-
-	if req.Port <= 0 || req.Port > 65535 {
-		return fmt.Errorf("invalid port number: %d (must be 1-65535)", req.Port)
+// Heartbeat records that a module is still alive.
+// It refreshes the module's last-seen timestamp, marks it ONLINE and tells the
+// caller how often it is expected to call in.
+func (s *Server) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("heartbeat request cannot be nil")
 	}
 
-	return nil
+	return s.handleHeartbeat(ctx, req)
 }
 
-// validateSetupRequest validates the setup request parameters.
-// It checks for empty module IDs, image data presence, file format validity,
-// and ensures the file format is in the allowed list of image formats.
-func (s *Server) validateSetupRequest(req *SetupRequest) error {
-	if strings.TrimSpace(req.ModuleId) == "" {
-		return fmt.Errorf("module ID cannot be empty")
-	}
-
-	if len(req.Image) == 0 {
-		return fmt.Errorf("image data cannot be empty")
-	}
-
-	if strings.TrimSpace(req.Fileformat) == "" {
-		return fmt.Errorf("file format cannot be empty")
-	}
-
-	// Validate common image formats
-	allowedFormats := []string{"png", "jpg", "jpeg", "gif", "webp", "svg"}
-	format := strings.ToLower(strings.Split(strings.TrimSpace(req.Fileformat), "/")[1])
-	for _, allowed := range allowedFormats {
-		if format == allowed {
+// HeartbeatStream is the bidirectional variant of Heartbeat.
+// Every request received on the stream is recorded and answered with a
+// response, until the client closes its side of the stream.
+func (s *Server) HeartbeatStream(stream ModulesService_HeartbeatStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
 			return nil
 		}
-	}
+		if err != nil {
+			return err
+		}
 
-	return fmt.Errorf("unsupported file format: %s", req.Fileformat)
-}
+		resp, err := s.handleHeartbeat(stream.Context(), req)
+		if err != nil {
+			return err
+		}
 
-// validateDeleteRequest validates the delete request parameters.
-// It ensures the module ID is not empty or whitespace-only.
-func (s *Server) validateDeleteRequest(req *DeleteRequest) error {
-	if strings.TrimSpace(req.ModuleId) == "" {
-		return fmt.Errorf("module ID cannot be empty")
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
 	}
-
-	return nil
 }
 
-// moduleNameExists checks if a module with the given name already exists.
-// It returns true if a module with the specified name is found in the database.
-func (s *Server) moduleNameExists(ctx context.Context, name string) (bool, error) {
-	var exists bool
-	query := `SELECT EXISTS (SELECT 1 FROM modules WHERE name = $1)`
-
-	if err := db.DB.GetContext(ctx, &exists, query, name); err != nil {
-		return false, fmt.Errorf("failed to check module name existence: %w", err)
+// RenewCertificate issues a new client certificate for an existing module.
+// Modules call it before their current certificate expires; the old
+// certificate stays valid until its own expiry unless revoked. The caller
+// must authenticate as the module with its credential or current
+// certificate.
+func (s *Server) RenewCertificate(ctx context.Context, req *RenewCertificateRequest) (*RenewCertificateResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("renew certificate request cannot be nil")
 	}
 
-	return exists, nil
-}
-
-// moduleIDExists checks if a module with the given ID exists.
-// It returns true if a module with the specified ID is found in the database.
-func (s *Server) moduleIDExists(ctx context.Context, moduleID string) (bool, error) {
-	var exists bool
-	query := `SELECT EXISTS (SELECT 1 FROM modules WHERE module_id = $1)`
-
-	if err := db.DB.GetContext(ctx, &exists, query, moduleID); err != nil {
-		return false, fmt.Errorf("failed to check module ID existence: %w", err)
+	if s.CA == nil {
+		return nil, status.Error(codes.Unimplemented, "certificate issuance is not enabled")
 	}
 
-	return exists, nil
-}
-
-// createModule inserts a new module into the database.
-// It creates a module record with the provided name and IP:port combination,
-// returning the generated module ID.
-func (s *Server) createModule(ctx context.Context, name, ip string, port int32) (string, error) {
-	var moduleID string
-	query := `INSERT INTO modules (name, ip_port) VALUES ($1, $2) RETURNING module_id`
-	ipPort := fmt.Sprintf("%s:%d", ip, port)
-
-	if err := db.DB.GetContext(ctx, &moduleID, query, name, ipPort); err != nil {
-		return "", fmt.Errorf("failed to insert module: %w", err)
+	csr, message := s.parseCSR(req.Csr)
+	if csr == nil {
+		return &RenewCertificateResponse{
+			Success: false,
+			Message: message,
+		}, nil
 	}
 
-	return moduleID, nil
-}
-
-// setupModuleImage inserts or updates module image data.
-// It performs an upsert operation to handle both new image uploads and updates
-// to existing module images, including updating the timestamp.
-func (s *Server) setupModuleImage(ctx context.Context, moduleID string, image []byte, fileFormat string) error {
-	query := `INSERT INTO images (module_id, image, fileformat) VALUES ($1, $2, $3)
-		ON CONFLICT (module_id) DO UPDATE SET 
-			image = EXCLUDED.image, 
-			fileformat = EXCLUDED.fileformat,
-			updated_at = CURRENT_TIMESTAMP`
-
-	result, err := db.DB.ExecContext(ctx, query, moduleID, image, fileFormat)
-	if err != nil {
-		return fmt.Errorf("failed to setup module image: %w", err)
+	module, err := s.service().Get(ctx, req.ModuleId)
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		return &RenewCertificateResponse{
+			Success: false,
+			Message: fmt.Sprintf("Validation failed: %s", err.Error()),
+		}, nil
+	case errors.Is(err, service.ErrNotFound):
+		return &RenewCertificateResponse{
+			Success: false,
+			Message: "Module not found",
+		}, nil
+	case err != nil:
+		return &RenewCertificateResponse{
+			Success: false,
+			Message: "Certificate renewal failed",
+		}, fmt.Errorf("failed to look up module: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	// Unlike Register, renewal never falls back to trusting the caller when
+	// module authentication is off
+	if !provesIdentity(ctx, module) {
+		return nil, status.Error(codes.PermissionDenied, "certificate renewal requires the module's credential or client certificate")
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("no rows affected during image setup")
+	issued, certPEM, err := s.CA.Issue(ctx, req.ModuleId, csr)
+	if err != nil {
+		return &RenewCertificateResponse{
+			Success: false,
+			Message: "Certificate renewal failed",
+		}, fmt.Errorf("failed to issue module certificate: %w", err)
 	}
 
-	return nil
+	return &RenewCertificateResponse{
+		Success:       true,
+		Message:       "Certificate issued",
+		Certificate:   certPEM,
+		CaCertificate: s.CA.Authority().CertificatePEM(),
+		ExpiresAt:     timestamppb.New(issued.NotAfter),
+	}, nil
 }
 
-// deleteModule removes a module from the database.
-// It deletes the module record and returns true if a module was actually deleted,
-// false if no module with the given ID was found.
-func (s *Server) deleteModule(ctx context.Context, moduleID string) (bool, error) {
-	query := `DELETE FROM modules WHERE module_id = $1`
+// handleHeartbeat validates and records a single heartbeat.
+// It is shared by the unary and streaming heartbeat RPCs.
+func (s *Server) handleHeartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	interval := int32(s.liveness().Interval.Seconds())
+
+	err := s.service().RecordHeartbeat(ctx, req.ModuleId, req.InstanceId)
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		return &HeartbeatResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Validation failed: %s", err.Error()),
+			IntervalSeconds: interval,
+		}, nil
+	case errors.Is(err, service.ErrNotFound):
+		return &HeartbeatResponse{
+			Success:         false,
+			Message:         "Module not found",
+			IntervalSeconds: interval,
+		}, nil
+	case errors.Is(err, service.ErrInstanceNotFound):
+		return &HeartbeatResponse{
+			Success:         false,
+			Message:         "Instance not found",
+			IntervalSeconds: interval,
+		}, nil
+	case err != nil:
+		return &HeartbeatResponse{
+			Success:         false,
+			Message:         "Heartbeat recording failed",
+			IntervalSeconds: interval,
+		}, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return &HeartbeatResponse{
+		Success:         true,
+		Message:         "Heartbeat recorded",
+		Status:          StatusOnline,
+		IntervalSeconds: interval,
+	}, nil
+}
 
-	result, err := db.DB.ExecContext(ctx, query, moduleID)
-	if err != nil {
-		return false, fmt.Errorf("failed to delete module: %w", err)
+// liveness returns the configured liveness settings, or the defaults
+// when the server was constructed without any.
+func (s *Server) liveness() LivenessConfig {
+	if s.Liveness.Interval <= 0 {
+		return DefaultLivenessConfig()
 	}
+	return s.Liveness
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return false, fmt.Errorf("failed to get rows affected: %w", err)
+// service returns the module service, defaulting to one backed by db.DB.
+func (s *Server) service() *service.Modules {
+	if s.Modules != nil {
+		return s.Modules
 	}
-
-	return rowsAffected > 0, nil
+	return service.NewModules(db.DB)
 }
@@ -9,6 +9,7 @@ package modules
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -22,7 +23,10 @@ const (
 )
 
 type HealthCheckRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// When set, the response also reports database status and the latest
+	// probe result of every registered module.
+	Detailed      bool `protobuf:"varint,1,opt,name=detailed,proto3" json:"detailed,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -57,9 +61,18 @@ func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
 	return file_proto_modules_proto_rawDescGZIP(), []int{0}
 }
 
+func (x *HealthCheckRequest) GetDetailed() bool {
+	if x != nil {
+		return x.Detailed
+	}
+	return false
+}
+
 type HealthCheckResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Database      string                 `protobuf:"bytes,2,opt,name=database,proto3" json:"database,omitempty"`
+	Modules       []*ModuleHealth        `protobuf:"bytes,3,rep,name=modules,proto3" json:"modules,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -101,29 +114,47 @@ func (x *HealthCheckResponse) GetStatus() string {
 	return ""
 }
 
-type RegisterRequest struct {
+func (x *HealthCheckResponse) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *HealthCheckResponse) GetModules() []*ModuleHealth {
+	if x != nil {
+		return x.Modules
+	}
+	return nil
+}
+
+type ModuleHealth struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
-	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Healthy       bool                   `protobuf:"varint,4,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	LatencyMs     int64                  `protobuf:"varint,5,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	CheckedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=checked_at,json=checkedAt,proto3" json:"checked_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterRequest) Reset() {
-	*x = RegisterRequest{}
+func (x *ModuleHealth) Reset() {
+	*x = ModuleHealth{}
 	mi := &file_proto_modules_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterRequest) String() string {
+func (x *ModuleHealth) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterRequest) ProtoMessage() {}
+func (*ModuleHealth) ProtoMessage() {}
 
-func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+func (x *ModuleHealth) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_modules_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -135,55 +166,116 @@ func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
-func (*RegisterRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ModuleHealth.ProtoReflect.Descriptor instead.
+func (*ModuleHealth) Descriptor() ([]byte, []int) {
 	return file_proto_modules_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *RegisterRequest) GetName() string {
+func (x *ModuleHealth) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+func (x *ModuleHealth) GetName() string {
 	if x != nil {
 		return x.Name
 	}
 	return ""
 }
 
-func (x *RegisterRequest) GetIp() string {
+func (x *ModuleHealth) GetStatus() string {
 	if x != nil {
-		return x.Ip
+		return x.Status
 	}
 	return ""
 }
 
-func (x *RegisterRequest) GetPort() int32 {
+func (x *ModuleHealth) GetHealthy() bool {
 	if x != nil {
-		return x.Port
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *ModuleHealth) GetLatencyMs() int64 {
+	if x != nil {
+		return x.LatencyMs
 	}
 	return 0
 }
 
-type RegisterResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	ModuleId      string                 `protobuf:"bytes,2,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+func (x *ModuleHealth) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ModuleHealth) GetCheckedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CheckedAt
+	}
+	return nil
+}
+
+type RegisterRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Host of the module: an IPv4 or IPv6 address or a DNS name such as a
+	// Kubernetes service. May be empty when the backend infers addresses.
+	Ip   string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port int32  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	// Optional PEM encoded certificate signing request. When the internal CA is
+	// enabled, a client certificate for the module is returned in the response.
+	Csr []byte `protobuf:"bytes,4,opt,name=csr,proto3" json:"csr,omitempty"`
+	// Optional metadata shown to people browsing the platform.
+	Description string `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	// Semantic version of the module, such as 1.4.0 or 2.0.0-rc.1.
+	Version string `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	Author  string `protobuf:"bytes,7,opt,name=author,proto3" json:"author,omitempty"`
+	// Lowercase tags such as "monitoring"; GET /api/modules can filter by them.
+	Tags []string `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+	// http or https URL of the module's documentation or source.
+	Homepage string `protobuf:"bytes,9,opt,name=homepage,proto3" json:"homepage,omitempty"`
+	// What the module offers to other modules and the UI, such as "auth.login".
+	Capabilities []string `protobuf:"bytes,10,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// When a module with this name exists and the caller proves it owns it,
+	// with the module's credential, a client certificate for the module or an
+	// API key, the existing module is updated and its ID returned instead of
+	// failing. No join token is needed then.
+	Idempotent bool `protobuf:"varint,11,opt,name=idempotent,proto3" json:"idempotent,omitempty"`
+	// Share of requests the instance gets when the module's load balancing
+	// policy is weighted, 1-1000. Defaults to 1.
+	Weight int32 `protobuf:"varint,12,opt,name=weight,proto3" json:"weight,omitempty"`
+	// Free-form placement of the instance, such as eu-west-1a.
+	Zone string `protobuf:"bytes,13,opt,name=zone,proto3" json:"zone,omitempty"`
+	// When a module with this name exists and the caller proves it owns it,
+	// like for idempotent, the address is added to the module as another
+	// instance instead of failing. Takes precedence over idempotent. The
+	// module's credential is never replaced, so replicas share it.
+	AddInstance bool `protobuf:"varint,14,opt,name=add_instance,json=addInstance,proto3" json:"add_instance,omitempty"`
+	// Modules this module needs. Registration fails if they would form a cycle.
+	Dependencies  []*Dependency `protobuf:"bytes,15,rep,name=dependencies,proto3" json:"dependencies,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterResponse) Reset() {
-	*x = RegisterResponse{}
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
 	mi := &file_proto_modules_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterResponse) String() string {
+func (x *RegisterRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterResponse) ProtoMessage() {}
+func (*RegisterRequest) ProtoMessage() {}
 
-func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_modules_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -195,55 +287,141 @@ func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
-func (*RegisterResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
 	return file_proto_modules_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *RegisterResponse) GetSuccess() bool {
+func (x *RegisterRequest) GetName() string {
 	if x != nil {
-		return x.Success
+		return x.Name
 	}
-	return false
+	return ""
 }
 
-func (x *RegisterResponse) GetModuleId() string {
+func (x *RegisterRequest) GetIp() string {
 	if x != nil {
-		return x.ModuleId
+		return x.Ip
 	}
 	return ""
 }
 
-func (x *RegisterResponse) GetMessage() string {
+func (x *RegisterRequest) GetPort() int32 {
 	if x != nil {
-		return x.Message
+		return x.Port
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetCsr() []byte {
+	if x != nil {
+		return x.Csr
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
 	}
 	return ""
 }
 
-type SetupRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
-	Image         []byte                 `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
-	Fileformat    string                 `protobuf:"bytes,3,opt,name=fileformat,proto3" json:"fileformat,omitempty"`
+func (x *RegisterRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetHomepage() string {
+	if x != nil {
+		return x.Homepage
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetIdempotent() bool {
+	if x != nil {
+		return x.Idempotent
+	}
+	return false
+}
+
+func (x *RegisterRequest) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetAddInstance() bool {
+	if x != nil {
+		return x.AddInstance
+	}
+	return false
+}
+
+func (x *RegisterRequest) GetDependencies() []*Dependency {
+	if x != nil {
+		return x.Dependencies
+	}
+	return nil
+}
+
+// Dependency names another module by its name. The module need not be
+// registered yet.
+type Dependency struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Semantic version range such as ">= 1.2, < 2". Empty accepts any version.
+	Version       string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetupRequest) Reset() {
-	*x = SetupRequest{}
+func (x *Dependency) Reset() {
+	*x = Dependency{}
 	mi := &file_proto_modules_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetupRequest) String() string {
+func (x *Dependency) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetupRequest) ProtoMessage() {}
+func (*Dependency) ProtoMessage() {}
 
-func (x *SetupRequest) ProtoReflect() protoreflect.Message {
+func (x *Dependency) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_modules_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -255,54 +433,62 @@ func (x *SetupRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetupRequest.ProtoReflect.Descriptor instead.
-func (*SetupRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use Dependency.ProtoReflect.Descriptor instead.
+func (*Dependency) Descriptor() ([]byte, []int) {
 	return file_proto_modules_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *SetupRequest) GetModuleId() string {
+func (x *Dependency) GetName() string {
 	if x != nil {
-		return x.ModuleId
+		return x.Name
 	}
 	return ""
 }
 
-func (x *SetupRequest) GetImage() []byte {
-	if x != nil {
-		return x.Image
-	}
-	return nil
-}
-
-func (x *SetupRequest) GetFileformat() string {
+func (x *Dependency) GetVersion() string {
 	if x != nil {
-		return x.Fileformat
+		return x.Version
 	}
 	return ""
 }
 
-type SetupResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+type RegisterResponse struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Success  bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	ModuleId string                 `protobuf:"bytes,2,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	Message  string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// Credential authenticates all later calls of the module. It is sent in the
+	// x-openplatform-module-credential metadata and only returned once.
+	Credential string `protobuf:"bytes,4,opt,name=credential,proto3" json:"credential,omitempty"`
+	// PEM encoded client certificate bound to the module ID, when a CSR was sent.
+	Certificate []byte `protobuf:"bytes,5,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	// PEM encoded certificate of the CA that signed it.
+	CaCertificate []byte `protobuf:"bytes,6,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"`
+	// Existing is set when an idempotent Register matched a module that was
+	// already registered. Credential is then empty if the caller proved
+	// ownership with it, and the module keeps using it.
+	Existing bool `protobuf:"varint,7,opt,name=existing,proto3" json:"existing,omitempty"`
+	// ID of the instance added by add_instance. Heartbeats of the instance
+	// send it along.
+	InstanceId    string `protobuf:"bytes,8,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SetupResponse) Reset() {
-	*x = SetupResponse{}
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
 	mi := &file_proto_modules_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SetupResponse) String() string {
+func (x *RegisterResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SetupResponse) ProtoMessage() {}
+func (*RegisterResponse) ProtoMessage() {}
 
-func (x *SetupResponse) ProtoReflect() protoreflect.Message {
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_modules_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -314,92 +500,101 @@ func (x *SetupResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SetupResponse.ProtoReflect.Descriptor instead.
-func (*SetupResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
 	return file_proto_modules_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *SetupResponse) GetSuccess() bool {
+func (x *RegisterResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *SetupResponse) GetMessage() string {
+func (x *RegisterResponse) GetModuleId() string {
 	if x != nil {
-		return x.Message
+		return x.ModuleId
 	}
 	return ""
 }
 
-type DeleteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *RegisterResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
 }
 
-func (x *DeleteRequest) Reset() {
-	*x = DeleteRequest{}
-	mi := &file_proto_modules_proto_msgTypes[6]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *RegisterResponse) GetCredential() string {
+	if x != nil {
+		return x.Credential
+	}
+	return ""
 }
 
-func (x *DeleteRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *RegisterResponse) GetCertificate() []byte {
+	if x != nil {
+		return x.Certificate
+	}
+	return nil
 }
 
-func (*DeleteRequest) ProtoMessage() {}
-
-func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_modules_proto_msgTypes[6]
+func (x *RegisterResponse) GetCaCertificate() []byte {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.CaCertificate
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
-func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_modules_proto_rawDescGZIP(), []int{6}
+func (x *RegisterResponse) GetExisting() bool {
+	if x != nil {
+		return x.Existing
+	}
+	return false
 }
 
-func (x *DeleteRequest) GetModuleId() string {
+func (x *RegisterResponse) GetInstanceId() string {
 	if x != nil {
-		return x.ModuleId
+		return x.InstanceId
 	}
 	return ""
 }
 
-type DeleteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+// ReregisterRequest replaces the address, metadata and dependencies of a
+// registered module. Its ID, name, credential and images are kept.
+type ReregisterRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	// Host of the module, as in RegisterRequest.
+	Ip            string        `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port          int32         `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Description   string        `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Version       string        `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	Author        string        `protobuf:"bytes,6,opt,name=author,proto3" json:"author,omitempty"`
+	Tags          []string      `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
+	Homepage      string        `protobuf:"bytes,8,opt,name=homepage,proto3" json:"homepage,omitempty"`
+	Capabilities  []string      `protobuf:"bytes,9,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	Dependencies  []*Dependency `protobuf:"bytes,10,rep,name=dependencies,proto3" json:"dependencies,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteResponse) Reset() {
-	*x = DeleteResponse{}
-	mi := &file_proto_modules_proto_msgTypes[7]
+func (x *ReregisterRequest) Reset() {
+	*x = ReregisterRequest{}
+	mi := &file_proto_modules_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteResponse) String() string {
+func (x *ReregisterRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteResponse) ProtoMessage() {}
+func (*ReregisterRequest) ProtoMessage() {}
 
-func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_modules_proto_msgTypes[7]
+func (x *ReregisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -410,41 +605,1228 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
-func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_modules_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use ReregisterRequest.ProtoReflect.Descriptor instead.
+func (*ReregisterRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *DeleteResponse) GetSuccess() bool {
+func (x *ReregisterRequest) GetModuleId() string {
 	if x != nil {
-		return x.Success
+		return x.ModuleId
 	}
-	return false
+	return ""
+}
+
+func (x *ReregisterRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ReregisterRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ReregisterRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ReregisterRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ReregisterRequest) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *ReregisterRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ReregisterRequest) GetHomepage() string {
+	if x != nil {
+		return x.Homepage
+	}
+	return ""
+}
+
+func (x *ReregisterRequest) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *ReregisterRequest) GetDependencies() []*Dependency {
+	if x != nil {
+		return x.Dependencies
+	}
+	return nil
+}
+
+type ReregisterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReregisterResponse) Reset() {
+	*x = ReregisterResponse{}
+	mi := &file_proto_modules_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReregisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReregisterResponse) ProtoMessage() {}
+
+func (x *ReregisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReregisterResponse.ProtoReflect.Descriptor instead.
+func (*ReregisterResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReregisterResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReregisterResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type SetupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	Image         []byte                 `protobuf:"bytes,2,opt,name=image,proto3" json:"image,omitempty"`
+	Fileformat    string                 `protobuf:"bytes,3,opt,name=fileformat,proto3" json:"fileformat,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetupRequest) Reset() {
+	*x = SetupRequest{}
+	mi := &file_proto_modules_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetupRequest) ProtoMessage() {}
+
+func (x *SetupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetupRequest.ProtoReflect.Descriptor instead.
+func (*SetupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetupRequest) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+func (x *SetupRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *SetupRequest) GetFileformat() string {
+	if x != nil {
+		return x.Fileformat
+	}
+	return ""
+}
+
+type SetupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetupResponse) Reset() {
+	*x = SetupResponse{}
+	mi := &file_proto_modules_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetupResponse) ProtoMessage() {}
+
+func (x *SetupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetupResponse.ProtoReflect.Descriptor instead.
+func (*SetupResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SetupResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetupResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_proto_modules_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteRequest) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_proto_modules_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type HeartbeatRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	// Instance the heartbeat is for. Empty means the module's primary instance.
+	InstanceId    string `protobuf:"bytes,2,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_proto_modules_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *HeartbeatRequest) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+type HeartbeatResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Status          string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	IntervalSeconds int32                  `protobuf:"varint,4,opt,name=interval_seconds,json=intervalSeconds,proto3" json:"interval_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_proto_modules_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HeartbeatResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *HeartbeatResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HeartbeatResponse) GetIntervalSeconds() int32 {
+	if x != nil {
+		return x.IntervalSeconds
+	}
+	return 0
+}
+
+// RenewCertificateRequest asks for a new client certificate before the
+// current one expires. The previous certificate stays valid until then.
+type RenewCertificateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	Csr           []byte                 `protobuf:"bytes,2,opt,name=csr,proto3" json:"csr,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewCertificateRequest) Reset() {
+	*x = RenewCertificateRequest{}
+	mi := &file_proto_modules_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewCertificateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewCertificateRequest) ProtoMessage() {}
+
+func (x *RenewCertificateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewCertificateRequest.ProtoReflect.Descriptor instead.
+func (*RenewCertificateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RenewCertificateRequest) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+func (x *RenewCertificateRequest) GetCsr() []byte {
+	if x != nil {
+		return x.Csr
+	}
+	return nil
+}
+
+type RenewCertificateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Certificate   []byte                 `protobuf:"bytes,3,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	CaCertificate []byte                 `protobuf:"bytes,4,opt,name=ca_certificate,json=caCertificate,proto3" json:"ca_certificate,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewCertificateResponse) Reset() {
+	*x = RenewCertificateResponse{}
+	mi := &file_proto_modules_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewCertificateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewCertificateResponse) ProtoMessage() {}
+
+func (x *RenewCertificateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewCertificateResponse.ProtoReflect.Descriptor instead.
+func (*RenewCertificateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RenewCertificateResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RenewCertificateResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RenewCertificateResponse) GetCertificate() []byte {
+	if x != nil {
+		return x.Certificate
+	}
+	return nil
+}
+
+func (x *RenewCertificateResponse) GetCaCertificate() []byte {
+	if x != nil {
+		return x.CaCertificate
+	}
+	return nil
+}
+
+func (x *RenewCertificateResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+// ModuleInfo is a registered module as other modules see it.
+type ModuleInfo struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	Name     string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// Address of the primary instance. host is an IP address or DNS name.
+	Host          string          `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Port          int32           `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Status        string          `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Description   string          `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Version       string          `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`
+	Author        string          `protobuf:"bytes,8,opt,name=author,proto3" json:"author,omitempty"`
+	Tags          []string        `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	Homepage      string          `protobuf:"bytes,10,opt,name=homepage,proto3" json:"homepage,omitempty"`
+	Capabilities  []string        `protobuf:"bytes,11,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	LbPolicy      string          `protobuf:"bytes,12,opt,name=lb_policy,json=lbPolicy,proto3" json:"lb_policy,omitempty"`
+	Instances     []*InstanceInfo `protobuf:"bytes,13,rep,name=instances,proto3" json:"instances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModuleInfo) Reset() {
+	*x = ModuleInfo{}
+	mi := &file_proto_modules_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModuleInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModuleInfo) ProtoMessage() {}
+
+func (x *ModuleInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModuleInfo.ProtoReflect.Descriptor instead.
+func (*ModuleInfo) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ModuleInfo) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ModuleInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ModuleInfo) GetHomepage() string {
+	if x != nil {
+		return x.Homepage
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *ModuleInfo) GetLbPolicy() string {
+	if x != nil {
+		return x.LbPolicy
+	}
+	return ""
+}
+
+func (x *ModuleInfo) GetInstances() []*InstanceInfo {
+	if x != nil {
+		return x.Instances
+	}
+	return nil
+}
+
+type InstanceInfo struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId string                 `protobuf:"bytes,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	Host       string                 `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	Port       int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Weight     int32                  `protobuf:"varint,4,opt,name=weight,proto3" json:"weight,omitempty"`
+	Zone       string                 `protobuf:"bytes,5,opt,name=zone,proto3" json:"zone,omitempty"`
+	Status     string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	// False when the latest health probe of the instance failed.
+	Healthy       bool `protobuf:"varint,7,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Primary       bool `protobuf:"varint,8,opt,name=primary,proto3" json:"primary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstanceInfo) Reset() {
+	*x = InstanceInfo{}
+	mi := &file_proto_modules_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstanceInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstanceInfo) ProtoMessage() {}
+
+func (x *InstanceInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstanceInfo.ProtoReflect.Descriptor instead.
+func (*InstanceInfo) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *InstanceInfo) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *InstanceInfo) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
 }
 
-func (x *DeleteResponse) GetMessage() string {
+func (x *InstanceInfo) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *InstanceInfo) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *InstanceInfo) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *InstanceInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *InstanceInfo) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *InstanceInfo) GetPrimary() bool {
+	if x != nil {
+		return x.Primary
+	}
+	return false
+}
+
+// ListModulesRequest lists the modules that have all the given tags and all
+// the given capabilities.
+type ListModulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	Capabilities  []string               `protobuf:"bytes,2,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModulesRequest) Reset() {
+	*x = ListModulesRequest{}
+	mi := &file_proto_modules_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModulesRequest) ProtoMessage() {}
+
+func (x *ListModulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModulesRequest.ProtoReflect.Descriptor instead.
+func (*ListModulesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListModulesRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ListModulesRequest) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type ListModulesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Modules       []*ModuleInfo          `protobuf:"bytes,1,rep,name=modules,proto3" json:"modules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModulesResponse) Reset() {
+	*x = ListModulesResponse{}
+	mi := &file_proto_modules_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModulesResponse) ProtoMessage() {}
+
+func (x *ListModulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModulesResponse.ProtoReflect.Descriptor instead.
+func (*ListModulesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListModulesResponse) GetModules() []*ModuleInfo {
+	if x != nil {
+		return x.Modules
+	}
+	return nil
+}
+
+// GetModuleRequest looks up a module by ID or, when module_id is empty, by
+// name.
+type GetModuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ModuleId      string                 `protobuf:"bytes,1,opt,name=module_id,json=moduleId,proto3" json:"module_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetModuleRequest) Reset() {
+	*x = GetModuleRequest{}
+	mi := &file_proto_modules_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetModuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModuleRequest) ProtoMessage() {}
+
+func (x *GetModuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModuleRequest.ProtoReflect.Descriptor instead.
+func (*GetModuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetModuleRequest) GetModuleId() string {
+	if x != nil {
+		return x.ModuleId
+	}
+	return ""
+}
+
+func (x *GetModuleRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type GetModuleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Module        *ModuleInfo            `protobuf:"bytes,3,opt,name=module,proto3" json:"module,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetModuleResponse) Reset() {
+	*x = GetModuleResponse{}
+	mi := &file_proto_modules_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetModuleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetModuleResponse) ProtoMessage() {}
+
+func (x *GetModuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetModuleResponse.ProtoReflect.Descriptor instead.
+func (*GetModuleResponse) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetModuleResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *GetModuleResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
+func (x *GetModuleResponse) GetModule() *ModuleInfo {
+	if x != nil {
+		return x.Module
+	}
+	return nil
+}
+
+// WatchModulesRequest filters the modules to watch like ListModulesRequest.
+type WatchModulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	Capabilities  []string               `protobuf:"bytes,2,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchModulesRequest) Reset() {
+	*x = WatchModulesRequest{}
+	mi := &file_proto_modules_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchModulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchModulesRequest) ProtoMessage() {}
+
+func (x *WatchModulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchModulesRequest.ProtoReflect.Descriptor instead.
+func (*WatchModulesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WatchModulesRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *WatchModulesRequest) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+// ModuleEvent reports a change of a watched module. A watch starts with an
+// ADDED event for every module that matches the filter. Modules that stop
+// matching it are REMOVED.
+type ModuleEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ADDED, UPDATED or REMOVED.
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// The module after the change, or before it was removed.
+	Module        *ModuleInfo `protobuf:"bytes,2,opt,name=module,proto3" json:"module,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModuleEvent) Reset() {
+	*x = ModuleEvent{}
+	mi := &file_proto_modules_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModuleEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModuleEvent) ProtoMessage() {}
+
+func (x *ModuleEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_modules_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModuleEvent.ProtoReflect.Descriptor instead.
+func (*ModuleEvent) Descriptor() ([]byte, []int) {
+	return file_proto_modules_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ModuleEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ModuleEvent) GetModule() *ModuleInfo {
+	if x != nil {
+		return x.Module
+	}
+	return nil
+}
+
 var File_proto_modules_proto protoreflect.FileDescriptor
 
 const file_proto_modules_proto_rawDesc = "" +
 	"\n" +
-	"\x13proto/modules.proto\x12\amodules\"\x14\n" +
-	"\x12HealthCheckRequest\"-\n" +
+	"\x13proto/modules.proto\x12\amodules\x1a\x1fgoogle/protobuf/timestamp.proto\"0\n" +
+	"\x12HealthCheckRequest\x12\x1a\n" +
+	"\bdetailed\x18\x01 \x01(\bR\bdetailed\"z\n" +
 	"\x13HealthCheckResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status\"I\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x1a\n" +
+	"\bdatabase\x18\x02 \x01(\tR\bdatabase\x12/\n" +
+	"\amodules\x18\x03 \x03(\v2\x15.modules.ModuleHealthR\amodules\"\xe1\x01\n" +
+	"\fModuleHealth\x12\x1b\n" +
+	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x18\n" +
+	"\ahealthy\x18\x04 \x01(\bR\ahealthy\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x05 \x01(\x03R\tlatencyMs\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"checked_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcheckedAt\"\xab\x03\n" +
 	"\x0fRegisterRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
 	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x12\n" +
-	"\x04port\x18\x03 \x01(\x05R\x04port\"c\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x10\n" +
+	"\x03csr\x18\x04 \x01(\fR\x03csr\x12 \n" +
+	"\vdescription\x18\x05 \x01(\tR\vdescription\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\tR\aversion\x12\x16\n" +
+	"\x06author\x18\a \x01(\tR\x06author\x12\x12\n" +
+	"\x04tags\x18\b \x03(\tR\x04tags\x12\x1a\n" +
+	"\bhomepage\x18\t \x01(\tR\bhomepage\x12\"\n" +
+	"\fcapabilities\x18\n" +
+	" \x03(\tR\fcapabilities\x12\x1e\n" +
+	"\n" +
+	"idempotent\x18\v \x01(\bR\n" +
+	"idempotent\x12\x16\n" +
+	"\x06weight\x18\f \x01(\x05R\x06weight\x12\x12\n" +
+	"\x04zone\x18\r \x01(\tR\x04zone\x12!\n" +
+	"\fadd_instance\x18\x0e \x01(\bR\vaddInstance\x127\n" +
+	"\fdependencies\x18\x0f \x03(\v2\x13.modules.DependencyR\fdependencies\":\n" +
+	"\n" +
+	"Dependency\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"\x89\x02\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1b\n" +
 	"\tmodule_id\x18\x02 \x01(\tR\bmoduleId\x12\x18\n" +
-	"\amessage\x18\x03 \x01(\tR\amessage\"a\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x1e\n" +
+	"\n" +
+	"credential\x18\x04 \x01(\tR\n" +
+	"credential\x12 \n" +
+	"\vcertificate\x18\x05 \x01(\fR\vcertificate\x12%\n" +
+	"\x0eca_certificate\x18\x06 \x01(\fR\rcaCertificate\x12\x1a\n" +
+	"\bexisting\x18\a \x01(\bR\bexisting\x12\x1f\n" +
+	"\vinstance_id\x18\b \x01(\tR\n" +
+	"instanceId\"\xb5\x02\n" +
+	"\x11ReregisterRequest\x12\x1b\n" +
+	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12\x16\n" +
+	"\x06author\x18\x06 \x01(\tR\x06author\x12\x12\n" +
+	"\x04tags\x18\a \x03(\tR\x04tags\x12\x1a\n" +
+	"\bhomepage\x18\b \x01(\tR\bhomepage\x12\"\n" +
+	"\fcapabilities\x18\t \x03(\tR\fcapabilities\x127\n" +
+	"\fdependencies\x18\n" +
+	" \x03(\v2\x13.modules.DependencyR\fdependencies\"H\n" +
+	"\x12ReregisterResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"a\n" +
 	"\fSetupRequest\x12\x1b\n" +
 	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x14\n" +
 	"\x05image\x18\x02 \x01(\fR\x05image\x12\x1e\n" +
@@ -458,12 +1840,83 @@ const file_proto_modules_proto_rawDesc = "" +
 	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\"D\n" +
 	"\x0eDeleteResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\x8e\x02\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"P\n" +
+	"\x10HeartbeatRequest\x12\x1b\n" +
+	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x1f\n" +
+	"\vinstance_id\x18\x02 \x01(\tR\n" +
+	"instanceId\"\x8a\x01\n" +
+	"\x11HeartbeatResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12)\n" +
+	"\x10interval_seconds\x18\x04 \x01(\x05R\x0fintervalSeconds\"H\n" +
+	"\x17RenewCertificateRequest\x12\x1b\n" +
+	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x10\n" +
+	"\x03csr\x18\x02 \x01(\fR\x03csr\"\xd2\x01\n" +
+	"\x18RenewCertificateResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12 \n" +
+	"\vcertificate\x18\x03 \x01(\fR\vcertificate\x12%\n" +
+	"\x0eca_certificate\x18\x04 \x01(\fR\rcaCertificate\x129\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"\xf7\x02\n" +
+	"\n" +
+	"ModuleInfo\x12\x1b\n" +
+	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04host\x18\x03 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x18\n" +
+	"\aversion\x18\a \x01(\tR\aversion\x12\x16\n" +
+	"\x06author\x18\b \x01(\tR\x06author\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12\x1a\n" +
+	"\bhomepage\x18\n" +
+	" \x01(\tR\bhomepage\x12\"\n" +
+	"\fcapabilities\x18\v \x03(\tR\fcapabilities\x12\x1b\n" +
+	"\tlb_policy\x18\f \x01(\tR\blbPolicy\x123\n" +
+	"\tinstances\x18\r \x03(\v2\x15.modules.InstanceInfoR\tinstances\"\xcf\x01\n" +
+	"\fInstanceInfo\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\tR\n" +
+	"instanceId\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x16\n" +
+	"\x06weight\x18\x04 \x01(\x05R\x06weight\x12\x12\n" +
+	"\x04zone\x18\x05 \x01(\tR\x04zone\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12\x18\n" +
+	"\ahealthy\x18\a \x01(\bR\ahealthy\x12\x18\n" +
+	"\aprimary\x18\b \x01(\bR\aprimary\"L\n" +
+	"\x12ListModulesRequest\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\x12\"\n" +
+	"\fcapabilities\x18\x02 \x03(\tR\fcapabilities\"D\n" +
+	"\x13ListModulesResponse\x12-\n" +
+	"\amodules\x18\x01 \x03(\v2\x13.modules.ModuleInfoR\amodules\"C\n" +
+	"\x10GetModuleRequest\x12\x1b\n" +
+	"\tmodule_id\x18\x01 \x01(\tR\bmoduleId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"t\n" +
+	"\x11GetModuleResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12+\n" +
+	"\x06module\x18\x03 \x01(\v2\x13.modules.ModuleInfoR\x06module\"M\n" +
+	"\x13WatchModulesRequest\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\x12\"\n" +
+	"\fcapabilities\x18\x02 \x03(\tR\fcapabilities\"N\n" +
+	"\vModuleEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12+\n" +
+	"\x06module\x18\x02 \x01(\v2\x13.modules.ModuleInfoR\x06module2\x94\x06\n" +
 	"\x0eModulesService\x12H\n" +
 	"\vHealthCheck\x12\x1b.modules.HealthCheckRequest\x1a\x1c.modules.HealthCheckResponse\x12?\n" +
-	"\bRegister\x12\x18.modules.RegisterRequest\x1a\x19.modules.RegisterResponse\x126\n" +
+	"\bRegister\x12\x18.modules.RegisterRequest\x1a\x19.modules.RegisterResponse\x12E\n" +
+	"\n" +
+	"Reregister\x12\x1a.modules.ReregisterRequest\x1a\x1b.modules.ReregisterResponse\x126\n" +
 	"\x05Setup\x12\x15.modules.SetupRequest\x1a\x16.modules.SetupResponse\x129\n" +
-	"\x06Delete\x12\x16.modules.DeleteRequest\x1a\x17.modules.DeleteResponseB!Z\x1f./internal/grpc/modules;modulesb\x06proto3"
+	"\x06Delete\x12\x16.modules.DeleteRequest\x1a\x17.modules.DeleteResponse\x12B\n" +
+	"\tHeartbeat\x12\x19.modules.HeartbeatRequest\x1a\x1a.modules.HeartbeatResponse\x12L\n" +
+	"\x0fHeartbeatStream\x12\x19.modules.HeartbeatRequest\x1a\x1a.modules.HeartbeatResponse(\x010\x01\x12W\n" +
+	"\x10RenewCertificate\x12 .modules.RenewCertificateRequest\x1a!.modules.RenewCertificateResponse\x12H\n" +
+	"\vListModules\x12\x1b.modules.ListModulesRequest\x1a\x1c.modules.ListModulesResponse\x12B\n" +
+	"\tGetModule\x12\x19.modules.GetModuleRequest\x1a\x1a.modules.GetModuleResponse\x12D\n" +
+	"\fWatchModules\x12\x1c.modules.WatchModulesRequest\x1a\x14.modules.ModuleEvent0\x01B!Z\x1f./internal/grpc/modules;modulesb\x06proto3"
 
 var (
 	file_proto_modules_proto_rawDescOnce sync.Once
@@ -477,31 +1930,71 @@ func file_proto_modules_proto_rawDescGZIP() []byte {
 	return file_proto_modules_proto_rawDescData
 }
 
-var file_proto_modules_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_modules_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_proto_modules_proto_goTypes = []any{
-	(*HealthCheckRequest)(nil),  // 0: modules.HealthCheckRequest
-	(*HealthCheckResponse)(nil), // 1: modules.HealthCheckResponse
-	(*RegisterRequest)(nil),     // 2: modules.RegisterRequest
-	(*RegisterResponse)(nil),    // 3: modules.RegisterResponse
-	(*SetupRequest)(nil),        // 4: modules.SetupRequest
-	(*SetupResponse)(nil),       // 5: modules.SetupResponse
-	(*DeleteRequest)(nil),       // 6: modules.DeleteRequest
-	(*DeleteResponse)(nil),      // 7: modules.DeleteResponse
+	(*HealthCheckRequest)(nil),       // 0: modules.HealthCheckRequest
+	(*HealthCheckResponse)(nil),      // 1: modules.HealthCheckResponse
+	(*ModuleHealth)(nil),             // 2: modules.ModuleHealth
+	(*RegisterRequest)(nil),          // 3: modules.RegisterRequest
+	(*Dependency)(nil),               // 4: modules.Dependency
+	(*RegisterResponse)(nil),         // 5: modules.RegisterResponse
+	(*ReregisterRequest)(nil),        // 6: modules.ReregisterRequest
+	(*ReregisterResponse)(nil),       // 7: modules.ReregisterResponse
+	(*SetupRequest)(nil),             // 8: modules.SetupRequest
+	(*SetupResponse)(nil),            // 9: modules.SetupResponse
+	(*DeleteRequest)(nil),            // 10: modules.DeleteRequest
+	(*DeleteResponse)(nil),           // 11: modules.DeleteResponse
+	(*HeartbeatRequest)(nil),         // 12: modules.HeartbeatRequest
+	(*HeartbeatResponse)(nil),        // 13: modules.HeartbeatResponse
+	(*RenewCertificateRequest)(nil),  // 14: modules.RenewCertificateRequest
+	(*RenewCertificateResponse)(nil), // 15: modules.RenewCertificateResponse
+	(*ModuleInfo)(nil),               // 16: modules.ModuleInfo
+	(*InstanceInfo)(nil),             // 17: modules.InstanceInfo
+	(*ListModulesRequest)(nil),       // 18: modules.ListModulesRequest
+	(*ListModulesResponse)(nil),      // 19: modules.ListModulesResponse
+	(*GetModuleRequest)(nil),         // 20: modules.GetModuleRequest
+	(*GetModuleResponse)(nil),        // 21: modules.GetModuleResponse
+	(*WatchModulesRequest)(nil),      // 22: modules.WatchModulesRequest
+	(*ModuleEvent)(nil),              // 23: modules.ModuleEvent
+	(*timestamppb.Timestamp)(nil),    // 24: google.protobuf.Timestamp
 }
 var file_proto_modules_proto_depIdxs = []int32{
-	0, // 0: modules.ModulesService.HealthCheck:input_type -> modules.HealthCheckRequest
-	2, // 1: modules.ModulesService.Register:input_type -> modules.RegisterRequest
-	4, // 2: modules.ModulesService.Setup:input_type -> modules.SetupRequest
-	6, // 3: modules.ModulesService.Delete:input_type -> modules.DeleteRequest
-	1, // 4: modules.ModulesService.HealthCheck:output_type -> modules.HealthCheckResponse
-	3, // 5: modules.ModulesService.Register:output_type -> modules.RegisterResponse
-	5, // 6: modules.ModulesService.Setup:output_type -> modules.SetupResponse
-	7, // 7: modules.ModulesService.Delete:output_type -> modules.DeleteResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: modules.HealthCheckResponse.modules:type_name -> modules.ModuleHealth
+	24, // 1: modules.ModuleHealth.checked_at:type_name -> google.protobuf.Timestamp
+	4,  // 2: modules.RegisterRequest.dependencies:type_name -> modules.Dependency
+	4,  // 3: modules.ReregisterRequest.dependencies:type_name -> modules.Dependency
+	24, // 4: modules.RenewCertificateResponse.expires_at:type_name -> google.protobuf.Timestamp
+	17, // 5: modules.ModuleInfo.instances:type_name -> modules.InstanceInfo
+	16, // 6: modules.ListModulesResponse.modules:type_name -> modules.ModuleInfo
+	16, // 7: modules.GetModuleResponse.module:type_name -> modules.ModuleInfo
+	16, // 8: modules.ModuleEvent.module:type_name -> modules.ModuleInfo
+	0,  // 9: modules.ModulesService.HealthCheck:input_type -> modules.HealthCheckRequest
+	3,  // 10: modules.ModulesService.Register:input_type -> modules.RegisterRequest
+	6,  // 11: modules.ModulesService.Reregister:input_type -> modules.ReregisterRequest
+	8,  // 12: modules.ModulesService.Setup:input_type -> modules.SetupRequest
+	10, // 13: modules.ModulesService.Delete:input_type -> modules.DeleteRequest
+	12, // 14: modules.ModulesService.Heartbeat:input_type -> modules.HeartbeatRequest
+	12, // 15: modules.ModulesService.HeartbeatStream:input_type -> modules.HeartbeatRequest
+	14, // 16: modules.ModulesService.RenewCertificate:input_type -> modules.RenewCertificateRequest
+	18, // 17: modules.ModulesService.ListModules:input_type -> modules.ListModulesRequest
+	20, // 18: modules.ModulesService.GetModule:input_type -> modules.GetModuleRequest
+	22, // 19: modules.ModulesService.WatchModules:input_type -> modules.WatchModulesRequest
+	1,  // 20: modules.ModulesService.HealthCheck:output_type -> modules.HealthCheckResponse
+	5,  // 21: modules.ModulesService.Register:output_type -> modules.RegisterResponse
+	7,  // 22: modules.ModulesService.Reregister:output_type -> modules.ReregisterResponse
+	9,  // 23: modules.ModulesService.Setup:output_type -> modules.SetupResponse
+	11, // 24: modules.ModulesService.Delete:output_type -> modules.DeleteResponse
+	13, // 25: modules.ModulesService.Heartbeat:output_type -> modules.HeartbeatResponse
+	13, // 26: modules.ModulesService.HeartbeatStream:output_type -> modules.HeartbeatResponse
+	15, // 27: modules.ModulesService.RenewCertificate:output_type -> modules.RenewCertificateResponse
+	19, // 28: modules.ModulesService.ListModules:output_type -> modules.ListModulesResponse
+	21, // 29: modules.ModulesService.GetModule:output_type -> modules.GetModuleResponse
+	23, // 30: modules.ModulesService.WatchModules:output_type -> modules.ModuleEvent
+	20, // [20:31] is the sub-list for method output_type
+	9,  // [9:20] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_proto_modules_proto_init() }
@@ -515,7 +2008,7 @@ func file_proto_modules_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_modules_proto_rawDesc), len(file_proto_modules_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,20 +19,45 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ModulesService_HealthCheck_FullMethodName = "/modules.ModulesService/HealthCheck"
-	ModulesService_Register_FullMethodName    = "/modules.ModulesService/Register"
-	ModulesService_Setup_FullMethodName       = "/modules.ModulesService/Setup"
-	ModulesService_Delete_FullMethodName      = "/modules.ModulesService/Delete"
+	ModulesService_HealthCheck_FullMethodName      = "/modules.ModulesService/HealthCheck"
+	ModulesService_Register_FullMethodName         = "/modules.ModulesService/Register"
+	ModulesService_Reregister_FullMethodName       = "/modules.ModulesService/Reregister"
+	ModulesService_Setup_FullMethodName            = "/modules.ModulesService/Setup"
+	ModulesService_Delete_FullMethodName           = "/modules.ModulesService/Delete"
+	ModulesService_Heartbeat_FullMethodName        = "/modules.ModulesService/Heartbeat"
+	ModulesService_HeartbeatStream_FullMethodName  = "/modules.ModulesService/HeartbeatStream"
+	ModulesService_RenewCertificate_FullMethodName = "/modules.ModulesService/RenewCertificate"
+	ModulesService_ListModules_FullMethodName      = "/modules.ModulesService/ListModules"
+	ModulesService_GetModule_FullMethodName        = "/modules.ModulesService/GetModule"
+	ModulesService_WatchModules_FullMethodName     = "/modules.ModulesService/WatchModules"
 )
 
 // ModulesServiceClient is the client API for ModulesService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Register requires a join token in the x-openplatform-join-token metadata.
+// All other calls except HealthCheck require the module credential returned
+// by Register in the x-openplatform-module-credential metadata.
+//
+// A module that restarts on a new address calls Reregister, or Register with
+// idempotent set, instead of registering a new module. Replicas of a module
+// call Register with add_instance set.
+//
+// ListModules, GetModule and WatchModules let modules discover each other.
+// Any authenticated module may look up every other module.
 type ModulesServiceClient interface {
 	HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
 	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
+	Reregister(ctx context.Context, in *ReregisterRequest, opts ...grpc.CallOption) (*ReregisterResponse, error)
 	Setup(ctx context.Context, in *SetupRequest, opts ...grpc.CallOption) (*SetupResponse, error)
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	HeartbeatStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[HeartbeatRequest, HeartbeatResponse], error)
+	RenewCertificate(ctx context.Context, in *RenewCertificateRequest, opts ...grpc.CallOption) (*RenewCertificateResponse, error)
+	ListModules(ctx context.Context, in *ListModulesRequest, opts ...grpc.CallOption) (*ListModulesResponse, error)
+	GetModule(ctx context.Context, in *GetModuleRequest, opts ...grpc.CallOption) (*GetModuleResponse, error)
+	WatchModules(ctx context.Context, in *WatchModulesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ModuleEvent], error)
 }
 
 type modulesServiceClient struct {
@@ -63,6 +88,16 @@ func (c *modulesServiceClient) Register(ctx context.Context, in *RegisterRequest
 	return out, nil
 }
 
+func (c *modulesServiceClient) Reregister(ctx context.Context, in *ReregisterRequest, opts ...grpc.CallOption) (*ReregisterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReregisterResponse)
+	err := c.cc.Invoke(ctx, ModulesService_Reregister_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *modulesServiceClient) Setup(ctx context.Context, in *SetupRequest, opts ...grpc.CallOption) (*SetupResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(SetupResponse)
@@ -83,14 +118,104 @@ func (c *modulesServiceClient) Delete(ctx context.Context, in *DeleteRequest, op
 	return out, nil
 }
 
+func (c *modulesServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, ModulesService_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modulesServiceClient) HeartbeatStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[HeartbeatRequest, HeartbeatResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ModulesService_ServiceDesc.Streams[0], ModulesService_HeartbeatStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[HeartbeatRequest, HeartbeatResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModulesService_HeartbeatStreamClient = grpc.BidiStreamingClient[HeartbeatRequest, HeartbeatResponse]
+
+func (c *modulesServiceClient) RenewCertificate(ctx context.Context, in *RenewCertificateRequest, opts ...grpc.CallOption) (*RenewCertificateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenewCertificateResponse)
+	err := c.cc.Invoke(ctx, ModulesService_RenewCertificate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modulesServiceClient) ListModules(ctx context.Context, in *ListModulesRequest, opts ...grpc.CallOption) (*ListModulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListModulesResponse)
+	err := c.cc.Invoke(ctx, ModulesService_ListModules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modulesServiceClient) GetModule(ctx context.Context, in *GetModuleRequest, opts ...grpc.CallOption) (*GetModuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetModuleResponse)
+	err := c.cc.Invoke(ctx, ModulesService_GetModule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *modulesServiceClient) WatchModules(ctx context.Context, in *WatchModulesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ModuleEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ModulesService_ServiceDesc.Streams[1], ModulesService_WatchModules_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchModulesRequest, ModuleEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModulesService_WatchModulesClient = grpc.ServerStreamingClient[ModuleEvent]
+
 // ModulesServiceServer is the server API for ModulesService service.
 // All implementations must embed UnimplementedModulesServiceServer
 // for forward compatibility.
+//
+// Register requires a join token in the x-openplatform-join-token metadata.
+// All other calls except HealthCheck require the module credential returned
+// by Register in the x-openplatform-module-credential metadata.
+//
+// A module that restarts on a new address calls Reregister, or Register with
+// idempotent set, instead of registering a new module. Replicas of a module
+// call Register with add_instance set.
+//
+// ListModules, GetModule and WatchModules let modules discover each other.
+// Any authenticated module may look up every other module.
 type ModulesServiceServer interface {
 	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
 	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	Reregister(context.Context, *ReregisterRequest) (*ReregisterResponse, error)
 	Setup(context.Context, *SetupRequest) (*SetupResponse, error)
 	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	HeartbeatStream(grpc.BidiStreamingServer[HeartbeatRequest, HeartbeatResponse]) error
+	RenewCertificate(context.Context, *RenewCertificateRequest) (*RenewCertificateResponse, error)
+	ListModules(context.Context, *ListModulesRequest) (*ListModulesResponse, error)
+	GetModule(context.Context, *GetModuleRequest) (*GetModuleResponse, error)
+	WatchModules(*WatchModulesRequest, grpc.ServerStreamingServer[ModuleEvent]) error
 	mustEmbedUnimplementedModulesServiceServer()
 }
 
@@ -107,12 +232,33 @@ func (UnimplementedModulesServiceServer) HealthCheck(context.Context, *HealthChe
 func (UnimplementedModulesServiceServer) Register(context.Context, *RegisterRequest) (*RegisterResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
 }
+func (UnimplementedModulesServiceServer) Reregister(context.Context, *ReregisterRequest) (*ReregisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reregister not implemented")
+}
 func (UnimplementedModulesServiceServer) Setup(context.Context, *SetupRequest) (*SetupResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Setup not implemented")
 }
 func (UnimplementedModulesServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedModulesServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedModulesServiceServer) HeartbeatStream(grpc.BidiStreamingServer[HeartbeatRequest, HeartbeatResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method HeartbeatStream not implemented")
+}
+func (UnimplementedModulesServiceServer) RenewCertificate(context.Context, *RenewCertificateRequest) (*RenewCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCertificate not implemented")
+}
+func (UnimplementedModulesServiceServer) ListModules(context.Context, *ListModulesRequest) (*ListModulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListModules not implemented")
+}
+func (UnimplementedModulesServiceServer) GetModule(context.Context, *GetModuleRequest) (*GetModuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetModule not implemented")
+}
+func (UnimplementedModulesServiceServer) WatchModules(*WatchModulesRequest, grpc.ServerStreamingServer[ModuleEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchModules not implemented")
+}
 func (UnimplementedModulesServiceServer) mustEmbedUnimplementedModulesServiceServer() {}
 func (UnimplementedModulesServiceServer) testEmbeddedByValue()                        {}
 
@@ -170,6 +316,24 @@ func _ModulesService_Register_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModulesService_Reregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModulesServiceServer).Reregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModulesService_Reregister_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModulesServiceServer).Reregister(ctx, req.(*ReregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ModulesService_Setup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetupRequest)
 	if err := dec(in); err != nil {
@@ -206,6 +370,96 @@ func _ModulesService_Delete_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModulesService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModulesServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModulesService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModulesServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModulesService_HeartbeatStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ModulesServiceServer).HeartbeatStream(&grpc.GenericServerStream[HeartbeatRequest, HeartbeatResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModulesService_HeartbeatStreamServer = grpc.BidiStreamingServer[HeartbeatRequest, HeartbeatResponse]
+
+func _ModulesService_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModulesServiceServer).RenewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModulesService_RenewCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModulesServiceServer).RenewCertificate(ctx, req.(*RenewCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModulesService_ListModules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListModulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModulesServiceServer).ListModules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModulesService_ListModules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModulesServiceServer).ListModules(ctx, req.(*ListModulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModulesService_GetModule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetModuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModulesServiceServer).GetModule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ModulesService_GetModule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModulesServiceServer).GetModule(ctx, req.(*GetModuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModulesService_WatchModules_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchModulesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ModulesServiceServer).WatchModules(m, &grpc.GenericServerStream[WatchModulesRequest, ModuleEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ModulesService_WatchModulesServer = grpc.ServerStreamingServer[ModuleEvent]
+
 // ModulesService_ServiceDesc is the grpc.ServiceDesc for ModulesService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -221,6 +475,10 @@ var ModulesService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Register",
 			Handler:    _ModulesService_Register_Handler,
 		},
+		{
+			MethodName: "Reregister",
+			Handler:    _ModulesService_Reregister_Handler,
+		},
 		{
 			MethodName: "Setup",
 			Handler:    _ModulesService_Setup_Handler,
@@ -229,7 +487,35 @@ var ModulesService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _ModulesService_Delete_Handler,
 		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _ModulesService_Heartbeat_Handler,
+		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _ModulesService_RenewCertificate_Handler,
+		},
+		{
+			MethodName: "ListModules",
+			Handler:    _ModulesService_ListModules_Handler,
+		},
+		{
+			MethodName: "GetModule",
+			Handler:    _ModulesService_GetModule_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "HeartbeatStream",
+			Handler:       _ModulesService_HeartbeatStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchModules",
+			Handler:       _ModulesService_WatchModules_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/modules.proto",
 }
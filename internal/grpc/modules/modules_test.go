@@ -1 +1,423 @@
 package modules
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/ca"
+	"github.com/The-OpenPlatform/backend/internal/db"
+)
+
+func setupMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	previous := db.DB
+	db.DB = sqlx.NewDb(mockDB, "sqlmock")
+	t.Cleanup(func() {
+		db.DB = previous
+		mockDB.Close()
+	})
+
+	return mock
+}
+
+func TestHeartbeat(t *testing.T) {
+	t.Run("records heartbeat for known module", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectExec(`UPDATE modules SET last_seen_at`).
+			WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE module_instances i SET last_seen_at`).
+			WithArgs("00000000-0000-4000-a000-000000000001", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{Liveness: LivenessConfig{Interval: 5 * time.Second, StaleAfter: 2, OfflineAfter: 4}}
+		resp, err := s.Heartbeat(context.Background(), &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000001"})
+
+		require.NoError(t, err)
+		assert.True(t, resp.Success)
+		assert.Equal(t, StatusOnline, resp.Status)
+		assert.Equal(t, int32(5), resp.IntervalSeconds)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports unknown module", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectExec(`UPDATE modules SET last_seen_at`).
+			WithArgs("00000000-0000-4000-a000-000000000404").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		resp, err := (&Server{}).Heartbeat(context.Background(), &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000404"})
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Module not found", resp.Message)
+		assert.Equal(t, int32(10), resp.IntervalSeconds)
+	})
+
+	t.Run("reports unknown instance", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectExec(`UPDATE modules SET last_seen_at`).
+			WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE module_instances i SET last_seen_at`).
+			WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000404").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		resp, err := (&Server{}).Heartbeat(context.Background(), &HeartbeatRequest{
+			ModuleId: "00000000-0000-4000-a000-000000000001", InstanceId: "00000000-0000-4000-9000-000000000404",
+		})
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Instance not found", resp.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports malformed instance ID as unknown", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectExec(`UPDATE modules SET last_seen_at`).
+			WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		resp, err := (&Server{}).Heartbeat(context.Background(), &HeartbeatRequest{
+			ModuleId: "00000000-0000-4000-a000-000000000001", InstanceId: "gone",
+		})
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Equal(t, "Instance not found", resp.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects empty module ID", func(t *testing.T) {
+		resp, err := (&Server{}).Heartbeat(context.Background(), &HeartbeatRequest{ModuleId: " "})
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Contains(t, resp.Message, "module ID cannot be empty")
+	})
+}
+
+func TestReaperSweep(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectExec(`UPDATE modules SET status = 'OFFLINE'`).
+		WithArgs(float64(60)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE modules SET status = 'STALE'`).
+		WithArgs(float64(30)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE module_instances SET status = 'OFFLINE'`).
+		WithArgs(float64(60)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE module_instances SET status = 'STALE'`).
+		WithArgs(float64(30)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM module_instances i USING modules m`).
+		WithArgs(float64(3600)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	reaper := NewReaper(LivenessConfig{Interval: 10 * time.Second, StaleAfter: 3, OfflineAfter: 6, InstanceTTL: time.Hour})
+	changed, err := reaper.Sweep(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), changed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLivenessConfigValidate(t *testing.T) {
+	assert.NoError(t, DefaultLivenessConfig().Validate())
+	assert.Error(t, LivenessConfig{Interval: time.Second, StaleAfter: 3, OfflineAfter: 3}.Validate())
+	assert.Error(t, LivenessConfig{StaleAfter: 1, OfflineAfter: 2}.Validate())
+}
+
+func TestHealthCheckDetailed(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	previous := db.DB
+	db.DB = sqlx.NewDb(mockDB, "sqlmock")
+	t.Cleanup(func() {
+		db.DB = previous
+		mockDB.Close()
+	})
+
+	checkedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectPing()
+	mock.ExpectQuery(`FROM modules m\s+LEFT JOIN LATERAL`).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id", "name", "status", "healthy", "latency_ms", "error", "checked_at"}).
+			AddRow("00000000-0000-4000-a000-000000000001", "alpha", StatusOnline, true, 12, "", checkedAt).
+			AddRow("00000000-0000-4000-a000-000000000002", "beta", StatusStale, nil, nil, nil, nil))
+
+	resp, err := (&Server{}).HealthCheck(context.Background(), &HealthCheckRequest{Detailed: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, "DEGRADED", resp.Status)
+	assert.Equal(t, "OK", resp.Database)
+	require.Len(t, resp.Modules, 2)
+	assert.True(t, resp.Modules[0].Healthy)
+	assert.Equal(t, int64(12), resp.Modules[0].LatencyMs)
+	assert.Equal(t, checkedAt, resp.Modules[0].CheckedAt.AsTime())
+	assert.False(t, resp.Modules[1].Healthy)
+	assert.Nil(t, resp.Modules[1].CheckedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// newTestIssuer returns a CA issuer backed by db.DB and a PEM encoded CSR.
+func newTestIssuer(t *testing.T) (*ca.Issuer, []byte) {
+	t.Helper()
+
+	authority, err := ca.LoadOrCreate(context.Background(), ca.FileStore{Dir: t.TempDir()}, "Test CA")
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	require.NoError(t, err)
+
+	return ca.NewIssuer(authority, db.DB, time.Hour), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func certificateRows(moduleID string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"serial", "module_id", "not_after", "revoked_at", "created_at"}).
+		AddRow("ab", moduleID, time.Now().Add(time.Hour), nil, time.Now())
+}
+
+func TestRegisterMetadata(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT m.name AS module`).WillReturnRows(sqlmock.NewRows([]string{"module", "name", "version_range"}))
+	mock.ExpectQuery(`INSERT INTO modules`).
+		WithArgs("dashboard", "10.0.0.1", int32(8080), "Platform overview", "1.4.0", "ops",
+			pq.StringArray{"ui"}, "https://example.com/dashboard", pq.StringArray{"ui.widget"}, int32(1), "",
+			pq.StringArray{"auth"}, pq.StringArray{"^1.2"}).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+	mock.ExpectCommit()
+
+	s := &Server{}
+	resp, err := s.Register(context.Background(), &RegisterRequest{
+		Name: "dashboard", Ip: "10.0.0.1", Port: 8080,
+		Description: "Platform overview", Version: "1.4.0", Author: "ops",
+		Tags: []string{"ui"}, Homepage: "https://example.com/dashboard", Capabilities: []string{"ui.widget"},
+		Dependencies: []*Dependency{{Name: "auth", Version: "^1.2"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Success, resp.Message)
+
+	resp, err = s.Register(context.Background(), &RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080, Version: "latest"})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Contains(t, resp.Message, "invalid version")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReregister(t *testing.T) {
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"description", "version", "author", "tags", "homepage", "capabilities"}
+
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "OFFLINE", nil, nil, "", "1.0.0", "", "{}", "", "{}"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM module_instances`).WithArgs("00000000-0000-4000-a000-000000000001", "10.0.0.9", int32(8080)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE modules SET host`).
+		WithArgs("10.0.0.9", int32(8080), "", "1.1.0", "", pq.StringArray{"ui"}, "", pq.StringArray{}, "00000000-0000-4000-a000-000000000001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO module_dependencies`).WithArgs("00000000-0000-4000-a000-000000000001", pq.StringArray{}, pq.StringArray{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.9", 8080, "10.0.0.9:8080", "ONLINE", nil, nil, "", "1.1.0", "", "{ui}", "", "{}"))
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000009").WillReturnRows(sqlmock.NewRows(columns))
+
+	s := &Server{}
+	resp, err := s.Reregister(context.Background(), &ReregisterRequest{
+		ModuleId: "00000000-0000-4000-a000-000000000001", Ip: "10.0.0.9", Port: 8080, Version: "1.1.0", Tags: []string{"UI"},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Success, resp.Message)
+
+	resp, err = s.Reregister(context.Background(), &ReregisterRequest{ModuleId: "00000000-0000-4000-a000-000000000009", Ip: "10.0.0.9", Port: 8080})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "Module not found", resp.Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterAddInstance(t *testing.T) {
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"description", "version", "author", "tags", "homepage", "capabilities"}
+
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT module_id, name, host.* WHERE name = \$1`).WithArgs("dashboard").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "", "", "", "{}", "", "{}"))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`INSERT INTO module_instances`).WithArgs("00000000-0000-4000-a000-000000000001", "10.0.0.2", int32(8080), int32(2), "eu-west-1b").
+		WillReturnRows(sqlmock.NewRows([]string{"instance_id"}).AddRow("00000000-0000-4000-9000-000000000002"))
+	mock.ExpectQuery(`SELECT i.instance_id`).WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000002").
+		WillReturnRows(sqlmock.NewRows([]string{"instance_id", "module_id", "host", "port", "ip_port", "weight", "zone", "status", "healthy", "last_seen_at", "is_primary"}).
+			AddRow("00000000-0000-4000-9000-000000000002", "00000000-0000-4000-a000-000000000001", "10.0.0.2", 8080, "10.0.0.2:8080", 2, "eu-west-1b", "ONLINE", nil, nil, false))
+
+	resp, err := (&Server{}).Register(context.Background(), &RegisterRequest{
+		Name: "dashboard", Ip: "10.0.0.2", Port: 8080, Weight: 2, Zone: "eu-west-1b", AddInstance: true,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, resp.Success, resp.Message)
+	assert.True(t, resp.Existing)
+	assert.Equal(t, "00000000-0000-4000-a000-000000000001", resp.ModuleId)
+	assert.Equal(t, "00000000-0000-4000-9000-000000000002", resp.InstanceId)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterInfersAddress(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("2001:db8::7"), Port: 51234},
+	})
+	req := &RegisterRequest{Name: "dashboard", Port: 8080}
+
+	t.Run("uses the peer address when enabled", func(t *testing.T) {
+		mock := setupMockDB(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).
+			WithArgs("dashboard", "2001:db8::7", int32(8080), "", "", "", pq.StringArray{}, "", pq.StringArray{}, int32(1), "", pq.StringArray{}, pq.StringArray{}).
+			WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+
+		resp, err := (&Server{InferAddress: true}).Register(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, resp.Success, resp.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requires an address otherwise", func(t *testing.T) {
+		setupMockDB(t)
+
+		resp, err := (&Server{}).Register(ctx, req)
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Contains(t, resp.Message, "host cannot be empty")
+	})
+}
+
+func TestRegisterWithCSR(t *testing.T) {
+	t.Run("issues certificate", func(t *testing.T) {
+		mock := setupMockDB(t)
+		issuer, csr := newTestIssuer(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`INSERT INTO module_certificates`).WithArgs(sqlmock.AnyArg(), "00000000-0000-4000-a000-000000000001", sqlmock.AnyArg()).
+			WillReturnRows(certificateRows("00000000-0000-4000-a000-000000000001"))
+
+		s := &Server{CA: issuer}
+		resp, err := s.Register(context.Background(), &RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080, Csr: csr})
+
+		require.NoError(t, err)
+		require.True(t, resp.Success, resp.Message)
+		assert.Equal(t, issuer.Authority().CertificatePEM(), resp.CaCertificate)
+
+		block, _ := pem.Decode(resp.Certificate)
+		require.NotNil(t, block)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		assert.Equal(t, "00000000-0000-4000-a000-000000000001", cert.Subject.CommonName)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects invalid CSR before registering", func(t *testing.T) {
+		mock := setupMockDB(t)
+		issuer, _ := newTestIssuer(t)
+
+		s := &Server{CA: issuer}
+		resp, err := s.Register(context.Background(), &RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080, Csr: []byte("junk")})
+
+		require.NoError(t, err)
+		assert.False(t, resp.Success)
+		assert.Contains(t, resp.Message, "certificate signing request")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("removes module when issuing fails", func(t *testing.T) {
+		mock := setupMockDB(t)
+		issuer, csr := newTestIssuer(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`INSERT INTO module_certificates`).WillReturnError(assert.AnError)
+		mock.ExpectExec(`DELETE FROM modules`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		s := &Server{CA: issuer}
+		resp, err := s.Register(context.Background(), &RegisterRequest{Name: "dashboard", Ip: "10.0.0.1", Port: 8080, Csr: csr})
+
+		assert.Error(t, err)
+		assert.False(t, resp.Success)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRenewCertificate(t *testing.T) {
+	_, err := (&Server{}).RenewCertificate(context.Background(), &RenewCertificateRequest{ModuleId: "00000000-0000-4000-a000-000000000001"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	mock := setupMockDB(t)
+	issuer, csr := newTestIssuer(t)
+	s := &Server{CA: issuer}
+
+	mock.ExpectQuery(`FROM modules WHERE module_id`).WithArgs("00000000-0000-4000-a000-000000000009").WillReturnRows(sqlmock.NewRows([]string{"module_id"}))
+	resp, err := s.RenewCertificate(context.Background(), &RenewCertificateRequest{ModuleId: "00000000-0000-4000-a000-000000000009", Csr: csr})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "Module not found", resp.Message)
+
+	moduleRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"module_id", "name", "ip_port"}).AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1:8080")
+	}
+
+	// Without authentication nobody proves to be the module
+	mock.ExpectQuery(`FROM modules WHERE module_id`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(moduleRows())
+	_, err = s.RenewCertificate(context.Background(), &RenewCertificateRequest{ModuleId: "00000000-0000-4000-a000-000000000001", Csr: csr})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	mock.ExpectQuery(`FROM modules WHERE module_id`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(moduleRows())
+	mock.ExpectQuery(`INSERT INTO module_certificates`).WithArgs(sqlmock.AnyArg(), "00000000-0000-4000-a000-000000000001", sqlmock.AnyArg()).
+		WillReturnRows(certificateRows("00000000-0000-4000-a000-000000000001"))
+
+	resp, err = s.RenewCertificate(withClientCert("dashboard"), &RenewCertificateRequest{ModuleId: "00000000-0000-4000-a000-000000000001", Csr: csr})
+	require.NoError(t, err)
+	assert.True(t, resp.Success, resp.Message)
+	assert.NotEmpty(t, resp.Certificate)
+	assert.NotNil(t, resp.ExpiresAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
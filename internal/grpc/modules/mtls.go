@@ -0,0 +1,145 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/certs"
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// moduleLookup resolves module IDs and names to modules.
+type moduleLookup interface {
+	Get(ctx context.Context, moduleID string) (*service.Module, error)
+	FindByName(ctx context.Context, name string) (*service.Module, error)
+}
+
+// UnaryIdentityInterceptor restricts callers that present a verified client
+// certificate to the module named by it. The certificate's common name, DNS
+// or URI SANs must equal the module's name or ID; Register may only create a
+// module with one of those names. Calls without a client certificate and
+// calls to other services pass through unchanged.
+func UnaryIdentityInterceptor(modules moduleLookup) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ids, ok := peerIdentities(ctx)
+		if !ok || !strings.HasPrefix(info.FullMethod, servicePrefix) {
+			return handler(ctx, req)
+		}
+
+		if err := checkIdentity(ctx, modules, ids, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamIdentityInterceptor is the streaming counterpart of
+// UnaryIdentityInterceptor. Every received message is checked; modules that
+// already passed are remembered for the lifetime of the stream.
+func StreamIdentityInterceptor(modules moduleLookup) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ids, ok := peerIdentities(ss.Context())
+		if !ok || !strings.HasPrefix(info.FullMethod, servicePrefix) {
+			return handler(srv, ss)
+		}
+
+		allowed := make(map[string]bool)
+		return handler(srv, &checkedStream{
+			ServerStream: ss,
+			ctx:          ss.Context(),
+			check: func(m interface{}) error {
+				scoped, ok := m.(moduleScoped)
+				if ok && allowed[scoped.GetModuleId()] {
+					return nil
+				}
+
+				if err := checkIdentity(ss.Context(), modules, ids, m); err != nil {
+					return err
+				}
+
+				if ok {
+					allowed[scoped.GetModuleId()] = true
+				}
+				return nil
+			},
+		})
+	}
+}
+
+// peerIdentities returns the identities of the verified client certificate
+// of the caller, if it presented one.
+func peerIdentities(ctx context.Context) ([]string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	return certs.Identities(tlsInfo.State.VerifiedChains[0][0]), true
+}
+
+// checkIdentity reports whether a certificate with the given identities may
+// issue req.
+func checkIdentity(ctx context.Context, modules moduleLookup, ids []string, req interface{}) error {
+	switch r := req.(type) {
+	case *RegisterRequest:
+		if slices.Contains(ids, r.Name) {
+			return nil
+		}
+
+		// Certificates from the internal CA name the module ID, which an
+		// idempotent Register or a new instance of an existing module may
+		// present
+		if r.Idempotent || r.AddInstance {
+			module, err := modules.FindByName(ctx, r.Name)
+			if err != nil && !errors.Is(err, service.ErrNotFound) {
+				return status.Error(codes.Internal, "failed to resolve module")
+			}
+			if module != nil && identifies(ids, module) {
+				return nil
+			}
+		}
+
+		return status.Errorf(codes.PermissionDenied, "client certificate does not allow registering module %q", r.Name)
+
+	case *GetModuleRequest:
+		// Any module may look up another for discovery
+		return nil
+
+	case moduleScoped:
+		module, err := modules.Get(ctx, r.GetModuleId())
+		if errors.Is(err, service.ErrNotFound) {
+			// Let the handler report the missing module as usual
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, "failed to resolve module")
+		}
+
+		if !identifies(ids, module) {
+			return status.Error(codes.PermissionDenied, "client certificate does not belong to the requested module")
+		}
+	}
+
+	return nil
+}
+
+// identifies reports whether certificate identities name module.
+func identifies(ids []string, module *service.Module) bool {
+	return slices.Contains(ids, module.Name) || slices.ContainsFunc(ids, func(id string) bool {
+		return strings.EqualFold(id, module.ModuleID)
+	})
+}
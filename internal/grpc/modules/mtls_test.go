@@ -0,0 +1,95 @@
+package modules
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/The-OpenPlatform/backend/internal/service"
+)
+
+// fakeModules resolves modules from a map.
+type fakeModules map[string]string
+
+func (f fakeModules) Get(_ context.Context, moduleID string) (*service.Module, error) {
+	name, ok := f[moduleID]
+	if !ok {
+		return nil, service.ErrNotFound
+	}
+	return &service.Module{ModuleID: moduleID, Name: name}, nil
+}
+
+func (f fakeModules) FindByName(_ context.Context, name string) (*service.Module, error) {
+	for moduleID, n := range f {
+		if n == name {
+			return &service.Module{ModuleID: moduleID, Name: name}, nil
+		}
+	}
+	return nil, service.ErrNotFound
+}
+
+// withClientCert returns a context whose peer presented a verified
+// certificate with the given common name.
+func withClientCert(cn string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func TestUnaryIdentityInterceptor(t *testing.T) {
+	interceptor := UnaryIdentityInterceptor(fakeModules{"00000000-0000-4000-a000-000000000001": "dashboard", "00000000-0000-4000-a000-000000000002": "notes"})
+	handler := func(context.Context, interface{}) (interface{}, error) { return nil, nil }
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+	}{
+		{"no client certificate", context.Background(), ModulesService_Delete_FullMethodName, &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.OK},
+		{"register own name", withClientCert("dashboard"), ModulesService_Register_FullMethodName, &RegisterRequest{Name: "dashboard"}, codes.OK},
+		{"register other name", withClientCert("dashboard"), ModulesService_Register_FullMethodName, &RegisterRequest{Name: "notes"}, codes.PermissionDenied},
+		{"reregister by module ID", withClientCert("00000000-0000-4000-a000-000000000001"), ModulesService_Register_FullMethodName, &RegisterRequest{Name: "dashboard", Idempotent: true}, codes.OK},
+		{"register by module ID", withClientCert("00000000-0000-4000-a000-000000000001"), ModulesService_Register_FullMethodName, &RegisterRequest{Name: "dashboard"}, codes.PermissionDenied},
+		{"reregister other module", withClientCert("00000000-0000-4000-a000-000000000001"), ModulesService_Register_FullMethodName, &RegisterRequest{Name: "notes", Idempotent: true}, codes.PermissionDenied},
+		{"reregister call", withClientCert("dashboard"), ModulesService_Reregister_FullMethodName, &ReregisterRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.PermissionDenied},
+		{"setup own module", withClientCert("dashboard"), ModulesService_Setup_FullMethodName, &SetupRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}, codes.OK},
+		{"certificate names module ID", withClientCert("00000000-0000-4000-A000-000000000002"), ModulesService_Setup_FullMethodName, &SetupRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.OK},
+		{"delete other module", withClientCert("dashboard"), ModulesService_Delete_FullMethodName, &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.PermissionDenied},
+		{"unknown module", withClientCert("dashboard"), ModulesService_Delete_FullMethodName, &DeleteRequest{ModuleId: "00000000-0000-4000-a000-000000000009"}, codes.OK},
+		{"health check", withClientCert("dashboard"), ModulesService_HealthCheck_FullMethodName, &HealthCheckRequest{}, codes.OK},
+		{"look up other module", withClientCert("dashboard"), ModulesService_GetModule_FullMethodName, &GetModuleRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(tt.ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestStreamIdentityInterceptor(t *testing.T) {
+	interceptor := StreamIdentityInterceptor(fakeModules{"00000000-0000-4000-a000-000000000001": "dashboard", "00000000-0000-4000-a000-000000000002": "notes"})
+	info := &grpc.StreamServerInfo{FullMethod: ModulesService_HeartbeatStream_FullMethodName}
+	recv := func(_ interface{}, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&HeartbeatRequest{})
+	}
+
+	ctx := withClientCert("dashboard")
+	assert.NoError(t, interceptor(nil, &recvStream{ctx: ctx, req: &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000001"}}, info, recv))
+
+	err := interceptor(nil, &recvStream{ctx: ctx, req: &HeartbeatRequest{ModuleId: "00000000-0000-4000-a000-000000000002"}}, info, recv)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,74 @@
+// Package lifecycle coordinates startup readiness and graceful shutdown of the
+// backend's servers.
+package lifecycle
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Readiness tracks whether the process should receive new traffic. It starts
+// out not ready, is flipped to ready once all listeners are bound, and back to
+// not ready as soon as shutdown begins so load balancers stop routing to the
+// instance before its servers drain.
+type Readiness struct {
+	mu        sync.Mutex
+	ready     bool
+	listeners []func(ready bool)
+}
+
+// Ready reports whether the process currently accepts new traffic.
+func (r *Readiness) Ready() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ready
+}
+
+// SetReady updates the readiness state and notifies listeners when it changes.
+func (r *Readiness) SetReady(ready bool) {
+	r.mu.Lock()
+	if r.ready == ready {
+		r.mu.Unlock()
+		return
+	}
+	r.ready = ready
+	listeners := append([]func(bool){}, r.listeners...)
+	r.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(ready)
+	}
+}
+
+// OnChange registers fn to be called with the new state on every change.
+// fn is called immediately with the current state.
+func (r *Readiness) OnChange(fn func(ready bool)) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, fn)
+	ready := r.ready
+	r.mu.Unlock()
+
+	fn(ready)
+}
+
+// StopGRPC gracefully stops s, waiting for pending RPCs to finish until ctx is
+// done, after which the remaining connections are closed forcibly. It reports
+// ctx's error when the drain deadline was hit.
+func StopGRPC(ctx context.Context, s *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package lifecycle
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReadinessNotifiesChanges(t *testing.T) {
+	var r Readiness
+	var states []bool
+	r.OnChange(func(ready bool) { states = append(states, ready) })
+
+	r.SetReady(true)
+	r.SetReady(true)
+	r.SetReady(false)
+
+	assert.False(t, r.Ready())
+	assert.Equal(t, []bool{false, true, false}, states)
+}
+
+// blockingHealth holds Watch streams open until the server stops them.
+type blockingHealth struct {
+	healthpb.UnimplementedHealthServer
+	started chan struct{}
+}
+
+func (b blockingHealth) Watch(_ *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	close(b.started)
+	<-stream.Context().Done()
+	return stream.Context().Err()
+}
+
+func TestStopGRPC(t *testing.T) {
+	t.Run("drains idle server", func(t *testing.T) {
+		s := grpc.NewServer()
+		healthpb.RegisterHealthServer(s, health.NewServer())
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go s.Serve(lis)
+
+		assert.NoError(t, StopGRPC(context.Background(), s))
+	})
+
+	t.Run("forces stop after deadline", func(t *testing.T) {
+		started := make(chan struct{})
+		s := grpc.NewServer()
+		healthpb.RegisterHealthServer(s, blockingHealth{started: started})
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go s.Serve(lis)
+
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, StopGRPC(ctx, s), context.DeadlineExceeded)
+	})
+}
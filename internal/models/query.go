@@ -1,12 +1,25 @@
 package models
 
+// QueryRequest is the body of POST /api/query. Params are bound to the
+// $1, $2, ... placeholders of Query.
 type QueryRequest struct {
-	Query string `json:"query"`
+	Query  string        `json:"query"`
+	Params []interface{} `json:"params,omitempty"`
 }
 
+// QueryResponse is the result of a POST /api/query statement that modifies
+// data, or an error. Read-only results are streamed by the query package.
 type QueryResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
 	Rows    int         `json:"rows,omitempty"`
 }
+
+// SavedQueryRequest is the body of POST /api/queries. Params, if given, are
+// the defaults used by runs that pass no parameters of their own.
+type SavedQueryRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	QueryRequest
+}
@@ -0,0 +1,249 @@
+// Package oidc lets users sign in to the REST API through an OpenID Connect
+// identity provider.
+//
+// Browsers use the authorization code flow with PKCE; API clients send a
+// token issued by the provider as a bearer token. Either way the token's
+// signature is checked against the provider's JWKS, which is cached and
+// refetched when a token names an unknown key, and a configurable claim is
+// mapped to a platform role.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+// ErrInvalidToken is returned for tokens that fail verification.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config describes the provider and how its claims map to roles.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// Audiences accepted on bearer tokens; defaults to the client ID.
+	Audiences     []string
+	UsernameClaim string
+	RoleClaim     string
+	// RoleMapping maps values of the role claim to roles. When several
+	// values match, the most privileged role wins.
+	RoleMapping map[string]users.Role
+	// DefaultRole is given to users without a mapped value; empty denies them.
+	DefaultRole users.Role
+}
+
+// ParseRoleMapping parses value=role entries.
+func ParseRoleMapping(entries []string) (map[string]users.Role, error) {
+	mapping := make(map[string]users.Role, len(entries))
+	for _, entry := range entries {
+		value, role, ok := strings.Cut(entry, "=")
+		if !ok || value == "" || !users.Role(role).Valid() {
+			return nil, fmt.Errorf("invalid role mapping %q", entry)
+		}
+		mapping[value] = users.Role(role)
+	}
+	return mapping, nil
+}
+
+// Identity is a user authenticated by the provider.
+type Identity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	// Role is empty when no claim value maps to a role and there is no default.
+	Role users.Role
+}
+
+// ExternalID identifies the account across logins.
+func (i *Identity) ExternalID() string {
+	return i.Issuer + "|" + i.Subject
+}
+
+// Provider is a configured OpenID Connect relying party.
+type Provider struct {
+	cfg      Config
+	oauth2   oauth2.Config
+	login    *gooidc.IDTokenVerifier
+	bearer   *gooidc.IDTokenVerifier
+	audience []string
+}
+
+// New discovers the provider's endpoints and keys. ctx bounds discovery and
+// carries the HTTP client used for later key fetches, see
+// gooidc.ClientContext.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	provider, err := gooidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	audience := cfg.Audiences
+	if len(audience) == 0 {
+		audience = []string{cfg.ClientID}
+	}
+
+	return &Provider{
+		cfg: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+		login: provider.Verifier(&gooidc.Config{ClientID: cfg.ClientID}),
+		// Audiences are checked by VerifyBearer, which accepts several
+		bearer:   provider.Verifier(&gooidc.Config{SkipClientIDCheck: true}),
+		audience: audience,
+	}, nil
+}
+
+// LoginState is kept by the browser between the redirect to the provider
+// and the callback. It must not be readable by scripts.
+type LoginState struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewLoginState generates the state, nonce and PKCE verifier of a login.
+func NewLoginState() (LoginState, error) {
+	state, err := randomString()
+	if err != nil {
+		return LoginState{}, err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return LoginState{}, err
+	}
+
+	return LoginState{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+// Encode serializes the state for a cookie.
+func (s LoginState) Encode() string {
+	return s.State + "." + s.Nonce + "." + s.Verifier
+}
+
+// DecodeLoginState parses the output of Encode.
+func DecodeLoginState(v string) (LoginState, bool) {
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return LoginState{}, false
+	}
+	return LoginState{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, true
+}
+
+// AuthCodeURL returns the provider URL that starts a login.
+func (p *Provider) AuthCodeURL(s LoginState) string {
+	return p.oauth2.AuthCodeURL(s.State, gooidc.Nonce(s.Nonce), oauth2.S256ChallengeOption(s.Verifier))
+}
+
+// Exchange redeems the authorization code of a login and verifies the
+// returned ID token.
+func (p *Provider) Exchange(ctx context.Context, code string, s LoginState) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(s.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidToken)
+	}
+
+	idToken, err := p.login.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if idToken.Nonce != s.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+
+	return p.identity(idToken)
+}
+
+// VerifyBearer verifies a token sent by an API client.
+func (p *Provider) VerifyBearer(ctx context.Context, rawToken string) (*Identity, error) {
+	token, err := p.bearer.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if !slices.ContainsFunc(token.Audience, func(aud string) bool { return slices.Contains(p.audience, aud) }) {
+		return nil, fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, token.Audience)
+	}
+
+	return p.identity(token)
+}
+
+// identity maps the claims of a verified token.
+func (p *Provider) identity(token *gooidc.IDToken) (*Identity, error) {
+	var claims map[string]interface{}
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	username, _ := claims[p.cfg.UsernameClaim].(string)
+	if username == "" {
+		username = token.Subject
+	}
+
+	return &Identity{
+		Issuer:   token.Issuer,
+		Subject:  token.Subject,
+		Username: username,
+		Role:     p.role(claims[p.cfg.RoleClaim]),
+	}, nil
+}
+
+// role returns the most privileged role mapped from a claim holding a
+// string or a list of strings.
+func (p *Provider) role(claim interface{}) users.Role {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var best users.Role
+	for _, value := range values {
+		role, ok := p.cfg.RoleMapping[value]
+		if ok && !best.Allows(role) {
+			best = role
+		}
+	}
+
+	if best == "" {
+		return p.cfg.DefaultRole
+	}
+	return best
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate login state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,137 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/The-OpenPlatform/backend/internal/oidc/oidctest"
+	"github.com/The-OpenPlatform/backend/internal/users"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Issuer) {
+	t.Helper()
+
+	issuer := oidctest.NewIssuer(t, "platform")
+	provider, err := New(context.Background(), Config{
+		IssuerURL:     issuer.URL,
+		ClientID:      "platform",
+		RedirectURL:   "https://platform.example/api/auth/oidc/callback",
+		Scopes:        []string{"openid"},
+		UsernameClaim: "preferred_username",
+		RoleClaim:     "groups",
+		RoleMapping:   map[string]users.Role{"ops": users.RoleOperator, "platform-admins": users.RoleAdmin},
+	})
+	require.NoError(t, err)
+
+	return provider, issuer
+}
+
+// authorize follows the login redirect to the issuer and returns the
+// callback query it redirects back with.
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	issuer.SetClaims(map[string]interface{}{
+		"sub":                "1234",
+		"preferred_username": "alice",
+		"groups":             []string{"staff", "ops"},
+	})
+
+	state, err := NewLoginState()
+	require.NoError(t, err)
+
+	callback := authorize(t, provider.AuthCodeURL(state))
+	assert.Equal(t, state.State, callback.Get("state"))
+
+	identity, err := provider.Exchange(context.Background(), callback.Get("code"), state)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Username)
+	assert.Equal(t, users.RoleOperator, identity.Role)
+	assert.Equal(t, issuer.URL+"|1234", identity.ExternalID())
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	issuer.SetClaims(map[string]interface{}{"sub": "1234"})
+
+	state, err := NewLoginState()
+	require.NoError(t, err)
+	callback := authorize(t, provider.AuthCodeURL(state))
+
+	other, err := NewLoginState()
+	require.NoError(t, err)
+	state.Verifier = other.Verifier
+
+	_, err = provider.Exchange(context.Background(), callback.Get("code"), state)
+	assert.Error(t, err)
+}
+
+func TestVerifyBearer(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	ctx := context.Background()
+
+	identity, err := provider.VerifyBearer(ctx, issuer.Sign(map[string]interface{}{
+		"sub":    "1234",
+		"groups": "ops platform-admins",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "1234", identity.Username)
+	assert.Equal(t, users.RoleAdmin, identity.Role)
+
+	identity, err = provider.VerifyBearer(ctx, issuer.Sign(map[string]interface{}{"sub": "1234", "groups": []string{"staff"}}))
+	require.NoError(t, err)
+	assert.Empty(t, identity.Role)
+
+	for name, claims := range map[string]map[string]interface{}{
+		"other audience": {"sub": "1234", "aud": "someone-else"},
+		"expired":        {"sub": "1234", "exp": time.Now().Add(-time.Hour).Unix()},
+		"other issuer":   {"sub": "1234", "iss": "https://evil.example"},
+	} {
+		_, err := provider.VerifyBearer(ctx, issuer.Sign(claims))
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+
+	forged := oidctest.NewIssuer(t, "platform").Sign(map[string]interface{}{"sub": "1234", "iss": issuer.URL})
+	_, err = provider.VerifyBearer(ctx, forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLoginStateEncoding(t *testing.T) {
+	state, err := NewLoginState()
+	require.NoError(t, err)
+
+	decoded, ok := DecodeLoginState(state.Encode())
+	require.True(t, ok)
+	assert.Equal(t, state, decoded)
+
+	_, ok = DecodeLoginState("a..b")
+	assert.False(t, ok)
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping, err := ParseRoleMapping([]string{"ops=operator", "admins=admin"})
+	require.NoError(t, err)
+	assert.Equal(t, users.RoleOperator, mapping["ops"])
+
+	_, err = ParseRoleMapping([]string{"ops=root"})
+	assert.Error(t, err)
+}
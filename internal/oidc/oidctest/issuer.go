@@ -0,0 +1,196 @@
+// Package oidctest provides a minimal OpenID Connect issuer for tests. It
+// serves discovery, JWKS, authorization and token endpoints and signs
+// tokens with an RSA key generated at start.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+const keyID = "test-key"
+
+// Issuer is a running stand-in identity provider.
+type Issuer struct {
+	URL      string
+	ClientID string
+
+	key    *rsa.PrivateKey
+	server *httptest.Server
+
+	mu sync.Mutex
+	// claims are added to the ID token of the next login
+	claims map[string]interface{}
+	codes  map[string]grant
+}
+
+// grant is an issued authorization code.
+type grant struct {
+	challenge string
+	nonce     string
+	claims    map[string]interface{}
+}
+
+// NewIssuer starts an issuer that is shut down when the test ends.
+func NewIssuer(t testing.TB, clientID string) *Issuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &Issuer{ClientID: clientID, key: key, codes: make(map[string]grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", i.discovery)
+	mux.HandleFunc("GET /jwks", i.jwks)
+	mux.HandleFunc("GET /authorize", i.authorize)
+	mux.HandleFunc("POST /token", i.token)
+
+	i.server = httptest.NewServer(mux)
+	i.URL = i.server.URL
+	t.Cleanup(i.server.Close)
+
+	return i
+}
+
+// SetClaims sets the claims of the user who logs in next.
+func (i *Issuer) SetClaims(claims map[string]interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.claims = claims
+}
+
+// Sign returns a token with the given claims. iss, aud, iat and exp are
+// filled in unless present.
+func (i *Issuer) Sign(claims map[string]interface{}) string {
+	payload := map[string]interface{}{
+		"iss": i.URL,
+		"aud": i.ClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		payload[k] = v
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	body, _ := json.Marshal(payload)
+	signingInput := encode(header) + "." + encode(body)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return signingInput + "." + encode(sig)
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": keyID,
+			"n":   encode(i.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize logs in the configured user without asking and redirects back
+// with a code.
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != i.ClientID || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	code := encode(b)
+
+	i.mu.Lock()
+	i.codes[code] = grant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: i.claims}
+	i.mu.Unlock()
+
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", q.Get("state"))
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token redeems a code after checking the PKCE verifier.
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	i.mu.Lock()
+	g, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || encode(verifier[:]) != g.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]interface{}{"nonce": g.nonce}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": "opaque",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     i.Sign(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
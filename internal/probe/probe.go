@@ -0,0 +1,276 @@
+// Package probe actively checks the health of registered modules.
+// It periodically calls every instance of every module using either the
+// standard gRPC health protocol (grpc.health.v1) or a plain HTTP GET, keeps a
+// history of probe results and latencies in the module_probes table and
+// records the latest result on the instance, where the module proxy reads it.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/The-OpenPlatform/backend/internal/db"
+)
+
+// Supported probe modes.
+const (
+	ModeGRPC = "grpc"
+	ModeHTTP = "http"
+)
+
+// Config controls how and how often modules are probed.
+type Config struct {
+	// Interval is the time between two probe rounds.
+	Interval time.Duration
+	// Timeout bounds a single probe.
+	Timeout time.Duration
+	// Mode selects the probe protocol, either ModeGRPC or ModeHTTP.
+	Mode string
+	// HTTPPath is the path requested when Mode is ModeHTTP.
+	HTTPPath string
+	// Concurrency limits how many modules are probed at the same time.
+	Concurrency int
+	// Retention is how long probe results are kept before being pruned.
+	Retention time.Duration
+}
+
+// DefaultConfig returns the probe settings used when none are configured.
+func DefaultConfig() Config {
+	return Config{
+		Interval:    30 * time.Second,
+		Timeout:     5 * time.Second,
+		Mode:        ModeGRPC,
+		HTTPPath:    "/healthz",
+		Concurrency: 8,
+		Retention:   24 * time.Hour,
+	}
+}
+
+// Validate checks that the configuration is usable.
+func (c Config) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("probe interval must be positive")
+	}
+
+	if c.Timeout <= 0 {
+		return fmt.Errorf("probe timeout must be positive")
+	}
+
+	if c.Mode != ModeGRPC && c.Mode != ModeHTTP {
+		return fmt.Errorf("unsupported probe mode: %s", c.Mode)
+	}
+
+	if c.Mode == ModeHTTP && !strings.HasPrefix(c.HTTPPath, "/") {
+		return fmt.Errorf("probe HTTP path must start with '/'")
+	}
+
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("probe concurrency must be positive")
+	}
+
+	return nil
+}
+
+// Result is the outcome of probing a single module instance.
+type Result struct {
+	ModuleID   string    `db:"module_id"`
+	InstanceID string    `db:"instance_id"`
+	Kind       string    `db:"kind"`
+	Healthy    bool      `db:"healthy"`
+	LatencyMS  int64     `db:"latency_ms"`
+	Error      string    `db:"error"`
+	CheckedAt  time.Time `db:"checked_at"`
+}
+
+// target is a module instance that should be probed.
+type target struct {
+	ModuleID   string `db:"module_id"`
+	InstanceID string `db:"instance_id"`
+	Host       string `db:"host"`
+	Port       int    `db:"port"`
+}
+
+// Prober probes registered modules on a schedule.
+type Prober struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a prober using the given configuration.
+func New(cfg Config) *Prober {
+	return &Prober{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Run probes all module instances once per interval until ctx is cancelled.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.ProbeAll(ctx); err != nil {
+				log.Printf("module probe round failed: %v", err)
+			}
+		}
+	}
+}
+
+// ProbeAll probes every instance of every registered module, stores the
+// results and prunes results older than the configured retention.
+func (p *Prober) ProbeAll(ctx context.Context) error {
+	var targets []target
+	if err := db.DB.SelectContext(ctx, &targets, `SELECT module_id, instance_id, host, port FROM module_instances`); err != nil {
+		return fmt.Errorf("failed to load module instances: %w", err)
+	}
+
+	results := make([]Result, len(targets))
+	sem := make(chan struct{}, p.cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = p.Probe(ctx, net.JoinHostPort(t.Host, strconv.Itoa(t.Port)))
+			results[i].ModuleID = t.ModuleID
+			results[i].InstanceID = t.InstanceID
+		}(i, t)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if err := saveResult(ctx, r); err != nil {
+			log.Printf("failed to save probe result of instance %s: %v", r.InstanceID, err)
+		}
+	}
+
+	if p.cfg.Retention > 0 {
+		if err := prune(ctx, p.cfg.Retention); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Probe checks a single address and reports the outcome and latency.
+func (p *Prober) Probe(ctx context.Context, address string) Result {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var err error
+	switch p.cfg.Mode {
+	case ModeHTTP:
+		err = p.probeHTTP(ctx, address)
+	default:
+		err = p.probeGRPC(ctx, address)
+	}
+
+	result := Result{
+		Kind:      p.cfg.Mode,
+		Healthy:   err == nil,
+		LatencyMS: time.Since(start).Milliseconds(),
+		CheckedAt: start.UTC(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// probeGRPC calls grpc.health.v1.Health/Check on the module.
+func (p *Prober) probeGRPC(ctx context.Context, address string) error {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("module reported %s", resp.Status)
+	}
+
+	return nil
+}
+
+// probeHTTP issues a GET against the configured path and expects a 2xx response.
+func (p *Prober) probeHTTP(ctx context.Context, address string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+p.cfg.HTTPPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// saveResult appends a probe result to the module's history and records it
+// as the instance's health.
+func saveResult(ctx context.Context, r Result) error {
+	// The history row is inserted from the updated instance, so an instance
+	// removed while the round was running is skipped instead of violating
+	// the foreign key.
+	query := `WITH probed AS (
+			UPDATE module_instances SET healthy = :healthy WHERE instance_id = :instance_id
+			RETURNING module_id, instance_id
+		)
+		INSERT INTO module_probes (module_id, instance_id, kind, healthy, latency_ms, error, checked_at)
+		SELECT module_id, instance_id, CAST(:kind AS text), CAST(:healthy AS boolean), CAST(:latency_ms AS bigint), CAST(:error AS text), CAST(:checked_at AS timestamptz)
+		FROM probed`
+
+	if _, err := db.DB.NamedExecContext(ctx, query, r); err != nil {
+		return fmt.Errorf("failed to store probe result: %w", err)
+	}
+
+	return nil
+}
+
+// prune removes probe results older than the retention period.
+func prune(ctx context.Context, retention time.Duration) error {
+	query := `DELETE FROM module_probes WHERE checked_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'`
+
+	if _, err := db.DB.ExecContext(ctx, query, retention.Seconds()); err != nil {
+		return fmt.Errorf("failed to prune probe results: %w", err)
+	}
+
+	return nil
+}
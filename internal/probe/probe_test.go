@@ -0,0 +1,133 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/The-OpenPlatform/backend/internal/db"
+)
+
+func testConfig(mode string) Config {
+	cfg := DefaultConfig()
+	cfg.Mode = mode
+	cfg.Timeout = 2 * time.Second
+	return cfg
+}
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	address := strings.TrimPrefix(srv.URL, "http://")
+
+	t.Run("healthy", func(t *testing.T) {
+		result := New(testConfig(ModeHTTP)).Probe(context.Background(), address)
+
+		assert.True(t, result.Healthy)
+		assert.Equal(t, ModeHTTP, result.Kind)
+		assert.Empty(t, result.Error)
+	})
+
+	t.Run("unhealthy status code", func(t *testing.T) {
+		cfg := testConfig(ModeHTTP)
+		cfg.HTTPPath = "/other"
+		result := New(cfg).Probe(context.Background(), address)
+
+		assert.False(t, result.Healthy)
+		assert.Contains(t, result.Error, "503")
+	})
+}
+
+func TestProbeGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	healthServer := health.NewServer()
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	prober := New(testConfig(ModeGRPC))
+
+	result := prober.Probe(context.Background(), lis.Addr().String())
+	assert.True(t, result.Healthy, result.Error)
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	result = prober.Probe(context.Background(), lis.Addr().String())
+	assert.False(t, result.Healthy)
+	assert.Contains(t, result.Error, "NOT_SERVING")
+}
+
+func TestProbeAll(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	host, portText, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portText)
+	require.NoError(t, err)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	previous := db.DB
+	db.DB = sqlx.NewDb(mockDB, "sqlmock")
+	t.Cleanup(func() {
+		db.DB = previous
+		mockDB.Close()
+	})
+
+	mock.ExpectQuery(`SELECT module_id, instance_id, host, port FROM module_instances`).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id", "instance_id", "host", "port"}).
+			AddRow("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000001", host, port).
+			AddRow("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000002", host, port))
+	mock.ExpectExec(`INSERT INTO module_probes`).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectExec(`INSERT INTO module_probes`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM module_probes`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	cfg := testConfig(ModeHTTP)
+	cfg.Retention = time.Hour
+
+	// A failed save must not stop the rest of the round or the pruning.
+	require.NoError(t, New(cfg).ProbeAll(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, DefaultConfig().Validate())
+
+	cfg := DefaultConfig()
+	cfg.Mode = "tcp"
+	assert.Error(t, cfg.Validate())
+
+	cfg = DefaultConfig()
+	cfg.Mode = ModeHTTP
+	cfg.HTTPPath = "healthz"
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,58 @@
+package probe
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/The-OpenPlatform/backend/internal/db"
+)
+
+// ModuleStatus combines a module's liveness state with its most recent probe result.
+// The probe fields are NULL for modules that have not been probed yet.
+type ModuleStatus struct {
+	ModuleID  string         `db:"module_id"`
+	Name      string         `db:"name"`
+	Status    string         `db:"status"`
+	Healthy   sql.NullBool   `db:"healthy"`
+	LatencyMS sql.NullInt64  `db:"latency_ms"`
+	Error     sql.NullString `db:"error"`
+	CheckedAt *time.Time     `db:"checked_at"`
+}
+
+// Latest returns every registered module together with its latest probe result.
+func Latest(ctx context.Context) ([]ModuleStatus, error) {
+	query := `SELECT m.module_id, m.name, m.status, p.healthy, p.latency_ms, p.error, p.checked_at
+		FROM modules m
+		LEFT JOIN LATERAL (
+			SELECT healthy, latency_ms, error, checked_at FROM module_probes
+			WHERE module_id = m.module_id
+			ORDER BY checked_at DESC
+			LIMIT 1
+		) p ON true
+		ORDER BY m.name`
+
+	var statuses []ModuleStatus
+	if err := db.DB.SelectContext(ctx, &statuses, query); err != nil {
+		return nil, fmt.Errorf("failed to load module probe status: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// History returns up to limit probe results for a module, newest first.
+func History(ctx context.Context, moduleID string, limit int) ([]Result, error) {
+	query := `SELECT module_id, COALESCE(instance_id::text, '') AS instance_id, kind, healthy, latency_ms, error, checked_at
+		FROM module_probes
+		WHERE module_id = $1
+		ORDER BY checked_at DESC
+		LIMIT $2`
+
+	var results []Result
+	if err := db.DB.SelectContext(ctx, &results, query, moduleID, limit); err != nil {
+		return nil, fmt.Errorf("failed to load probe history: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// auditTimeout bounds writing an audit entry, which happens after the
+// statement's own context may have run out.
+const auditTimeout = 5 * time.Second
+
+// AuditEntry records one statement run by the Runner.
+type AuditEntry struct {
+	AuditID int64  `db:"audit_id" json:"audit_id"`
+	Actor   string `db:"actor" json:"actor"`
+	// QueryName is the saved query that was run, if any.
+	QueryName  *string         `db:"query_name" json:"query_name,omitempty"`
+	SQL        string          `db:"sql" json:"query"`
+	Params     json.RawMessage `db:"params" json:"params"`
+	ReadOnly   bool            `db:"read_only" json:"read_only"`
+	StartedAt  time.Time       `db:"started_at" json:"started_at"`
+	DurationMS int64           `db:"duration_ms" json:"duration_ms"`
+	// Rows counts the rows returned by reads and affected by writes.
+	Rows    int64  `db:"rows" json:"rows"`
+	Success bool   `db:"success" json:"success"`
+	Error   string `db:"error" json:"error,omitempty"`
+}
+
+// AuditFilter selects audit entries, newest first.
+type AuditFilter struct {
+	Actor     string
+	QueryName string
+	// Before only returns entries older than this audit ID, for paging.
+	Before int64
+	Limit  int
+}
+
+type actorKey struct{}
+
+// WithActor returns a context naming the user or API key on whose behalf
+// statements are run, for the audit log.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// audit records a finished statement. Failing to do so does not fail the
+// statement, whose effects are already visible.
+func (r *Runner) audit(ctx context.Context, stmt *Statement, params []interface{}, started time.Time, rows int64, err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+
+	if params == nil {
+		params = []interface{}{}
+	}
+	encoded, jsonErr := json.Marshal(params)
+	if jsonErr != nil {
+		encoded = []byte("[]")
+	}
+
+	var name *string
+	if stmt.Name != "" {
+		name = &stmt.Name
+	}
+
+	var message string
+	if err != nil {
+		message = err.Error()
+	}
+
+	_, dbErr := r.db.ExecContext(ctx, `INSERT INTO query_audit
+		(actor, query_name, sql, params, read_only, started_at, duration_ms, rows, success, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		actorFromContext(ctx), name, stmt.SQL, string(encoded), stmt.ReadOnly,
+		started, time.Since(started).Milliseconds(), rows, err == nil, message)
+	if dbErr != nil {
+		log.Printf("failed to record query audit entry: %v", dbErr)
+	}
+}
+
+// Reject records a statement that was refused before it could run, such
+// as one outside the allowlist.
+func (r *Runner) Reject(ctx context.Context, stmt *Statement, params []interface{}, err error) {
+	r.audit(ctx, stmt, params, time.Now(), 0, err)
+}
+
+// Audit returns the audit entries matching f, newest first.
+func (s *Store) Audit(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	query := `SELECT audit_id, actor, query_name, sql, params, read_only, started_at, duration_ms, rows, success, error
+		FROM query_audit
+		WHERE ($1 = '' OR actor = $1)
+		AND ($2 = '' OR query_name = $2)
+		AND ($3 = 0 OR audit_id < $3)
+		ORDER BY audit_id DESC
+		LIMIT $4`
+
+	if err := s.db.SelectContext(ctx, &entries, query, f.Actor, f.QueryName, f.Before, f.Limit); err != nil {
+		return nil, fmt.Errorf("failed to list query audit entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,229 @@
+// Package query runs ad-hoc SQL statements submitted by administrators.
+//
+// Statements are parsed with the PostgreSQL parser and checked against an
+// allowlist before they reach the database: a single SELECT, VALUES,
+// INSERT, UPDATE or DELETE that only calls well-known built-in functions
+// and touches neither the system catalogs nor the tables holding
+// credentials and the audit log. Read-only statements run in a
+// READ ONLY transaction that is always rolled back, and their rows are
+// streamed to the client as they arrive, up to a row and a byte limit.
+// Every statement runs with a statement timeout.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	// ErrSyntax is returned for statements the parser rejects.
+	ErrSyntax = errors.New("invalid SQL")
+	// ErrNotAllowed is returned for statements outside the allowlist.
+	ErrNotAllowed = errors.New("statement not allowed")
+)
+
+// Statement is a checked SQL statement.
+type Statement struct {
+	SQL string
+	// ReadOnly is true for SELECT and VALUES queries, including WITH queries
+	// whose common table expressions only read.
+	ReadOnly bool
+	// Params is the number of $n placeholders the statement expects.
+	Params int
+	// Name is the saved query the statement was loaded from, if any.
+	Name string
+}
+
+// allowedFunctions are the built-in functions statements may call. They
+// neither modify data nor reach outside the database, and unlike
+// set_config or query_to_xml cannot escape the checks of this package.
+var allowedFunctions = map[string]bool{
+	// aggregates
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+	"array_agg": true, "string_agg": true, "json_agg": true, "jsonb_agg": true,
+	"json_object_agg": true, "jsonb_object_agg": true, "bool_and": true, "bool_or": true,
+	"every": true, "percentile_cont": true, "percentile_disc": true, "mode": true,
+	"stddev": true, "variance": true,
+	// window functions
+	"row_number": true, "rank": true, "dense_rank": true, "percent_rank": true,
+	"cume_dist": true, "ntile": true, "lag": true, "lead": true,
+	"first_value": true, "last_value": true, "nth_value": true,
+	// strings
+	"lower": true, "upper": true, "length": true, "char_length": true, "octet_length": true,
+	"substring": true, "substr": true, "trim": true, "btrim": true, "ltrim": true, "rtrim": true,
+	"replace": true, "concat": true, "concat_ws": true, "left": true, "right": true,
+	"split_part": true, "position": true, "strpos": true, "starts_with": true,
+	"lpad": true, "rpad": true, "initcap": true, "reverse": true, "format": true,
+	"regexp_replace": true, "regexp_match": true, "regexp_matches": true,
+	"md5": true, "encode": true, "decode": true,
+	// numbers
+	"abs": true, "round": true, "ceil": true, "ceiling": true, "floor": true, "trunc": true,
+	"mod": true, "power": true, "sqrt": true, "sign": true,
+	// dates and times
+	"now": true, "date_trunc": true, "date_part": true, "extract": true, "age": true,
+	"to_char": true, "to_timestamp": true, "to_date": true, "to_number": true,
+	"make_interval": true, "timezone": true, "date_bin": true,
+	// JSON and arrays
+	"to_json": true, "to_jsonb": true, "json_build_object": true, "jsonb_build_object": true,
+	"json_build_array": true, "jsonb_build_array": true, "jsonb_array_length": true,
+	"json_array_length": true, "jsonb_typeof": true, "json_typeof": true,
+	"jsonb_extract_path_text": true, "json_extract_path_text": true,
+	"jsonb_array_elements": true, "jsonb_each": true, "jsonb_object_keys": true,
+	"array_length": true, "array_position": true, "array_to_string": true,
+	"cardinality": true, "unnest": true,
+	// miscellaneous
+	"gen_random_uuid": true, "generate_series": true,
+}
+
+// protectedTables hold password and secret hashes, certificates, the audit
+// log and the schema version. Statements may neither read nor change them.
+var protectedTables = map[string]bool{
+	"users":               true,
+	"user_sessions":       true,
+	"api_keys":            true,
+	"join_tokens":         true,
+	"module_credentials":  true,
+	"module_certificates": true,
+	"query_audit":         true,
+	"schema_version":      true,
+}
+
+// Parse parses sql and checks it against the allowlist.
+func Parse(sql string) (*Statement, error) {
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+
+	if len(tree.Stmts) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one statement, got %d", ErrNotAllowed, len(tree.Stmts))
+	}
+
+	node := tree.Stmts[0].Stmt
+	stmt := &Statement{SQL: sql}
+	switch {
+	case node.GetSelectStmt() != nil:
+		stmt.ReadOnly = true
+	case node.GetInsertStmt() != nil:
+	case node.GetUpdateStmt() != nil:
+		if node.GetUpdateStmt().WhereClause == nil {
+			return nil, fmt.Errorf("%w: UPDATE requires a WHERE clause", ErrNotAllowed)
+		}
+	case node.GetDeleteStmt() != nil:
+		if node.GetDeleteStmt().WhereClause == nil {
+			return nil, fmt.Errorf("%w: DELETE requires a WHERE clause", ErrNotAllowed)
+		}
+	default:
+		return nil, fmt.Errorf("%w: only SELECT, VALUES, INSERT, UPDATE and DELETE are supported", ErrNotAllowed)
+	}
+
+	top := topLevel(node)
+	err = walk(top.ProtoReflect(), func(m proto.Message) error {
+		switch n := m.(type) {
+		case *pg_query.InsertStmt, *pg_query.UpdateStmt, *pg_query.DeleteStmt:
+			if m != top {
+				return fmt.Errorf("%w: data-modifying WITH queries are not supported", ErrNotAllowed)
+			}
+		case *pg_query.SelectStmt:
+			if n.IntoClause != nil {
+				return fmt.Errorf("%w: SELECT INTO is not supported", ErrNotAllowed)
+			}
+			if len(n.LockingClause) > 0 {
+				return fmt.Errorf("%w: locking clauses are not supported", ErrNotAllowed)
+			}
+		case *pg_query.RangeVar:
+			if isSystemRelation(n) {
+				return fmt.Errorf("%w: system catalog %s cannot be queried", ErrNotAllowed, n.Relname)
+			}
+			if protectedTables[strings.ToLower(n.Relname)] {
+				return fmt.Errorf("%w: table %s cannot be queried", ErrNotAllowed, n.Relname)
+			}
+		case *pg_query.FuncCall:
+			if name, ok := functionName(n); !ok || !allowedFunctions[name] {
+				return fmt.Errorf("%w: function %s is not allowed", ErrNotAllowed, qualifiedName(n))
+			}
+		case *pg_query.ParamRef:
+			stmt.Params = max(stmt.Params, int(n.Number))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// topLevel returns the statement wrapped by node.
+func topLevel(node *pg_query.Node) proto.Message {
+	switch {
+	case node.GetSelectStmt() != nil:
+		return node.GetSelectStmt()
+	case node.GetInsertStmt() != nil:
+		return node.GetInsertStmt()
+	case node.GetUpdateStmt() != nil:
+		return node.GetUpdateStmt()
+	default:
+		return node.GetDeleteStmt()
+	}
+}
+
+// walk calls visit for m and every message nested in it.
+func walk(m protoreflect.Message, visit func(proto.Message) error) error {
+	if err := visit(m.Interface()); err != nil {
+		return err
+	}
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() == nil || fd.IsMap():
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && err == nil; i++ {
+				err = walk(list.Get(i).Message(), visit)
+			}
+		default:
+			err = walk(v.Message(), visit)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// functionName returns the unqualified name of a call to a built-in
+// function. Functions in schemas other than pg_catalog are not built in.
+func functionName(call *pg_query.FuncCall) (string, bool) {
+	parts := nameParts(call)
+	switch {
+	case len(parts) == 1:
+		return parts[0], true
+	case len(parts) == 2 && parts[0] == "pg_catalog":
+		return parts[1], true
+	default:
+		return "", false
+	}
+}
+
+func qualifiedName(call *pg_query.FuncCall) string {
+	return strings.Join(nameParts(call), ".")
+}
+
+func nameParts(call *pg_query.FuncCall) []string {
+	parts := make([]string, 0, len(call.Funcname))
+	for _, n := range call.Funcname {
+		parts = append(parts, n.GetString_().GetSval())
+	}
+	return parts
+}
+
+// isSystemRelation reports whether a table reference names a system
+// catalog or view.
+func isSystemRelation(rv *pg_query.RangeVar) bool {
+	return strings.HasPrefix(rv.Schemaname, "pg_") || strings.HasPrefix(rv.Relname, "pg_")
+}
@@ -0,0 +1,72 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllows(t *testing.T) {
+	for _, tt := range []struct {
+		sql      string
+		readOnly bool
+		params   int
+	}{
+		{"SELECT module_id, created_at FROM modules", true, 0},
+		{"select name from modules where status = $1 and port > $2", true, 2},
+		{"WITH recent AS (SELECT * FROM modules WHERE created_at > now() - interval '1 day') SELECT count(*) FROM recent", true, 0},
+		{"SELECT date_trunc('hour', checked_at), count(*) FROM module_probes GROUP BY 1", true, 0},
+		{"SELECT extract(epoch FROM created_at) FROM modules", true, 0},
+		{"VALUES (1), (2)", true, 0},
+		{"INSERT INTO modules (name, ip_port) VALUES ($1, $2) RETURNING module_id", false, 2},
+		{"UPDATE modules SET status = 'offline' WHERE module_id = $1", false, 1},
+		{"DELETE FROM module_images WHERE module_id = $1", false, 1},
+	} {
+		stmt, err := Parse(tt.sql)
+		require.NoError(t, err, tt.sql)
+		assert.Equal(t, tt.readOnly, stmt.ReadOnly, tt.sql)
+		assert.Equal(t, tt.params, stmt.Params, tt.sql)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, sql := range []string{
+		"DROP TABLE modules",
+		"TRUNCATE modules",
+		"CREATE TABLE x (id int)",
+		"ALTER TABLE modules ADD COLUMN x int",
+		"GRANT ALL ON modules TO public",
+		"SET statement_timeout = 0",
+		"COPY modules TO '/tmp/out'",
+		"DO $$ BEGIN END $$",
+		"SELECT 1; DROP TABLE modules",
+		"DELETE FROM modules",
+		"UPDATE modules SET status = 'offline'",
+		"SELECT * INTO backup FROM modules",
+		"SELECT * FROM modules FOR UPDATE",
+		"WITH gone AS (DELETE FROM modules WHERE true RETURNING *) SELECT * FROM gone",
+		"SELECT pg_sleep(10)",
+		"SELECT set_config('statement_timeout', '0', true)",
+		"SELECT query_to_xml('DROP TABLE modules', true, true, '')",
+		"SELECT * FROM pg_catalog.pg_authid",
+		"SELECT * FROM pg_stat_activity",
+		"SELECT public.my_function()",
+		"SELECT username, password_hash FROM users",
+		"SELECT key_hash FROM public.api_keys",
+		"SELECT m.name FROM modules m JOIN module_credentials c USING (module_id)",
+		"SELECT * FROM modules WHERE name IN (SELECT module_name FROM join_tokens)",
+		"UPDATE user_sessions SET expires_at = now() WHERE true",
+		"SELECT version FROM schema_version",
+		// The audit log cannot cover its own tracks
+		"DELETE FROM query_audit WHERE true",
+		"UPDATE query_audit SET success = true WHERE actor = 'alice'",
+		"WITH gone AS (DELETE FROM query_audit RETURNING audit_id) SELECT count(*) FROM gone",
+	} {
+		_, err := Parse(sql)
+		assert.ErrorIs(t, err, ErrNotAllowed, sql)
+	}
+
+	_, err := Parse("SELEC 1")
+	assert.ErrorIs(t, err, ErrSyntax)
+}
@@ -0,0 +1,249 @@
+package query
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column describes a result column.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the PostgreSQL type name, such as int4 or timestamptz.
+	Type string `json:"type"`
+}
+
+// Summary describes a result set once all of it has been written.
+type Summary struct {
+	Rows int
+	// Truncated is set when the row or byte limit cut the result short.
+	Truncated bool
+	// Err is set when the statement failed after rows were written.
+	Err error
+}
+
+// Encoder writes a result set in one format. Rows are encoded into a
+// buffer first so that the byte limit can be checked before they are
+// written.
+type Encoder interface {
+	Begin(w io.Writer, cols []Column) error
+	Row(buf *bytes.Buffer, values []interface{}) error
+	End(w io.Writer, s Summary) error
+}
+
+// Format is an output format, identified by its media type.
+type Format string
+
+const (
+	// FormatJSON is a single JSON object holding the columns with their
+	// types and the rows as objects whose keys follow the column order.
+	FormatJSON Format = "application/json"
+	// FormatNDJSON writes one JSON object per row.
+	FormatNDJSON Format = "application/x-ndjson"
+	// FormatCSV writes a header line with the column names and one line per row.
+	FormatCSV Format = "text/csv"
+)
+
+// NegotiateFormat picks the format for an Accept header. An empty header
+// selects JSON. Types are tried by descending q-value and, among equal
+// ones, in the order given. It reports false if none of the accepted types
+// is supported.
+func NegotiateFormat(accept string) (Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, true
+	}
+
+	type candidate struct {
+		format Format
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			candidates = append(candidates, candidate{FormatJSON, q})
+		case "application/x-ndjson", "application/ndjson":
+			candidates = append(candidates, candidate{FormatNDJSON, q})
+		case "text/csv", "text/*":
+			candidates = append(candidates, candidate{FormatCSV, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].format, true
+}
+
+// NewEncoder returns an encoder for f.
+func (f Format) NewEncoder() Encoder {
+	switch f {
+	case FormatNDJSON:
+		return &ndjsonEncoder{}
+	case FormatCSV:
+		return &csvEncoder{}
+	default:
+		return &jsonEncoder{}
+	}
+}
+
+// jsonEncoder writes {"columns":[...],"data":[...],"rows":n,...}. The
+// outcome comes last because it is only known once the rows are written.
+type jsonEncoder struct {
+	cols []Column
+	rows int
+}
+
+func (e *jsonEncoder) Begin(w io.Writer, cols []Column) error {
+	e.cols = cols
+	header, err := json.Marshal(cols)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, `{"columns":%s,"data":[`, header)
+	return err
+}
+
+func (e *jsonEncoder) Row(buf *bytes.Buffer, values []interface{}) error {
+	if e.rows > 0 {
+		buf.WriteByte(',')
+	}
+	e.rows++
+	return writeObject(buf, e.cols, values)
+}
+
+func (e *jsonEncoder) End(w io.Writer, s Summary) error {
+	footer := struct {
+		Rows      int    `json:"rows"`
+		Truncated bool   `json:"truncated"`
+		Success   bool   `json:"success"`
+		Error     string `json:"error,omitempty"`
+	}{Rows: s.Rows, Truncated: s.Truncated, Success: s.Err == nil}
+	if s.Err != nil {
+		footer.Error = s.Err.Error()
+	}
+
+	b, err := json.Marshal(footer)
+	if err != nil {
+		return err
+	}
+
+	// Splice the footer's fields into the open object
+	_, err = fmt.Fprintf(w, "],%s\n", b[1:])
+	return err
+}
+
+// ndjsonEncoder writes one object per line.
+type ndjsonEncoder struct {
+	cols []Column
+}
+
+func (e *ndjsonEncoder) Begin(_ io.Writer, cols []Column) error {
+	e.cols = cols
+	return nil
+}
+
+func (e *ndjsonEncoder) Row(buf *bytes.Buffer, values []interface{}) error {
+	if err := writeObject(buf, e.cols, values); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func (e *ndjsonEncoder) End(io.Writer, Summary) error {
+	return nil
+}
+
+// csvEncoder writes RFC 4180 CSV. NULL becomes an empty field.
+type csvEncoder struct {
+	record []string
+}
+
+func (e *csvEncoder) Begin(w io.Writer, cols []Column) error {
+	e.record = make([]string, len(cols))
+	for i, col := range cols {
+		e.record[i] = col.Name
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(e.record)
+	cw.Flush()
+	return cw.Error()
+}
+
+func (e *csvEncoder) Row(buf *bytes.Buffer, values []interface{}) error {
+	for i, v := range values {
+		e.record[i] = csvField(v)
+	}
+
+	cw := csv.NewWriter(buf)
+	cw.Write(e.record)
+	cw.Flush()
+	return cw.Error()
+}
+
+func (e *csvEncoder) End(io.Writer, Summary) error {
+	return nil
+}
+
+func csvField(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeObject encodes a row as a JSON object with its keys in column order.
+func writeObject(buf *bytes.Buffer, cols []Column, values []interface{}) error {
+	buf.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(cols[i].Name)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
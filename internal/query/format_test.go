@@ -0,0 +1,74 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	for accept, want := range map[string]Format{
+		"":                                   FormatJSON,
+		"*/*":                                FormatJSON,
+		"application/json":                   FormatJSON,
+		"application/x-ndjson":               FormatNDJSON,
+		"application/ndjson":                 FormatNDJSON,
+		"text/csv; charset=utf-8":            FormatCSV,
+		"text/html, text/*;q=0.5":            FormatCSV,
+		"text/csv;q=0, application/x-ndjson": FormatNDJSON,
+		"text/csv;q=0.5, application/json":   FormatJSON,
+		"*/*;q=0.1, text/csv;q=0.9":          FormatCSV,
+		"text/csv, application/x-ndjson":     FormatCSV,
+	} {
+		got, ok := NegotiateFormat(accept)
+		assert.True(t, ok, accept)
+		assert.Equal(t, want, got, accept)
+	}
+
+	_, ok := NegotiateFormat("application/xml")
+	assert.False(t, ok)
+}
+
+func encode(t *testing.T, f Format, cols []Column, rows [][]interface{}, s Summary) string {
+	t.Helper()
+
+	var out, buf bytes.Buffer
+	enc := f.NewEncoder()
+	require.NoError(t, enc.Begin(&out, cols))
+	for _, row := range rows {
+		buf.Reset()
+		require.NoError(t, enc.Row(&buf, row))
+		out.Write(buf.Bytes())
+	}
+	require.NoError(t, enc.End(&out, s))
+	return out.String()
+}
+
+func TestEncoders(t *testing.T) {
+	// Column order must survive, so the names are deliberately not sorted
+	cols := []Column{{Name: "zeta", Type: "text"}, {Name: "alpha", Type: "int4"}, {Name: "at", Type: "timestamptz"}}
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := [][]interface{}{{"a,b", int64(1), at}, {nil, int64(2), nil}}
+	summary := Summary{Rows: 2}
+
+	assert.Equal(t,
+		`{"columns":[{"name":"zeta","type":"text"},{"name":"alpha","type":"int4"},{"name":"at","type":"timestamptz"}],`+
+			`"data":[{"zeta":"a,b","alpha":1,"at":"2025-01-02T03:04:05Z"},{"zeta":null,"alpha":2,"at":null}],`+
+			`"rows":2,"truncated":false,"success":true}`+"\n",
+		encode(t, FormatJSON, cols, rows, summary))
+
+	assert.Equal(t,
+		`{"zeta":"a,b","alpha":1,"at":"2025-01-02T03:04:05Z"}`+"\n"+`{"zeta":null,"alpha":2,"at":null}`+"\n",
+		encode(t, FormatNDJSON, cols, rows, summary))
+
+	assert.Equal(t,
+		"zeta,alpha,at\n\"a,b\",1,2025-01-02T03:04:05Z\n,2,\n",
+		encode(t, FormatCSV, cols, rows, summary))
+
+	assert.Equal(t,
+		`{"columns":[],"data":[],"rows":0,"truncated":true,"success":true}`+"\n",
+		encode(t, FormatJSON, []Column{}, nil, Summary{Truncated: true}))
+}
@@ -0,0 +1,260 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrParams is returned when the parameters do not match the placeholders.
+	ErrParams = errors.New("wrong number of parameters")
+	// ErrTimeout is returned when a statement runs out of time.
+	ErrTimeout = errors.New("query timed out")
+	// ErrFailed is returned when the database rejects a statement.
+	ErrFailed = errors.New("query execution failed")
+)
+
+// queryCanceled is the SQLSTATE of statements stopped by statement_timeout
+// or a cancel request.
+const queryCanceled = "57014"
+
+// Limits bound the resources a statement may use.
+type Limits struct {
+	Timeout time.Duration
+	// MaxRows and MaxBytes cap the rows and the encoded bytes of a result.
+	MaxRows  int
+	MaxBytes int
+}
+
+// Runner executes checked statements.
+type Runner struct {
+	db     *sqlx.DB
+	limits Limits
+}
+
+// NewRunner creates a runner that enforces limits on every statement.
+func NewRunner(db *sqlx.DB, limits Limits) *Runner {
+	return &Runner{db: db, limits: limits}
+}
+
+// Exec runs a statement that modifies data and returns the number of
+// affected rows. Objects and arrays among params are passed as JSON text.
+// Like Stream, it records the statement in the audit log.
+func (r *Runner) Exec(ctx context.Context, stmt *Statement, params []interface{}) (affected int64, err error) {
+	defer func(started time.Time) {
+		r.audit(ctx, stmt, params, started, affected, err)
+	}(time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, r.limits.Timeout)
+	defer cancel()
+
+	tx, args, err := r.begin(ctx, stmt, params)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, stmt.SQL, args...)
+	if err != nil {
+		return 0, classify(ctx, err)
+	}
+
+	affected, _ = result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return 0, classify(ctx, err)
+	}
+
+	return affected, nil
+}
+
+// Stream runs a read-only statement and writes its rows to w with enc as
+// they arrive. start is called with the columns before anything is
+// written, so the caller can still set headers; failures before that are
+// returned without writing anything. Later failures end the result early
+// and are reported in the summary.
+func (r *Runner) Stream(ctx context.Context, stmt *Statement, params []interface{}, w io.Writer, enc Encoder, start func([]Column)) (summary Summary, err error) {
+	defer func(started time.Time) {
+		failure := err
+		if failure == nil {
+			failure = summary.Err
+		}
+		r.audit(ctx, stmt, params, started, int64(summary.Rows), failure)
+	}(time.Now())
+
+	if !stmt.ReadOnly {
+		return Summary{}, fmt.Errorf("%w: statement modifies data", ErrNotAllowed)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.limits.Timeout)
+	defer cancel()
+
+	tx, args, err := r.begin(ctx, stmt, params)
+	if err != nil {
+		return Summary{}, err
+	}
+	// Reads are never committed
+	defer tx.Rollback()
+
+	// Closing the rows reads whatever the statement still returns, so the
+	// query gets its own context to stop it once the result is cut short
+	query, stop := context.WithCancel(ctx)
+	defer stop()
+
+	rows, err := tx.QueryxContext(query, stmt.SQL, args...)
+	if err != nil {
+		return Summary{}, classify(ctx, err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return Summary{}, classify(ctx, err)
+	}
+
+	cols := make([]Column, len(types))
+	for i, t := range types {
+		cols[i] = Column{Name: t.Name(), Type: strings.ToLower(t.DatabaseTypeName())}
+	}
+
+	start(cols)
+	out := &countingWriter{w: w}
+	if err := enc.Begin(out, cols); err != nil {
+		return Summary{}, err
+	}
+
+	summary = r.writeRows(ctx, rows, out, enc)
+	if summary.Truncated {
+		stop()
+	}
+	return summary, enc.End(out, summary)
+}
+
+// writeRows encodes rows until they run out or a limit is reached.
+func (r *Runner) writeRows(ctx context.Context, rows *sqlx.Rows, out *countingWriter, enc Encoder) Summary {
+	var s Summary
+	var buf bytes.Buffer
+	for rows.Next() {
+		if s.Rows == r.limits.MaxRows {
+			s.Truncated = true
+			return s
+		}
+
+		values, err := rows.SliceScan()
+		if err != nil {
+			s.Err = classify(ctx, err)
+			return s
+		}
+
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+
+		buf.Reset()
+		if err := enc.Row(&buf, values); err != nil {
+			s.Err = err
+			return s
+		}
+
+		if out.n+buf.Len() > r.limits.MaxBytes {
+			s.Truncated = true
+			return s
+		}
+
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			s.Err = err
+			return s
+		}
+		s.Rows++
+	}
+
+	if err := rows.Err(); err != nil {
+		s.Err = classify(ctx, err)
+	}
+	return s
+}
+
+// begin opens the transaction of a statement, read-only where possible, and
+// binds its parameters.
+func (r *Runner) begin(ctx context.Context, stmt *Statement, params []interface{}) (*sqlx.Tx, []interface{}, error) {
+	if len(params) != stmt.Params {
+		return nil, nil, fmt.Errorf("%w: statement expects %d, got %d", ErrParams, stmt.Params, len(params))
+	}
+
+	args, err := bindParams(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: stmt.ReadOnly})
+	if err != nil {
+		return nil, nil, classify(ctx, fmt.Errorf("failed to begin transaction: %w", err))
+	}
+
+	// The server enforces the timeout too, in case the cancel request is lost
+	timeout := strconv.FormatInt(r.limits.Timeout.Milliseconds(), 10)
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`, timeout); err != nil {
+		tx.Rollback()
+		return nil, nil, classify(ctx, fmt.Errorf("failed to set statement timeout: %w", err))
+	}
+
+	return tx, args, nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// bindParams converts decoded JSON values to query arguments.
+func bindParams(params []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, len(params))
+	for i, p := range params {
+		switch v := p.(type) {
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter $%d: %v", ErrParams, i+1, err)
+			}
+			args[i] = string(b)
+		case json.Number:
+			args[i] = v.String()
+		default:
+			args[i] = v
+		}
+	}
+	return args, nil
+}
+
+// classify maps database errors to the errors of this package.
+func classify(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &pqErr) && pqErr.Code == queryCanceled) {
+		return ErrTimeout
+	}
+
+	if pqErr != nil {
+		return fmt.Errorf("%w: %s", ErrFailed, pqErr.Message)
+	}
+
+	return err
+}
@@ -0,0 +1,232 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRunner(t *testing.T, limits Limits) (*Runner, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	limits.Timeout = 5 * time.Second
+	return NewRunner(sqlx.NewDb(mockDB, "sqlmock"), limits), mock
+}
+
+// expectAudit expects the audit entry written after every statement.
+func expectAudit(mock sqlmock.Sqlmock, rows int64, success bool) {
+	mock.ExpectExec(`INSERT INTO query_audit`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), rows, success, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestStream(t *testing.T) {
+	runner, mock := newTestRunner(t, Limits{MaxRows: 2, MaxBytes: 1 << 20})
+	stmt, err := Parse("SELECT name, port FROM modules WHERE port > $1")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('statement_timeout', \$1, true\)`).WithArgs("5000").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name, port FROM modules`).WithArgs("8000").
+		WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+			sqlmock.NewColumn("name").OfType("TEXT", ""),
+			sqlmock.NewColumn("port").OfType("INT4", 0),
+		).AddRow("a", 8080).AddRow("b", 8081).AddRow("c", 8082))
+	mock.ExpectRollback()
+	mock.ExpectExec(`INSERT INTO query_audit`).
+		WithArgs("alice", nil, stmt.SQL, `[8000]`, true, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(2), true, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	var out bytes.Buffer
+	var cols []Column
+	ctx := WithActor(context.Background(), "alice")
+	summary, err := runner.Stream(ctx, stmt, []interface{}{json.Number("8000")}, &out, FormatNDJSON.NewEncoder(),
+		func(c []Column) { cols = c })
+	require.NoError(t, err)
+	assert.Equal(t, []Column{{Name: "name", Type: "text"}, {Name: "port", Type: "int4"}}, cols)
+	assert.Equal(t, Summary{Rows: 2, Truncated: true}, summary)
+	assert.Equal(t, "{\"name\":\"a\",\"port\":8080}\n{\"name\":\"b\",\"port\":8081}\n", out.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamByteLimit(t *testing.T) {
+	runner, mock := newTestRunner(t, Limits{MaxRows: 100, MaxBytes: 30})
+	stmt, err := Parse("SELECT name FROM modules")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name FROM modules`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("first").AddRow("second").AddRow("third"))
+	mock.ExpectRollback()
+	expectAudit(mock, 1, true)
+
+	var out bytes.Buffer
+	summary, err := runner.Stream(context.Background(), stmt, nil, &out, FormatNDJSON.NewEncoder(), func([]Column) {})
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Rows: 1, Truncated: true}, summary)
+	// Rows are never cut in half
+	assert.Equal(t, "{\"name\":\"first\"}\n", out.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamCancelsTruncatedQuery(t *testing.T) {
+	conn := &endlessConnector{}
+	runner := NewRunner(sqlx.NewDb(sql.OpenDB(conn), "postgres"), Limits{Timeout: 5 * time.Second, MaxRows: 3, MaxBytes: 1 << 20})
+	stmt, err := Parse("SELECT n FROM generate_series(1, 1000000000) AS n")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	summary, err := runner.Stream(context.Background(), stmt, nil, &out, FormatNDJSON.NewEncoder(), func([]Column) {})
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Rows: 3, Truncated: true}, summary)
+	assert.True(t, conn.cancelled.Load(), "the query was still running when its rows were closed")
+}
+
+// endlessConnector is a database whose queries never run out of rows. Like
+// lib/pq, closing the rows would read them to the end, so it records
+// whether the query was cancelled by then instead.
+type endlessConnector struct {
+	cancelled atomic.Bool
+}
+
+func (c *endlessConnector) Connect(context.Context) (driver.Conn, error) { return endlessConn{c}, nil }
+func (c *endlessConnector) Driver() driver.Driver                        { return nil }
+
+type endlessConn struct{ c *endlessConnector }
+
+func (endlessConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (endlessConn) Close() error                        { return nil }
+func (endlessConn) Begin() (driver.Tx, error)           { return endlessTx{}, nil }
+
+func (endlessConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return endlessTx{}, nil
+}
+
+func (endlessConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (c endlessConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	return &endlessRows{ctx: ctx, c: c.c}, nil
+}
+
+type endlessTx struct{}
+
+func (endlessTx) Commit() error   { return nil }
+func (endlessTx) Rollback() error { return nil }
+
+type endlessRows struct {
+	ctx context.Context
+	c   *endlessConnector
+	n   int64
+}
+
+func (r *endlessRows) Columns() []string { return []string{"n"} }
+
+func (r *endlessRows) Close() error {
+	r.c.cancelled.Store(r.ctx.Err() != nil)
+	return nil
+}
+
+func (r *endlessRows) Next(dest []driver.Value) error {
+	r.n++
+	dest[0] = r.n
+	return nil
+}
+
+func TestStreamLateError(t *testing.T) {
+	runner, mock := newTestRunner(t, Limits{MaxRows: 100, MaxBytes: 1 << 20})
+	stmt, err := Parse("SELECT name FROM modules")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name FROM modules`).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("a").AddRow("b").
+			RowError(1, &pq.Error{Code: "22012", Message: "division by zero"}))
+	mock.ExpectRollback()
+	expectAudit(mock, 1, false)
+
+	var out bytes.Buffer
+	summary, err := runner.Stream(context.Background(), stmt, nil, &out, FormatJSON.NewEncoder(), func([]Column) {})
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Rows)
+	assert.ErrorIs(t, summary.Err, ErrFailed)
+	assert.JSONEq(t, `{"columns":[{"name":"name","type":""}],"data":[{"name":"a"}],"rows":1,"truncated":false,"success":false,"error":"query execution failed: division by zero"}`, out.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExec(t *testing.T) {
+	runner, mock := newTestRunner(t, Limits{MaxRows: 10, MaxBytes: 1 << 20})
+	stmt, err := Parse("UPDATE modules SET tags = $1 WHERE module_id = $2")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE modules`).WithArgs(`["a","b"]`, "m1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectAudit(mock, 1, true)
+
+	affected, err := runner.Exec(context.Background(), stmt, []interface{}{[]interface{}{"a", "b"}, "m1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamErrors(t *testing.T) {
+	runner, mock := newTestRunner(t, Limits{MaxRows: 10, MaxBytes: 1 << 20})
+	stmt, err := Parse("SELECT * FROM modules WHERE name = $1")
+	require.NoError(t, err)
+
+	stream := func(params ...interface{}) error {
+		_, err := runner.Stream(context.Background(), stmt, params, &strings.Builder{}, FormatJSON.NewEncoder(), func([]Column) {
+			t.Error("start called for a failed statement")
+		})
+		return err
+	}
+
+	expectAudit(mock, 0, false)
+	assert.ErrorIs(t, stream(), ErrParams)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT`).WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+	mock.ExpectRollback()
+	expectAudit(mock, 0, false)
+	assert.ErrorIs(t, stream("a"), ErrTimeout)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`set_config`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT`).WillReturnError(&pq.Error{Code: "42703", Message: `column "nam" does not exist`})
+	mock.ExpectRollback()
+	expectAudit(mock, 0, false)
+	err = stream("a")
+	assert.ErrorIs(t, err, ErrFailed)
+	assert.ErrorContains(t, err, `column "nam" does not exist`)
+
+	write, err := Parse("DELETE FROM modules WHERE name = 'x'")
+	require.NoError(t, err)
+	expectAudit(mock, 0, false)
+	_, err = runner.Stream(context.Background(), write, nil, &strings.Builder{}, FormatJSON.NewEncoder(), func([]Column) {})
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrValidation is returned for invalid saved query names or parameters.
+	ErrValidation = errors.New("validation failed")
+	// ErrNotFound is returned when a saved query does not exist.
+	ErrNotFound = errors.New("saved query not found")
+	// ErrConflict is returned when a saved query name is already taken.
+	ErrConflict = errors.New("saved query already exists")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Saved is a named statement with default values for its parameters.
+type Saved struct {
+	QueryID     string `db:"query_id" json:"query_id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	SQL         string `db:"sql" json:"query"`
+	// Params is a JSON array with the defaults of $1, $2, ...
+	Params    json.RawMessage `db:"params" json:"params"`
+	CreatedBy string          `db:"created_by" json:"created_by"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Statement checks the saved SQL again, in case the allowlist has become
+// stricter since the query was saved.
+func (s *Saved) Statement() (*Statement, error) {
+	stmt, err := Parse(s.SQL)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = s.Name
+	return stmt, nil
+}
+
+// Defaults returns the default parameters.
+func (s *Saved) Defaults() ([]interface{}, error) {
+	var params []interface{}
+	dec := json.NewDecoder(bytes.NewReader(s.Params))
+	dec.UseNumber()
+	if err := dec.Decode(&params); err != nil {
+		return nil, fmt.Errorf("invalid defaults of saved query %s: %w", s.Name, err)
+	}
+	return params, nil
+}
+
+// SaveInput describes a new saved query.
+type SaveInput struct {
+	Name        string
+	Description string
+	SQL         string
+	// Params are the defaults used when a run passes no parameters. Either
+	// none or one for every placeholder must be given.
+	Params []interface{}
+	// CreatedBy names the user who saved the query.
+	CreatedBy string
+}
+
+const savedColumns = `query_id, name, description, sql, params, created_by, created_at, updated_at`
+
+// Store persists saved queries and reads the audit log.
+type Store struct {
+	db *sqlx.DB
+}
+
+// NewStore creates a store backed by the given database.
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create checks and saves a statement.
+func (s *Store) Create(ctx context.Context, in SaveInput) (*Saved, error) {
+	if !namePattern.MatchString(in.Name) {
+		return nil, fmt.Errorf("%w: name must be 1-64 lowercase letters, digits, '_' or '-'", ErrValidation)
+	}
+
+	stmt, err := Parse(in.SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(in.Params) != 0 && len(in.Params) != stmt.Params {
+		return nil, fmt.Errorf("%w: statement expects %d default parameters, got %d", ErrValidation, stmt.Params, len(in.Params))
+	}
+
+	params := in.Params
+	if params == nil {
+		params = []interface{}{}
+	}
+	defaults, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid parameters: %v", ErrValidation, err)
+	}
+
+	var saved Saved
+	query := `INSERT INTO saved_queries (name, description, sql, params, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + savedColumns
+
+	err = s.db.GetContext(ctx, &saved, query, in.Name, in.Description, in.SQL, string(defaults), in.CreatedBy)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save query: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// List returns all saved queries ordered by name.
+func (s *Store) List(ctx context.Context) ([]Saved, error) {
+	saved := []Saved{}
+	query := `SELECT ` + savedColumns + ` FROM saved_queries ORDER BY name`
+
+	if err := s.db.SelectContext(ctx, &saved, query); err != nil {
+		return nil, fmt.Errorf("failed to list saved queries: %w", err)
+	}
+
+	return saved, nil
+}
+
+// Get returns the saved query with the given name.
+func (s *Store) Get(ctx context.Context, name string) (*Saved, error) {
+	var saved Saved
+	query := `SELECT ` + savedColumns + ` FROM saved_queries WHERE name = $1`
+
+	err := s.db.GetContext(ctx, &saved, query, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved query: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// Delete removes a saved query. Its audit entries are kept.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM saved_queries WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved query: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewStore(sqlx.NewDb(mockDB, "sqlmock")), mock
+}
+
+var savedColumnNames = []string{"query_id", "name", "description", "sql", "params", "created_by", "created_at", "updated_at"}
+
+func TestSave(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	for name, tt := range map[string]struct {
+		in   SaveInput
+		want error
+	}{
+		"bad name":       {SaveInput{Name: "Stale Modules", SQL: "SELECT 1"}, ErrValidation},
+		"wrong defaults": {SaveInput{Name: "stale", SQL: "SELECT * FROM modules WHERE port = $1", Params: []interface{}{1, 2}}, ErrValidation},
+		"not allowed":    {SaveInput{Name: "stale", SQL: "DROP TABLE modules"}, ErrNotAllowed},
+		"syntax error":   {SaveInput{Name: "stale", SQL: "SELEC 1"}, ErrSyntax},
+	} {
+		_, err := store.Create(ctx, tt.in)
+		assert.ErrorIs(t, err, tt.want, name)
+	}
+
+	now := time.Now()
+	sql := "SELECT name FROM modules WHERE last_seen < now() - $1::interval"
+	mock.ExpectQuery(`INSERT INTO saved_queries`).
+		WithArgs("stale", "", sql, `["5m"]`, "alice").
+		WillReturnRows(sqlmock.NewRows(savedColumnNames).AddRow("q1", "stale", "", sql, []byte(`["5m"]`), "alice", now, now))
+
+	saved, err := store.Create(ctx, SaveInput{Name: "stale", SQL: sql, Params: []interface{}{"5m"}, CreatedBy: "alice"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `["5m"]`, string(saved.Params))
+
+	defaults, err := saved.Defaults()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"5m"}, defaults)
+
+	stmt, err := saved.Statement()
+	require.NoError(t, err)
+	assert.Equal(t, "stale", stmt.Name)
+	assert.Equal(t, 1, stmt.Params)
+
+	mock.ExpectQuery(`INSERT INTO saved_queries`).WillReturnError(&pq.Error{Code: "23505"})
+	_, err = store.Create(ctx, SaveInput{Name: "stale", SQL: sql})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAndDelete(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery(`SELECT .* FROM saved_queries WHERE name = \$1`).WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(savedColumnNames))
+	_, err := store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mock.ExpectExec(`DELETE FROM saved_queries`).WithArgs("missing").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, store.Delete(ctx, "missing"), ErrNotFound)
+
+	mock.ExpectExec(`DELETE FROM saved_queries`).WithArgs("stale").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, store.Delete(ctx, "stale"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAudit(t *testing.T) {
+	store, mock := newTestStore(t)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM query_audit`).WithArgs("alice", "", int64(40), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"audit_id", "actor", "query_name", "sql", "params", "read_only",
+			"started_at", "duration_ms", "rows", "success", "error"}).
+			AddRow(39, "alice", "stale", "SELECT 1", []byte(`[]`), true, now, 3, 1, true, "").
+			AddRow(38, "alice", nil, "DELETE FROM modules WHERE name = $1", []byte(`["x"]`), false, now, 5, 0, false, "query timed out"))
+
+	entries, err := store.Audit(context.Background(), AuditFilter{Actor: "alice", Before: 40, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "stale", *entries[0].QueryName)
+	assert.Nil(t, entries[1].QueryName)
+	assert.Equal(t, json.RawMessage(`["x"]`), entries[1].Params)
+	assert.Equal(t, "query timed out", entries[1].Error)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
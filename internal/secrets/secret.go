@@ -0,0 +1,29 @@
+// Package secrets generates the random secrets behind join tokens, module
+// credentials, API keys and sessions, and hashes them for storage. Only the
+// hashes are stored; a secret is shown once when it is created.
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Bytes is the number of random bytes in a secret.
+const Bytes = 32
+
+// New returns a random hex-encoded secret.
+func New() (string, error) {
+	b := make([]byte, Bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Hash returns the SHA-256 hash under which a secret is stored.
+func Hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
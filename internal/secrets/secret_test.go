@@ -0,0 +1,20 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	a, err := New()
+	require.NoError(t, err)
+	b, err := New()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 2*Bytes)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, Hash(a), 32)
+	assert.Equal(t, Hash(a), Hash(a))
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// maxHostLength is the longest DNS name a module may register with.
+const maxHostLength = 253
+
+var hostLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// HostResolver resolves DNS names. *net.Resolver implements it.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Option configures a module service.
+type Option func(*Modules)
+
+// WithResolver makes the service reject DNS names that do not resolve when
+// a module registers or changes its address. IP addresses are never looked up.
+func WithResolver(resolver HostResolver) Option {
+	return func(m *Modules) {
+		m.resolver = resolver
+	}
+}
+
+// normalizeHost trims a host and strips the brackets of an IPv6 address.
+// IP addresses are returned in their canonical form and DNS names are
+// lowercased without a trailing dot.
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil && addr.Zone() == "" {
+		return addr.String()
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// validateHost checks that a normalized host is an IPv4 or IPv6 address or
+// a DNS name made of RFC 1123 labels.
+func validateHost(host string) error {
+	if host == "" {
+		return validationErrorf("host cannot be empty")
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if addr.Zone() != "" {
+			return validationErrorf("invalid host %q: IPv6 zones are not supported", host)
+		}
+		return nil
+	}
+
+	if len(host) > maxHostLength {
+		return validationErrorf("host too long (max %d characters)", maxHostLength)
+	}
+
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if !hostLabelPattern.MatchString(label) {
+			return validationErrorf("invalid host %q: must be an IP address or DNS name", host)
+		}
+	}
+
+	// A numeric top-level label means a mistyped IP address such as 10.0.0.256
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return validationErrorf("invalid host %q: must be an IP address or DNS name", host)
+	}
+
+	return nil
+}
+
+// validatePort checks that a port is in the range 1-65535.
+func validatePort(port int32) error {
+	if port <= 0 || port > 65535 {
+		return validationErrorf("invalid port number: %d (must be 1-65535)", port)
+	}
+	return nil
+}
+
+// checkResolvable looks up a DNS name when the service has a resolver.
+func (m *Modules) checkResolvable(ctx context.Context, host string) error {
+	if m.resolver == nil {
+		return nil
+	}
+
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+
+	addrs, err := m.resolver.LookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return validationErrorf("cannot resolve host %q", host)
+	}
+
+	return nil
+}
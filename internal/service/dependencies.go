@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Limits of module dependencies.
+const (
+	maxDependencies       = 50
+	maxVersionRangeLength = 255
+)
+
+// Reasons a dependency is not satisfied.
+const (
+	// DependencyMissing means no module with the dependency's name is registered.
+	DependencyMissing = "missing"
+	// DependencyVersionMismatch means the registered module's version is
+	// outside the range, or the module reports no version.
+	DependencyVersionMismatch = "version_mismatch"
+	// DependencyUnhealthy means the module is not ONLINE or none of its
+	// instances can serve requests.
+	DependencyUnhealthy = "unhealthy"
+)
+
+// Dependency names a module another module needs.
+type Dependency struct {
+	Name string `db:"name" json:"name"`
+	// Version is a semantic version range such as ">= 1.2, < 2". Empty
+	// accepts any version.
+	Version string `db:"version_range" json:"version,omitempty"`
+}
+
+// GraphNode is a registered module in the dependency graph.
+type GraphNode struct {
+	ModuleID string `db:"module_id" json:"module_id"`
+	Name     string `db:"name" json:"name"`
+	Version  string `db:"version" json:"version,omitempty"`
+	Status   string `db:"status" json:"status"`
+	Healthy  bool   `db:"healthy" json:"healthy"`
+}
+
+// GraphEdge is a dependency of module From on the module named To.
+type GraphEdge struct {
+	From    string `db:"module" json:"from"`
+	To      string `db:"name" json:"to"`
+	Version string `db:"version_range" json:"version,omitempty"`
+	// Problem is why the dependency is not satisfied, empty when it is.
+	Problem string `db:"-" json:"problem,omitempty"`
+}
+
+// Graph is the dependency graph of all registered modules.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Order lists the modules so that each comes after the registered
+	// modules it depends on, which is the order to start them in.
+	Order []string `json:"order"`
+}
+
+// Readiness reports whether the dependencies of a module are satisfied.
+type Readiness struct {
+	ModuleID string `json:"module_id"`
+	Name     string `json:"name"`
+	Ready    bool   `json:"ready"`
+	// Unmet lists the dependencies that are missing, of the wrong version or
+	// unhealthy.
+	Unmet []GraphEdge `json:"unmet,omitempty"`
+}
+
+// Dependencies returns the dependencies of a module sorted by name.
+func (m *Modules) Dependencies(ctx context.Context, moduleID string) ([]Dependency, error) {
+	return moduleDependencies(ctx, m.db, moduleID)
+}
+
+// moduleDependencies returns the dependencies of a module sorted by name.
+func moduleDependencies(ctx context.Context, q sqlx.QueryerContext, moduleID string) ([]Dependency, error) {
+	query := `SELECT name, version_range FROM module_dependencies WHERE module_id = $1 ORDER BY name`
+
+	dependencies := []Dependency{}
+	if err := sqlx.SelectContext(ctx, q, &dependencies, query, moduleID); err != nil {
+		return nil, fmt.Errorf("failed to load dependencies: %w", err)
+	}
+
+	return dependencies, nil
+}
+
+// Graph returns the dependency graph. Edges carry the problem that keeps
+// them from being satisfied, if any.
+func (m *Modules) Graph(ctx context.Context) (*Graph, error) {
+	query := `SELECT m.module_id, m.name, m.version, m.status,
+			m.status = 'ONLINE' AND EXISTS (
+				SELECT 1 FROM module_instances i
+				WHERE i.module_id = m.module_id AND i.status <> 'OFFLINE' AND i.healthy IS NOT FALSE
+			) AS healthy
+		FROM modules m
+		ORDER BY m.name`
+
+	graph := &Graph{Nodes: []GraphNode{}}
+	if err := m.db.SelectContext(ctx, &graph.Nodes, query); err != nil {
+		return nil, fmt.Errorf("failed to load modules: %w", err)
+	}
+
+	edges, err := dependencyEdges(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]GraphNode, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node.Name] = node
+	}
+
+	for i := range edges {
+		edges[i].Problem = dependencyProblem(edges[i], nodes)
+	}
+
+	graph.Edges = edges
+	graph.Order = startOrder(graph.Nodes, edges)
+
+	return graph, nil
+}
+
+// Readiness reports for every module, sorted by name, whether its
+// dependencies are registered, in range and healthy.
+func (m *Modules) Readiness(ctx context.Context) ([]Readiness, error) {
+	graph, err := m.Graph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unmet := make(map[string][]GraphEdge)
+	for _, edge := range graph.Edges {
+		if edge.Problem != "" {
+			unmet[edge.From] = append(unmet[edge.From], edge)
+		}
+	}
+
+	readiness := make([]Readiness, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		readiness = append(readiness, Readiness{
+			ModuleID: node.ModuleID,
+			Name:     node.Name,
+			Ready:    len(unmet[node.Name]) == 0,
+			Unmet:    unmet[node.Name],
+		})
+	}
+
+	return readiness, nil
+}
+
+// normalizeDependencies trims names and ranges. The result is never nil.
+func normalizeDependencies(dependencies []Dependency) []Dependency {
+	normalized := make([]Dependency, 0, len(dependencies))
+	for _, d := range dependencies {
+		normalized = append(normalized, Dependency{
+			Name:    strings.TrimSpace(d.Name),
+			Version: strings.TrimSpace(d.Version),
+		})
+	}
+	return normalized
+}
+
+// validateDependencies checks normalized dependencies: names, duplicates and
+// version ranges.
+func validateDependencies(dependencies []Dependency) error {
+	if len(dependencies) > maxDependencies {
+		return validationErrorf("too many dependencies (max %d)", maxDependencies)
+	}
+
+	seen := make(map[string]bool, len(dependencies))
+	for _, d := range dependencies {
+		if d.Name == "" {
+			return validationErrorf("dependency name cannot be empty")
+		}
+
+		if len(d.Name) > 255 {
+			return validationErrorf("dependency name too long (max 255 characters)")
+		}
+
+		if seen[d.Name] {
+			return validationErrorf("duplicate dependency %q", d.Name)
+		}
+		seen[d.Name] = true
+
+		if d.Version == "" {
+			continue
+		}
+
+		if _, err := semver.NewConstraint(d.Version); err != nil || len(d.Version) > maxVersionRangeLength {
+			return validationErrorf("invalid version range %q for dependency %q", d.Version, d.Name)
+		}
+	}
+
+	return nil
+}
+
+// lockDependencies serializes changes to the dependency graph until tx
+// ends. Otherwise two modules could each pass the cycle check while
+// together closing a cycle.
+func lockDependencies(ctx context.Context, tx *sqlx.Tx) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('module_dependencies'))`); err != nil {
+		return fmt.Errorf("failed to lock dependencies: %w", err)
+	}
+
+	return nil
+}
+
+// checkCycles reports a validation error when giving the module called name
+// the dependencies would close a cycle. oldName is the module's current
+// name, whose stored dependencies are replaced, or empty for a new module.
+// tx must hold lockDependencies.
+func checkCycles(ctx context.Context, tx *sqlx.Tx, name, oldName string, dependencies []Dependency) error {
+	if len(dependencies) == 0 {
+		return nil
+	}
+
+	edges, err := dependencyEdges(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	graph := make(map[string][]string)
+	for _, edge := range edges {
+		if edge.From != oldName && edge.From != name {
+			graph[edge.From] = append(graph[edge.From], edge.To)
+		}
+	}
+	for _, d := range dependencies {
+		graph[name] = append(graph[name], d.Name)
+	}
+
+	if cycle := findCycle(graph, name); cycle != nil {
+		return validationErrorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// setDependencies replaces the stored dependencies of a module.
+func setDependencies(ctx context.Context, tx *sqlx.Tx, moduleID string, dependencies []Dependency) error {
+	names, ranges := dependencyArrays(dependencies)
+
+	query := `WITH removed AS (
+			DELETE FROM module_dependencies WHERE module_id = $1 AND NOT (name = ANY ($2::text[]))
+		)
+		INSERT INTO module_dependencies (module_id, name, version_range)
+		SELECT $1, d.name, d.version_range FROM unnest($2::text[], $3::text[]) AS d (name, version_range)
+		ON CONFLICT (module_id, name) DO UPDATE SET version_range = EXCLUDED.version_range`
+	if _, err := tx.ExecContext(ctx, query, moduleID, names, ranges); err != nil {
+		return fmt.Errorf("failed to store dependencies: %w", err)
+	}
+
+	return nil
+}
+
+// dependencyEdges returns every stored dependency, keyed by the depending
+// module's name.
+func dependencyEdges(ctx context.Context, q sqlx.QueryerContext) ([]GraphEdge, error) {
+	query := `SELECT m.name AS module, d.name, d.version_range
+		FROM module_dependencies d
+		JOIN modules m ON m.module_id = d.module_id
+		ORDER BY m.name, d.name`
+
+	edges := []GraphEdge{}
+	if err := sqlx.SelectContext(ctx, q, &edges, query); err != nil {
+		return nil, fmt.Errorf("failed to load dependencies: %w", err)
+	}
+
+	return edges, nil
+}
+
+// dependencyArrays splits dependencies into name and range arrays for unnest.
+func dependencyArrays(dependencies []Dependency) (pq.StringArray, pq.StringArray) {
+	names := make(pq.StringArray, 0, len(dependencies))
+	ranges := make(pq.StringArray, 0, len(dependencies))
+	for _, d := range dependencies {
+		names = append(names, d.Name)
+		ranges = append(ranges, d.Version)
+	}
+	return names, ranges
+}
+
+// dependencyProblem returns why edge is not satisfied by the registered
+// modules, or an empty string.
+func dependencyProblem(edge GraphEdge, nodes map[string]GraphNode) string {
+	node, ok := nodes[edge.To]
+	if !ok {
+		return DependencyMissing
+	}
+
+	if edge.Version != "" {
+		constraint, err := semver.NewConstraint(edge.Version)
+		if err != nil {
+			return DependencyVersionMismatch
+		}
+		version, err := semver.NewVersion(node.Version)
+		if err != nil || !constraint.Check(version) {
+			return DependencyVersionMismatch
+		}
+	}
+
+	if !node.Healthy {
+		return DependencyUnhealthy
+	}
+
+	return ""
+}
+
+// findCycle returns a path from start back to itself, or nil.
+func findCycle(graph map[string][]string, start string) []string {
+	visited := make(map[string]bool)
+	var path []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		path = append(path, name)
+		for _, next := range graph[name] {
+			if next == start {
+				path = append(path, next)
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if visit(start) {
+		return path
+	}
+	return nil
+}
+
+// startOrder sorts the modules topologically so that dependencies come
+// first, breaking ties by name. Modules caught in a cycle come last.
+func startOrder(nodes []GraphNode, edges []GraphEdge) []string {
+	registered := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		registered[node.Name] = true
+	}
+
+	pending := make(map[string]int, len(nodes))
+	dependents := make(map[string][]string)
+	for _, edge := range edges {
+		if registered[edge.From] && registered[edge.To] {
+			pending[edge.From]++
+			dependents[edge.To] = append(dependents[edge.To], edge.From)
+		}
+	}
+
+	var ready []string
+	for _, node := range nodes {
+		if pending[node.Name] == 0 {
+			ready = append(ready, node.Name)
+		}
+	}
+
+	order := make([]string, 0, len(nodes))
+	done := make(map[string]bool, len(nodes))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		done[name] = true
+
+		for _, dependent := range dependents[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if !done[node.Name] {
+			order = append(order, node.Name)
+		}
+	}
+
+	return order
+}
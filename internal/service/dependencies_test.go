@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var edgeColumns = []string{"module", "name", "version_range"}
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies []Dependency
+		wantErr      string
+	}{
+		{"valid", []Dependency{{Name: "auth", Version: ">= 1.2, < 2"}, {Name: "storage"}}, ""},
+		{"empty name", []Dependency{{Name: ""}}, "dependency name cannot be empty"},
+		{"duplicate", []Dependency{{Name: "auth"}, {Name: "auth", Version: "^1"}}, "duplicate dependency"},
+		{"bad range", []Dependency{{Name: "auth", Version: "newest"}}, "invalid version range"},
+		{"too many", make([]Dependency, maxDependencies+1), "too many dependencies"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDependencies(tt.dependencies)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRegisterDependencies(t *testing.T) {
+	t.Run("stores dependencies", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`SELECT m.name AS module`).
+			WillReturnRows(sqlmock.NewRows(edgeColumns).AddRow("auth", "storage", ""))
+		mock.ExpectQuery(`INSERT INTO modules`).
+			WithArgs("dashboard", "10.0.0.1", int32(8080), "", "", "", pq.StringArray{}, "", pq.StringArray{}, int32(1), "",
+				pq.StringArray{"auth"}, pq.StringArray{"^1.2"}).
+			WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+
+		_, err := svc.Register(context.Background(), RegisterInput{
+			Name: "dashboard", IP: "10.0.0.1", Port: 8080,
+			Dependencies: []Dependency{{Name: " auth ", Version: " ^1.2 "}},
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects cycles", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`SELECT m.name AS module`).
+			WillReturnRows(sqlmock.NewRows(edgeColumns).
+				AddRow("auth", "storage", "").
+				AddRow("storage", "dashboard", ""))
+		mock.ExpectRollback()
+
+		_, err := svc.Register(context.Background(), RegisterInput{
+			Name: "dashboard", IP: "10.0.0.1", Port: 8080,
+			Dependencies: []Dependency{{Name: "auth"}},
+		})
+
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Contains(t, err.Error(), "dependency cycle: dashboard -> auth -> storage -> dashboard")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects depending on itself", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`SELECT m.name AS module`).WillReturnRows(sqlmock.NewRows(edgeColumns))
+		mock.ExpectRollback()
+
+		_, err := svc.Register(context.Background(), RegisterInput{
+			Name: "dashboard", IP: "10.0.0.1", Port: 8080,
+			Dependencies: []Dependency{{Name: "dashboard"}},
+		})
+
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestUpdateDependencies(t *testing.T) {
+	svc, mock := newMockService(t)
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"lb_policy", "description", "version", "author", "tags", "homepage", "capabilities"}
+	row := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "round_robin", "", "", "", "{}", "", "{}")
+	}
+
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(row())
+	// The check and both writes happen in one transaction, under the lock
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\('module_dependencies'\)\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT m.name AS module`).
+		WillReturnRows(sqlmock.NewRows(edgeColumns).AddRow("dashboard", "storage", ""))
+	mock.ExpectExec(`UPDATE modules SET name`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO module_dependencies .* ON CONFLICT \(module_id, name\)`).
+		WithArgs("00000000-0000-4000-a000-000000000001", pq.StringArray{"auth"}, pq.StringArray{""}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").WillReturnRows(row())
+
+	dependencies := []Dependency{{Name: "auth"}}
+	_, err := svc.Update(context.Background(), "00000000-0000-4000-a000-000000000001", UpdateInput{Dependencies: &dependencies})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraph(t *testing.T) {
+	svc, mock := newMockService(t)
+	mock.ExpectQuery(`SELECT m.module_id, m.name, m.version, m.status`).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id", "name", "version", "status", "healthy"}).
+			AddRow("00000000-0000-4000-a000-000000000001", "auth", "1.4.0", "ONLINE", true).
+			AddRow("00000000-0000-4000-a000-000000000002", "dashboard", "2.0.0", "ONLINE", true).
+			AddRow("00000000-0000-4000-a000-000000000003", "reports", "", "ONLINE", true).
+			AddRow("00000000-0000-4000-a000-000000000004", "storage", "1.0.0", "OFFLINE", false))
+	mock.ExpectQuery(`SELECT m.name AS module`).
+		WillReturnRows(sqlmock.NewRows(edgeColumns).
+			AddRow("auth", "storage", "").
+			AddRow("dashboard", "auth", ">= 1.2, < 2").
+			AddRow("reports", "auth", "^2").
+			AddRow("reports", "mailer", ""))
+
+	graph, err := svc.Graph(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, graph.Nodes, 4)
+	problems := make(map[string]string)
+	for _, edge := range graph.Edges {
+		problems[edge.From+"->"+edge.To] = edge.Problem
+	}
+	assert.Equal(t, map[string]string{
+		"auth->storage":   DependencyUnhealthy,
+		"dashboard->auth": "",
+		"reports->auth":   DependencyVersionMismatch,
+		"reports->mailer": DependencyMissing,
+	}, problems)
+	assert.Equal(t, []string{"storage", "auth", "dashboard", "reports"}, graph.Order)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadiness(t *testing.T) {
+	svc, mock := newMockService(t)
+	mock.ExpectQuery(`SELECT m.module_id, m.name, m.version, m.status`).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id", "name", "version", "status", "healthy"}).
+			AddRow("00000000-0000-4000-a000-000000000001", "auth", "1.4.0", "ONLINE", true).
+			AddRow("00000000-0000-4000-a000-000000000002", "dashboard", "", "ONLINE", true))
+	mock.ExpectQuery(`SELECT m.name AS module`).
+		WillReturnRows(sqlmock.NewRows(edgeColumns).
+			AddRow("dashboard", "auth", "").
+			AddRow("dashboard", "mailer", ""))
+
+	readiness, err := svc.Readiness(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, readiness, 2)
+	assert.True(t, readiness[0].Ready)
+	assert.False(t, readiness[1].Ready)
+	assert.Equal(t, []GraphEdge{{From: "dashboard", To: "mailer", Problem: DependencyMissing}}, readiness[1].Unmet)
+}
+
+func TestStartOrderKeepsCycles(t *testing.T) {
+	nodes := []GraphNode{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	edges := []GraphEdge{{From: "a", To: "b"}, {From: "b", To: "a"}}
+
+	assert.Equal(t, []string{"c", "a", "b"}, startOrder(nodes, edges))
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Load balancing policies of the module proxy.
+const (
+	// PolicyRoundRobin sends requests to the instances in turn.
+	PolicyRoundRobin = "round_robin"
+	// PolicyLeastConnections sends requests to the instance with the fewest
+	// requests in flight.
+	PolicyLeastConnections = "least_connections"
+	// PolicyWeighted spreads requests in proportion to instance weights.
+	PolicyWeighted = "weighted"
+)
+
+// Limits of instance placement.
+const (
+	defaultWeight = 1
+	maxWeight     = 1000
+	maxZoneLength = 64
+)
+
+// Instance is one replica of a module. Every module has a primary instance
+// at the module's own address; replicas add more.
+type Instance struct {
+	InstanceID string `db:"instance_id" json:"instance_id"`
+	ModuleID   string `db:"module_id" json:"module_id"`
+	Host       string `db:"host" json:"host"`
+	Port       int32  `db:"port" json:"port"`
+	IPPort     string `db:"ip_port" json:"ip_port"`
+	// Weight is the share of requests the instance gets under the weighted policy.
+	Weight int32  `db:"weight" json:"weight"`
+	Zone   string `db:"zone" json:"zone,omitempty"`
+	Status string `db:"status" json:"status"`
+	// Healthy is the latest probe result, nil until the instance was probed.
+	Healthy    *bool      `db:"healthy" json:"healthy"`
+	LastSeenAt *time.Time `db:"last_seen_at" json:"last_seen_at,omitempty"`
+	Primary    bool       `db:"is_primary" json:"primary"`
+}
+
+// InstanceInput describes an instance to add to a module. A zero weight
+// means the default of 1.
+type InstanceInput struct {
+	// IP is the host of the instance: an IPv4 or IPv6 address or a DNS name.
+	IP     string `json:"ip"`
+	Port   int32  `json:"port"`
+	Weight int32  `json:"weight"`
+	Zone   string `json:"zone"`
+}
+
+// instanceColumns are the columns scanned into an Instance. The query must
+// join the module as m.
+const instanceColumns = `i.instance_id, i.module_id, i.host, i.port, i.ip_port, i.weight, i.zone, i.status,
+	i.healthy, i.last_seen_at, (i.host, i.port) = (m.host, m.port) AS is_primary`
+
+// Instances returns the instances of a module, oldest first.
+func (m *Modules) Instances(ctx context.Context, moduleID string) ([]Instance, error) {
+	if !ValidID(moduleID) {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + instanceColumns + ` FROM module_instances i
+		JOIN modules m ON m.module_id = i.module_id
+		WHERE i.module_id = $1
+		ORDER BY i.created_at, i.instance_id`
+
+	var instances []Instance
+	if err := m.db.SelectContext(ctx, &instances, query, moduleID); err != nil {
+		return nil, fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	// Every module has its primary instance
+	if len(instances) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return instances, nil
+}
+
+// InstancesOf returns the instances of the given modules keyed by module
+// ID, oldest first.
+func (m *Modules) InstancesOf(ctx context.Context, moduleIDs []string) (map[string][]Instance, error) {
+	query := `SELECT ` + instanceColumns + ` FROM module_instances i
+		JOIN modules m ON m.module_id = i.module_id
+		WHERE i.module_id = ANY ($1::uuid[])
+		ORDER BY i.created_at, i.instance_id`
+
+	var instances []Instance
+	if err := m.db.SelectContext(ctx, &instances, query, pq.StringArray(moduleIDs)); err != nil {
+		return nil, fmt.Errorf("failed to load instances: %w", err)
+	}
+
+	byModule := make(map[string][]Instance, len(moduleIDs))
+	for _, instance := range instances {
+		byModule[instance.ModuleID] = append(byModule[instance.ModuleID], instance)
+	}
+
+	return byModule, nil
+}
+
+// AddInstance adds an instance to a module and marks it ONLINE. Adding an
+// address the module already has updates its weight and zone instead.
+func (m *Modules) AddInstance(ctx context.Context, moduleID string, in InstanceInput) (*Instance, error) {
+	in.IP = normalizeHost(in.IP)
+	in.Weight, in.Zone = placementDefaults(in.Weight, in.Zone)
+	if err := validateHost(in.IP); err != nil {
+		return nil, err
+	}
+
+	if err := validatePort(in.Port); err != nil {
+		return nil, err
+	}
+
+	if err := validatePlacement(in.Weight, in.Zone); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkResolvable(ctx, in.IP); err != nil {
+		return nil, err
+	}
+
+	exists, err := m.moduleIDExists(ctx, moduleID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	var instanceID string
+	query := `INSERT INTO module_instances (module_id, host, port, weight, zone, status, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, 'ONLINE', CURRENT_TIMESTAMP)
+		ON CONFLICT (module_id, host, port) DO UPDATE
+			SET weight = EXCLUDED.weight, zone = EXCLUDED.zone, status = 'ONLINE', last_seen_at = CURRENT_TIMESTAMP
+		RETURNING instance_id`
+	if err := m.db.GetContext(ctx, &instanceID, query, moduleID, in.IP, in.Port, in.Weight, in.Zone); err != nil {
+		return nil, fmt.Errorf("failed to add instance: %w", err)
+	}
+
+	return m.instance(ctx, moduleID, instanceID)
+}
+
+// RemoveInstance removes an instance other than the primary one, which only
+// goes away with the module.
+func (m *Modules) RemoveInstance(ctx context.Context, moduleID, instanceID string) error {
+	instance, err := m.instance(ctx, moduleID, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if instance.Primary {
+		return validationErrorf("the primary instance cannot be removed, change the module's address instead")
+	}
+
+	query := `DELETE FROM module_instances WHERE instance_id = $1`
+	if _, err := m.db.ExecContext(ctx, query, instance.InstanceID); err != nil {
+		return fmt.Errorf("failed to remove instance: %w", err)
+	}
+
+	return nil
+}
+
+// freeAddress removes the replica of a module that listens on host and
+// port, so that the primary instance can move there. A replica at the
+// primary's new address is the same server, and keeping both rows would
+// break their unique address.
+func freeAddress(ctx context.Context, tx *sqlx.Tx, moduleID, host string, port int32) error {
+	query := `DELETE FROM module_instances
+		WHERE module_id = $1 AND host = $2 AND port = $3
+		AND (host, port) <> (SELECT host, port FROM modules WHERE module_id = $1)`
+	if _, err := tx.ExecContext(ctx, query, moduleID, host, port); err != nil {
+		return fmt.Errorf("failed to free address: %w", err)
+	}
+
+	return nil
+}
+
+// instance returns an instance of a module.
+func (m *Modules) instance(ctx context.Context, moduleID, instanceID string) (*Instance, error) {
+	if !ValidID(moduleID) || !ValidID(instanceID) {
+		return nil, ErrInstanceNotFound
+	}
+
+	query := `SELECT ` + instanceColumns + ` FROM module_instances i
+		JOIN modules m ON m.module_id = i.module_id
+		WHERE i.module_id = $1 AND i.instance_id = $2`
+
+	var instance Instance
+	err := m.db.GetContext(ctx, &instance, query, moduleID, instanceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInstanceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instance: %w", err)
+	}
+
+	return &instance, nil
+}
+
+// placementDefaults fills in the default weight and trims the zone.
+func placementDefaults(weight int32, zone string) (int32, string) {
+	if weight == 0 {
+		weight = defaultWeight
+	}
+	return weight, strings.TrimSpace(zone)
+}
+
+// validatePlacement checks the weight and zone of an instance.
+func validatePlacement(weight int32, zone string) error {
+	if weight < 1 || weight > maxWeight {
+		return validationErrorf("invalid weight: %d (must be 1-%d)", weight, maxWeight)
+	}
+
+	if len(zone) > maxZoneLength {
+		return validationErrorf("zone too long (max %d characters)", maxZoneLength)
+	}
+
+	return nil
+}
+
+// validatePolicy checks a load balancing policy name.
+func validatePolicy(policy string) error {
+	switch policy {
+	case PolicyRoundRobin, PolicyLeastConnections, PolicyWeighted:
+		return nil
+	default:
+		return validationErrorf("invalid load balancing policy %q (expected %s, %s or %s)",
+			policy, PolicyRoundRobin, PolicyLeastConnections, PolicyWeighted)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var instanceRowColumns = []string{"instance_id", "module_id", "host", "port", "ip_port", "weight", "zone", "status", "healthy", "last_seen_at", "is_primary"}
+
+func TestInstances(t *testing.T) {
+	svc, mock := newMockService(t)
+	mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(instanceRowColumns).
+			AddRow("00000000-0000-4000-9000-000000000001", "00000000-0000-4000-a000-000000000001", "10.0.0.1", 8080, "10.0.0.1:8080", 1, "", "ONLINE", true, nil, true).
+			AddRow("00000000-0000-4000-9000-000000000002", "00000000-0000-4000-a000-000000000001", "10.0.0.2", 8080, "10.0.0.2:8080", 3, "eu-west-1b", "STALE", nil, nil, false))
+	mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000009").
+		WillReturnRows(sqlmock.NewRows(instanceRowColumns))
+
+	instances, err := svc.Instances(context.Background(), "00000000-0000-4000-a000-000000000001")
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.True(t, instances[0].Primary)
+	assert.True(t, *instances[0].Healthy)
+	assert.Equal(t, int32(3), instances[1].Weight)
+	assert.Nil(t, instances[1].Healthy)
+
+	_, err = svc.Instances(context.Background(), "00000000-0000-4000-a000-000000000009")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddInstance(t *testing.T) {
+	t.Run("adds instance", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`INSERT INTO module_instances .* ON CONFLICT \(module_id, host, port\) DO UPDATE`).
+			WithArgs("00000000-0000-4000-a000-000000000001", "10.0.0.2", int32(8080), int32(1), "eu-west-1b").
+			WillReturnRows(sqlmock.NewRows([]string{"instance_id"}).AddRow("00000000-0000-4000-9000-000000000002"))
+		mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000002").
+			WillReturnRows(sqlmock.NewRows(instanceRowColumns).
+				AddRow("00000000-0000-4000-9000-000000000002", "00000000-0000-4000-a000-000000000001", "10.0.0.2", 8080, "10.0.0.2:8080", 1, "eu-west-1b", "ONLINE", nil, nil, false))
+
+		instance, err := svc.AddInstance(context.Background(), "00000000-0000-4000-a000-000000000001", InstanceInput{IP: "10.0.0.2", Port: 8080, Zone: " eu-west-1b "})
+
+		require.NoError(t, err)
+		assert.Equal(t, "00000000-0000-4000-9000-000000000002", instance.InstanceID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("adds instance by DNS name", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`INSERT INTO module_instances`).
+			WithArgs("00000000-0000-4000-a000-000000000001", "dashboard-1.dashboard.default.svc", int32(8080), int32(1), "").
+			WillReturnRows(sqlmock.NewRows([]string{"instance_id"}).AddRow("00000000-0000-4000-9000-000000000003"))
+		mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000003").
+			WillReturnRows(sqlmock.NewRows(instanceRowColumns).
+				AddRow("00000000-0000-4000-9000-000000000003", "00000000-0000-4000-a000-000000000001", "dashboard-1.dashboard.default.svc", 8080, "dashboard-1.dashboard.default.svc:8080", 1, "", "ONLINE", nil, nil, false))
+
+		instance, err := svc.AddInstance(context.Background(), "00000000-0000-4000-a000-000000000001", InstanceInput{IP: "Dashboard-1.dashboard.default.svc.", Port: 8080})
+
+		require.NoError(t, err)
+		assert.Equal(t, "dashboard-1.dashboard.default.svc", instance.Host)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects invalid weight", func(t *testing.T) {
+		svc, _ := newMockService(t)
+
+		_, err := svc.AddInstance(context.Background(), "00000000-0000-4000-a000-000000000001", InstanceInput{IP: "10.0.0.2", Port: 8080, Weight: 1001})
+
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Contains(t, err.Error(), "invalid weight")
+	})
+
+	t.Run("unknown module", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("00000000-0000-4000-a000-000000000009").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := svc.AddInstance(context.Background(), "00000000-0000-4000-a000-000000000009", InstanceInput{IP: "10.0.0.2", Port: 8080})
+
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRemoveInstance(t *testing.T) {
+	t.Run("removes replica", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000002").
+			WillReturnRows(sqlmock.NewRows(instanceRowColumns).
+				AddRow("00000000-0000-4000-9000-000000000002", "00000000-0000-4000-a000-000000000001", "10.0.0.2", 8080, "10.0.0.2:8080", 1, "", "OFFLINE", false, nil, false))
+		mock.ExpectExec(`DELETE FROM module_instances`).WithArgs("00000000-0000-4000-9000-000000000002").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, svc.RemoveInstance(context.Background(), "00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000002"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keeps primary instance", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000001").
+			WillReturnRows(sqlmock.NewRows(instanceRowColumns).
+				AddRow("00000000-0000-4000-9000-000000000001", "00000000-0000-4000-a000-000000000001", "10.0.0.1", 8080, "10.0.0.1:8080", 1, "", "ONLINE", true, nil, true))
+
+		err := svc.RemoveInstance(context.Background(), "00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000001")
+
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown instance", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectQuery(`SELECT i.instance_id, .* FROM module_instances i`).
+			WithArgs("00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000404").
+			WillReturnRows(sqlmock.NewRows(instanceRowColumns))
+
+		err := svc.RemoveInstance(context.Background(), "00000000-0000-4000-a000-000000000001", "00000000-0000-4000-9000-000000000404")
+		assert.ErrorIs(t, err, ErrInstanceNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed instance ID", func(t *testing.T) {
+		svc, mock := newMockService(t)
+
+		assert.ErrorIs(t, svc.RemoveInstance(context.Background(), "00000000-0000-4000-a000-000000000001", "nope"), ErrInstanceNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,847 @@
+// Package service contains the transport-independent module management logic.
+// Both the gRPC ModulesService and the REST API call into it, so validation
+// rules and database behaviour are identical regardless of how a request arrives.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrValidation is matched by every ValidationError.
+	ErrValidation = errors.New("validation failed")
+	// ErrNotFound is returned when the requested module does not exist.
+	ErrNotFound = errors.New("module not found")
+	// ErrConflict is returned when a module with the same name already exists.
+	ErrConflict = errors.New("module with the same name already exists")
+	// ErrInstanceNotFound is returned when the requested instance does not exist.
+	ErrInstanceNotFound = errors.New("instance not found")
+)
+
+// ValidationError describes invalid input supplied by the caller.
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+// Is reports whether target is ErrValidation.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func validationErrorf(format string, args ...interface{}) error {
+	return &ValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Limits of module metadata.
+const (
+	maxDescriptionLength = 1000
+	maxAuthorLength      = 255
+	maxHomepageLength    = 2048
+	maxTags              = 20
+	maxCapabilities      = 50
+)
+
+var (
+	tagPattern        = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+	capabilityPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:/-]{0,63}$`)
+	idPattern         = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// ValidID reports whether id is a UUID, as module and instance IDs are.
+// Other IDs cannot exist, and Postgres would fail to compare them with the
+// uuid columns, so they are reported as not found without a query.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Metadata describes what a module is and does. Every field is optional and
+// omitted from JSON when empty.
+type Metadata struct {
+	Description string `db:"description" json:"description,omitempty"`
+	// Version is a semantic version such as 1.4.0 or 2.0.0-rc.1.
+	Version string `db:"version" json:"version,omitempty"`
+	Author  string `db:"author" json:"author,omitempty"`
+	// Tags are lowercase labels such as "monitoring" used to find modules.
+	Tags pq.StringArray `db:"tags" json:"tags,omitempty"`
+	// Homepage is an http or https URL.
+	Homepage string `db:"homepage" json:"homepage,omitempty"`
+	// Capabilities name what the module offers, such as "auth.login".
+	Capabilities pq.StringArray `db:"capabilities" json:"capabilities,omitempty"`
+}
+
+// Module is a registered module as stored in the modules table.
+type Module struct {
+	ModuleID string `db:"module_id" json:"module_id"`
+	Name     string `db:"name" json:"name"`
+	// Host is an IP address or DNS name. IPPort joins it with the port as
+	// net.JoinHostPort does.
+	Host           string     `db:"host" json:"host"`
+	Port           int32      `db:"port" json:"port"`
+	IPPort         string     `db:"ip_port" json:"ip_port"`
+	Status         string     `db:"status" json:"status"`
+	LastSeenAt     *time.Time `db:"last_seen_at" json:"last_seen_at,omitempty"`
+	ProxyTimeoutMS *int64     `db:"proxy_timeout_ms" json:"proxy_timeout_ms,omitempty"`
+	// LBPolicy selects how the proxy spreads requests over the instances.
+	LBPolicy string `db:"lb_policy" json:"lb_policy,omitempty"`
+	Metadata
+}
+
+// moduleColumns are the columns scanned into a Module.
+const moduleColumns = `module_id, name, host, port, ip_port, status, last_seen_at, proxy_timeout_ms, lb_policy,
+	description, version, author, tags, homepage, capabilities`
+
+// RegisterInput holds the data needed to register a module. The address,
+// weight and zone describe its primary instance.
+type RegisterInput struct {
+	Name string `json:"name"`
+	// IP is the host of the module: an IPv4 or IPv6 address or a DNS name.
+	IP     string `json:"ip"`
+	Port   int32  `json:"port"`
+	Weight int32  `json:"weight"`
+	Zone   string `json:"zone"`
+	Metadata
+	// Dependencies name the modules this module needs.
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// UpdateInput holds a partial module update. Nil fields are left unchanged.
+type UpdateInput struct {
+	Name           *string   `json:"name"`
+	IP             *string   `json:"ip"`
+	Port           *int32    `json:"port"`
+	ProxyTimeoutMS *int64    `json:"proxy_timeout_ms"`
+	LBPolicy       *string   `json:"lb_policy"`
+	Description    *string   `json:"description"`
+	Version        *string   `json:"version"`
+	Author         *string   `json:"author"`
+	Tags           *[]string `json:"tags"`
+	Homepage       *string   `json:"homepage"`
+	Capabilities   *[]string `json:"capabilities"`
+	// Dependencies replaces all dependencies of the module.
+	Dependencies *[]Dependency `json:"dependencies"`
+}
+
+// ImageInput holds the data needed to set up a module image.
+type ImageInput struct {
+	ModuleID   string
+	Image      []byte
+	FileFormat string
+}
+
+// Modules implements module registration, image setup, updates and deletion.
+type Modules struct {
+	db       *sqlx.DB
+	resolver HostResolver
+}
+
+// NewModules creates a module service backed by the given database.
+func NewModules(db *sqlx.DB, opts ...Option) *Modules {
+	m := &Modules{db: db}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register validates the input, checks for name conflicts and creates the module.
+// It returns the generated module ID.
+func (m *Modules) Register(ctx context.Context, in RegisterInput) (string, error) {
+	in.IP = normalizeHost(in.IP)
+	in.Metadata = in.Metadata.normalized()
+	in.Dependencies = normalizeDependencies(in.Dependencies)
+	in.Weight, in.Zone = placementDefaults(in.Weight, in.Zone)
+	if err := validateRegisterRequest(in); err != nil {
+		return "", err
+	}
+
+	if err := validatePlacement(in.Weight, in.Zone); err != nil {
+		return "", err
+	}
+
+	if err := m.checkResolvable(ctx, in.IP); err != nil {
+		return "", err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert module: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockDependencies(ctx, tx); err != nil {
+		return "", err
+	}
+
+	// Check if module with same name already exists
+	exists, err := moduleNameExists(ctx, tx, in.Name, "")
+	if err != nil {
+		return "", err
+	}
+
+	if exists {
+		return "", ErrConflict
+	}
+
+	if err := checkCycles(ctx, tx, in.Name, "", in.Dependencies); err != nil {
+		return "", err
+	}
+
+	moduleID, err := createModule(ctx, tx, in)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to insert module: %w", err)
+	}
+
+	return moduleID, nil
+}
+
+// Get returns the module with the given ID.
+func (m *Modules) Get(ctx context.Context, moduleID string) (*Module, error) {
+	if !ValidID(moduleID) {
+		return nil, ErrNotFound
+	}
+
+	var module Module
+	query := `SELECT ` + moduleColumns + ` FROM modules WHERE module_id = $1`
+
+	err := m.db.GetContext(ctx, &module, query, moduleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load module: %w", err)
+	}
+
+	return &module, nil
+}
+
+// FindByName returns the module with the given name.
+func (m *Modules) FindByName(ctx context.Context, name string) (*Module, error) {
+	var module Module
+	query := `SELECT ` + moduleColumns + ` FROM modules WHERE name = $1`
+
+	err := m.db.GetContext(ctx, &module, query, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load module: %w", err)
+	}
+
+	return &module, nil
+}
+
+// ListFilter selects modules that have all of the given tags and all of the
+// given capabilities.
+type ListFilter struct {
+	Tags         []string
+	Capabilities []string
+}
+
+// List returns the modules matching filter, sorted by name.
+func (m *Modules) List(ctx context.Context, filter ListFilter) ([]Module, error) {
+	query := `SELECT ` + moduleColumns + ` FROM modules WHERE tags @> $1 AND capabilities @> $2 ORDER BY name`
+
+	modules := []Module{}
+	err := m.db.SelectContext(ctx, &modules, query, normalizeList(filter.Tags), normalizeList(filter.Capabilities))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	return modules, nil
+}
+
+// Update applies a partial update to a module and returns the updated module.
+func (m *Modules) Update(ctx context.Context, moduleID string, in UpdateInput) (*Module, error) {
+	current, err := m.Get(ctx, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := RegisterInput{Name: current.Name, IP: current.Host, Port: current.Port, Metadata: current.Metadata}
+	if in.Name != nil {
+		merged.Name = *in.Name
+	}
+	if in.IP != nil {
+		merged.IP = normalizeHost(*in.IP)
+	}
+	if in.Port != nil {
+		merged.Port = *in.Port
+	}
+	in.applyMetadata(&merged.Metadata)
+	merged.Metadata = merged.Metadata.normalized()
+	if in.Dependencies != nil {
+		merged.Dependencies = normalizeDependencies(*in.Dependencies)
+	}
+
+	if err := validateRegisterRequest(merged); err != nil {
+		return nil, err
+	}
+
+	if in.ProxyTimeoutMS != nil && *in.ProxyTimeoutMS < 0 {
+		return nil, validationErrorf("proxy timeout cannot be negative")
+	}
+
+	policy := current.LBPolicy
+	if in.LBPolicy != nil {
+		policy = *in.LBPolicy
+		if err := validatePolicy(policy); err != nil {
+			return nil, err
+		}
+	}
+
+	if merged.IP != current.Host {
+		if err := m.checkResolvable(ctx, merged.IP); err != nil {
+			return nil, err
+		}
+	}
+
+	// A zero timeout clears the module's override
+	proxyTimeout := current.ProxyTimeoutMS
+	if in.ProxyTimeoutMS != nil {
+		proxyTimeout = in.ProxyTimeoutMS
+		if *in.ProxyTimeoutMS == 0 {
+			proxyTimeout = nil
+		}
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update module: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A new name can close a cycle through modules that depend on it
+	if in.Dependencies != nil || merged.Name != current.Name {
+		if err := lockDependencies(ctx, tx); err != nil {
+			return nil, err
+		}
+
+		dependencies := merged.Dependencies
+		if in.Dependencies == nil {
+			if dependencies, err = moduleDependencies(ctx, tx, moduleID); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkCycles(ctx, tx, merged.Name, current.Name, dependencies); err != nil {
+			return nil, err
+		}
+	}
+
+	if merged.Name != current.Name {
+		exists, err := moduleNameExists(ctx, tx, merged.Name, moduleID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrConflict
+		}
+	}
+
+	if merged.IP != current.Host || merged.Port != current.Port {
+		if err := freeAddress(ctx, tx, moduleID, merged.IP, merged.Port); err != nil {
+			return nil, err
+		}
+	}
+
+	// The primary instance moves along with the module's address
+	md := merged.Metadata
+	query := `WITH moved AS (
+			UPDATE module_instances SET host = $2, port = $3
+			WHERE module_id = $11 AND (host, port) = (SELECT host, port FROM modules WHERE module_id = $11)
+		)
+		UPDATE modules SET name = $1, host = $2, port = $3, proxy_timeout_ms = $4,
+		description = $5, version = $6, author = $7, tags = $8, homepage = $9, capabilities = $10, lb_policy = $12
+		WHERE module_id = $11`
+	_, err = tx.ExecContext(ctx, query, merged.Name, merged.IP, merged.Port, proxyTimeout,
+		md.Description, md.Version, md.Author, md.Tags, md.Homepage, md.Capabilities, moduleID, policy)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update module: %w", err)
+	}
+
+	if in.Dependencies != nil {
+		if err := setDependencies(ctx, tx, moduleID, merged.Dependencies); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update module: %w", err)
+	}
+
+	return m.Get(ctx, moduleID)
+}
+
+// Reregister replaces the address, metadata and dependencies of a module
+// that restarted, keeping its ID and name, and marks it and its primary
+// instance ONLINE. The name, weight and zone in the input are ignored.
+func (m *Modules) Reregister(ctx context.Context, moduleID string, in RegisterInput) (*Module, error) {
+	current, err := m.Get(ctx, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	in.Name = current.Name
+	in.IP = normalizeHost(in.IP)
+	in.Metadata = in.Metadata.normalized()
+	in.Dependencies = normalizeDependencies(in.Dependencies)
+	if err := validateRegisterRequest(in); err != nil {
+		return nil, err
+	}
+
+	if err := m.checkResolvable(ctx, in.IP); err != nil {
+		return nil, err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reregister module: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockDependencies(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	if err := checkCycles(ctx, tx, in.Name, current.Name, in.Dependencies); err != nil {
+		return nil, err
+	}
+
+	if err := freeAddress(ctx, tx, moduleID, in.IP, in.Port); err != nil {
+		return nil, err
+	}
+
+	md := in.Metadata
+	query := `WITH moved AS (
+			UPDATE module_instances SET host = $1, port = $2, status = 'ONLINE', last_seen_at = CURRENT_TIMESTAMP
+			WHERE module_id = $9 AND (host, port) = (SELECT host, port FROM modules WHERE module_id = $9)
+		)
+		UPDATE modules SET host = $1, port = $2, description = $3, version = $4, author = $5, tags = $6,
+		homepage = $7, capabilities = $8, status = 'ONLINE', last_seen_at = CURRENT_TIMESTAMP
+		WHERE module_id = $9`
+	if _, err := tx.ExecContext(ctx, query, in.IP, in.Port,
+		md.Description, md.Version, md.Author, md.Tags, md.Homepage, md.Capabilities, moduleID); err != nil {
+		return nil, fmt.Errorf("failed to reregister module: %w", err)
+	}
+
+	if err := setDependencies(ctx, tx, moduleID, in.Dependencies); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to reregister module: %w", err)
+	}
+
+	return m.Get(ctx, moduleID)
+}
+
+// SetupImage validates the image, verifies the module exists and stores the image.
+func (m *Modules) SetupImage(ctx context.Context, in ImageInput) error {
+	if err := validateSetupRequest(in); err != nil {
+		return err
+	}
+
+	// Verify module exists before setup
+	exists, err := m.moduleIDExists(ctx, in.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNotFound
+	}
+
+	return m.setupModuleImage(ctx, in.ModuleID, in.Image, in.FileFormat)
+}
+
+// Delete removes a module. It returns false if the module did not exist.
+func (m *Modules) Delete(ctx context.Context, moduleID string) (bool, error) {
+	if strings.TrimSpace(moduleID) == "" {
+		return false, validationErrorf("module ID cannot be empty")
+	}
+
+	// Delete module (cascading deletes should handle related records)
+	return m.deleteModule(ctx, moduleID)
+}
+
+// DeleteAll removes every module and, through cascading deletes, all related
+// records. It returns the number of deleted modules.
+func (m *Modules) DeleteAll(ctx context.Context) (int64, error) {
+	result, err := m.db.ExecContext(ctx, `DELETE FROM modules`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete modules: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RecordHeartbeat refreshes the last-seen timestamp of a module and one of
+// its instances and marks both ONLINE. Without an instance ID, the primary
+// instance is refreshed.
+func (m *Modules) RecordHeartbeat(ctx context.Context, moduleID, instanceID string) error {
+	if strings.TrimSpace(moduleID) == "" {
+		return validationErrorf("module ID cannot be empty")
+	}
+
+	if !ValidID(moduleID) {
+		return ErrNotFound
+	}
+
+	query := `UPDATE modules SET last_seen_at = CURRENT_TIMESTAMP, status = 'ONLINE' WHERE module_id = $1`
+
+	result, err := m.db.ExecContext(ctx, query, moduleID)
+	if err != nil {
+		return fmt.Errorf("failed to update last seen timestamp: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if instanceID != "" && !ValidID(instanceID) {
+		return ErrInstanceNotFound
+	}
+
+	// Without an instance ID, $2 is NULL and the primary instance matches
+	query = `UPDATE module_instances i SET last_seen_at = CURRENT_TIMESTAMP, status = 'ONLINE'
+		FROM modules m
+		WHERE m.module_id = i.module_id AND i.module_id = $1
+			AND (i.instance_id = $2 OR ($2 IS NULL AND (i.host, i.port) = (m.host, m.port)))`
+
+	result, err = m.db.ExecContext(ctx, query, moduleID, sql.NullString{String: instanceID, Valid: instanceID != ""})
+	if err != nil {
+		return fmt.Errorf("failed to update instance last seen timestamp: %w", err)
+	}
+
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 && instanceID != "" {
+		return ErrInstanceNotFound
+	}
+
+	return nil
+}
+
+// ValidateRegisterInput checks registration input without touching the database.
+func ValidateRegisterInput(in RegisterInput) error {
+	in.IP = normalizeHost(in.IP)
+	in.Metadata = in.Metadata.normalized()
+	in.Dependencies = normalizeDependencies(in.Dependencies)
+	return validateRegisterRequest(in)
+}
+
+// ValidateImageInput checks image input without touching the database.
+func ValidateImageInput(in ImageInput) error {
+	return validateSetupRequest(in)
+}
+
+// Helper functions for validation and database operations
+
+// validateRegisterRequest validates the register request parameters.
+// It checks for empty module names, name length limits, valid hosts,
+// port number ranges (1-65535), the module's metadata and its dependencies.
+// The host and dependencies must already be normalized.
+func validateRegisterRequest(in RegisterInput) error {
+	if strings.TrimSpace(in.Name) == "" {
+		return validationErrorf("module name cannot be empty")
+	}
+
+	if len(in.Name) > 255 {
+		return validationErrorf("module name too long (max 255 characters)")
+	}
+
+	if err := validateHost(in.IP); err != nil {
+		return err
+	}
+
+	if err := validatePort(in.Port); err != nil {
+		return err
+	}
+
+	if err := validateMetadata(in.Metadata); err != nil {
+		return err
+	}
+
+	return validateDependencies(in.Dependencies)
+}
+
+// validateMetadata checks normalized metadata: lengths, the semantic
+// version, the homepage URL and the format of tags and capabilities.
+func validateMetadata(md Metadata) error {
+	if len(md.Description) > maxDescriptionLength {
+		return validationErrorf("description too long (max %d characters)", maxDescriptionLength)
+	}
+
+	if md.Version != "" {
+		if _, err := semver.StrictNewVersion(md.Version); err != nil {
+			return validationErrorf("invalid version %q: must be a semantic version such as 1.2.3", md.Version)
+		}
+	}
+
+	if len(md.Author) > maxAuthorLength {
+		return validationErrorf("author too long (max %d characters)", maxAuthorLength)
+	}
+
+	if md.Homepage != "" {
+		u, err := url.Parse(md.Homepage)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(md.Homepage) > maxHomepageLength {
+			return validationErrorf("invalid homepage %q: must be an http or https URL", md.Homepage)
+		}
+	}
+
+	if len(md.Tags) > maxTags {
+		return validationErrorf("too many tags (max %d)", maxTags)
+	}
+	for _, tag := range md.Tags {
+		if !tagPattern.MatchString(tag) {
+			return validationErrorf("invalid tag %q: must be 1-32 lowercase letters, digits or '-'", tag)
+		}
+	}
+
+	if len(md.Capabilities) > maxCapabilities {
+		return validationErrorf("too many capabilities (max %d)", maxCapabilities)
+	}
+	for _, capability := range md.Capabilities {
+		if !capabilityPattern.MatchString(capability) {
+			return validationErrorf("invalid capability %q: must be 1-64 lowercase letters, digits, '.', '_', ':', '/' or '-'", capability)
+		}
+	}
+
+	return nil
+}
+
+// normalized trims the metadata, lowercases tags and capabilities and drops
+// duplicates. Lists are never nil, as the columns are NOT NULL.
+func (md Metadata) normalized() Metadata {
+	md.Description = strings.TrimSpace(md.Description)
+	md.Version = strings.TrimSpace(md.Version)
+	md.Author = strings.TrimSpace(md.Author)
+	md.Homepage = strings.TrimSpace(md.Homepage)
+	md.Tags = normalizeList(md.Tags)
+	md.Capabilities = normalizeList(md.Capabilities)
+	return md
+}
+
+func normalizeList(values []string) pq.StringArray {
+	list := pq.StringArray{}
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if !seen[v] {
+			seen[v] = true
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// applyMetadata copies the metadata fields set in the update onto md.
+func (in UpdateInput) applyMetadata(md *Metadata) {
+	if in.Description != nil {
+		md.Description = *in.Description
+	}
+	if in.Version != nil {
+		md.Version = *in.Version
+	}
+	if in.Author != nil {
+		md.Author = *in.Author
+	}
+	if in.Tags != nil {
+		md.Tags = *in.Tags
+	}
+	if in.Homepage != nil {
+		md.Homepage = *in.Homepage
+	}
+	if in.Capabilities != nil {
+		md.Capabilities = *in.Capabilities
+	}
+}
+
+// validateSetupRequest validates the setup request parameters.
+// It checks for empty module IDs, image data presence, file format validity,
+// and ensures the file format is in the allowed list of image formats.
+func validateSetupRequest(in ImageInput) error {
+	if strings.TrimSpace(in.ModuleID) == "" {
+		return validationErrorf("module ID cannot be empty")
+	}
+
+	if len(in.Image) == 0 {
+		return validationErrorf("image data cannot be empty")
+	}
+
+	if strings.TrimSpace(in.FileFormat) == "" {
+		return validationErrorf("file format cannot be empty")
+	}
+
+	// File formats are MIME types such as image/png
+	kind, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(in.FileFormat)), "/")
+	if !ok || kind != "image" {
+		return validationErrorf("unsupported file format: %s", in.FileFormat)
+	}
+
+	// Validate common image formats
+	allowedFormats := []string{"png", "jpg", "jpeg", "gif", "webp", "svg", "svg+xml"}
+	for _, allowed := range allowedFormats {
+		if subtype == allowed {
+			return nil
+		}
+	}
+
+	return validationErrorf("unsupported file format: %s", in.FileFormat)
+}
+
+// moduleNameExists checks if a module with the given name already exists.
+// A non-empty excludeID ignores that module, which allows renaming checks.
+// Callers hold the dependency lock, which also serializes name changes; the
+// unique constraint on the name backs this up.
+func moduleNameExists(ctx context.Context, q sqlx.QueryerContext, name, excludeID string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM modules WHERE name = $1)`
+	args := []interface{}{name}
+
+	if excludeID != "" {
+		query = `SELECT EXISTS (SELECT 1 FROM modules WHERE name = $1 AND module_id <> $2)`
+		args = append(args, excludeID)
+	}
+
+	if err := sqlx.GetContext(ctx, q, &exists, query, args...); err != nil {
+		return false, fmt.Errorf("failed to check module name existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// moduleIDExists checks if a module with the given ID exists.
+// It returns true if a module with the specified ID is found in the database.
+func (m *Modules) moduleIDExists(ctx context.Context, moduleID string) (bool, error) {
+	if !ValidID(moduleID) {
+		return false, nil
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM modules WHERE module_id = $1)`
+
+	if err := m.db.GetContext(ctx, &exists, query, moduleID); err != nil {
+		return false, fmt.Errorf("failed to check module ID existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// createModule inserts a new module into the database.
+// It creates a module record with the provided name, host, port and
+// metadata together with its primary instance and dependencies, returning
+// the generated module ID. Registration counts as the first heartbeat.
+func createModule(ctx context.Context, tx *sqlx.Tx, in RegisterInput) (string, error) {
+	var moduleID string
+	query := `WITH created AS (
+			INSERT INTO modules (name, host, port, status, last_seen_at,
+				description, version, author, tags, homepage, capabilities)
+			VALUES ($1, $2, $3, 'ONLINE', CURRENT_TIMESTAMP, $4, $5, $6, $7, $8, $9) RETURNING module_id, host, port
+		),
+		primary_instance AS (
+			INSERT INTO module_instances (module_id, host, port, weight, zone, status, last_seen_at)
+			SELECT module_id, host, port, $10, $11, 'ONLINE', CURRENT_TIMESTAMP FROM created
+		),
+		dependencies AS (
+			INSERT INTO module_dependencies (module_id, name, version_range)
+			SELECT created.module_id, d.name, d.version_range
+			FROM created, unnest($12::text[], $13::text[]) AS d (name, version_range)
+		)
+		SELECT module_id FROM created`
+
+	md := in.Metadata
+	names, ranges := dependencyArrays(in.Dependencies)
+	err := tx.GetContext(ctx, &moduleID, query, in.Name, in.IP, in.Port,
+		md.Description, md.Version, md.Author, md.Tags, md.Homepage, md.Capabilities, in.Weight, in.Zone,
+		names, ranges)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return "", ErrConflict
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to insert module: %w", err)
+	}
+
+	return moduleID, nil
+}
+
+// setupModuleImage inserts or updates module image data.
+// It performs an upsert operation to handle both new image uploads and updates
+// to existing module images, including updating the timestamp.
+func (m *Modules) setupModuleImage(ctx context.Context, moduleID string, image []byte, fileFormat string) error {
+	query := `INSERT INTO images (module_id, image, fileformat) VALUES ($1, $2, $3)
+		ON CONFLICT (module_id) DO UPDATE SET
+			image = EXCLUDED.image,
+			fileformat = EXCLUDED.fileformat,
+			updated_at = CURRENT_TIMESTAMP`
+
+	result, err := m.db.ExecContext(ctx, query, moduleID, image, fileFormat)
+	if err != nil {
+		return fmt.Errorf("failed to setup module image: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no rows affected during image setup")
+	}
+
+	return nil
+}
+
+// deleteModule removes a module from the database.
+// It deletes the module record and returns true if a module was actually deleted,
+// false if no module with the given ID was found.
+func (m *Modules) deleteModule(ctx context.Context, moduleID string) (bool, error) {
+	if !ValidID(moduleID) {
+		return false, nil
+	}
+
+	query := `DELETE FROM modules WHERE module_id = $1`
+
+	result, err := m.db.ExecContext(ctx, query, moduleID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete module: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockService(t *testing.T) (*Modules, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewModules(sqlx.NewDb(mockDB, "sqlmock")), mock
+}
+
+func TestValidateRegisterRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      RegisterInput
+		wantErr string
+	}{
+		{"valid", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080}, ""},
+		{"empty name", RegisterInput{Name: "  ", IP: "10.0.0.1", Port: 8080}, "module name cannot be empty"},
+		{"ipv6", RegisterInput{Name: "dashboard", IP: "2001:db8::1", Port: 8080}, ""},
+		{"dns name", RegisterInput{Name: "dashboard", IP: "dashboard.default.svc.cluster.local", Port: 8080}, ""},
+		{"empty host", RegisterInput{Name: "dashboard", IP: "", Port: 8080}, "host cannot be empty"},
+		{"bad host", RegisterInput{Name: "dashboard", IP: "not a host", Port: 8080}, "invalid host"},
+		{"bad ipv4", RegisterInput{Name: "dashboard", IP: "10.0.0.256", Port: 8080}, "invalid host"},
+		{"bad label", RegisterInput{Name: "dashboard", IP: "-dashboard.local", Port: 8080}, "invalid host"},
+		{"ipv6 zone", RegisterInput{Name: "dashboard", IP: "fe80::1%eth0", Port: 8080}, "zones are not supported"},
+		{"bad port", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 70000}, "invalid port number"},
+		{"metadata", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Metadata: Metadata{
+			Version: "2.0.0-rc.1", Homepage: "https://example.com/dashboard",
+			Tags: []string{"ui", "monitoring"}, Capabilities: []string{"ui.widget", "proxy:http"},
+		}}, ""},
+		{"bad version", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Metadata: Metadata{Version: "v1.2"}}, "invalid version"},
+		{"bad homepage", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Metadata: Metadata{Homepage: "javascript:alert(1)"}}, "invalid homepage"},
+		{"bad tag", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Metadata: Metadata{Tags: []string{"two words"}}}, "invalid tag"},
+		{"bad capability", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Metadata: Metadata{Capabilities: []string{"-x"}}}, "invalid capability"},
+		{"too many tags", RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Metadata: Metadata{Tags: make([]string, 21)}}, "too many tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRegisterRequest(tt.in)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrValidation)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", normalizeHost(" 10.0.0.1 "))
+	assert.Equal(t, "2001:db8::1", normalizeHost("[2001:DB8:0::1]"))
+	assert.Equal(t, "dashboard.example.com", normalizeHost("Dashboard.Example.COM."))
+}
+
+func TestValidateSetupRequest(t *testing.T) {
+	valid := ImageInput{ModuleID: "00000000-0000-4000-a000-000000000001", Image: []byte{1}, FileFormat: "image/png"}
+	assert.NoError(t, validateSetupRequest(valid))
+
+	noSlash := valid
+	noSlash.FileFormat = "png"
+	assert.ErrorIs(t, validateSetupRequest(noSlash), ErrValidation)
+
+	notImage := valid
+	notImage.FileFormat = "text/html"
+	assert.ErrorIs(t, validateSetupRequest(notImage), ErrValidation)
+
+	empty := valid
+	empty.Image = nil
+	assert.ErrorIs(t, validateSetupRequest(empty), ErrValidation)
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("creates module", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).
+			WithArgs("dashboard", "10.0.0.1", int32(8080), "Platform overview", "1.4.0", "", pq.StringArray{"ui"}, "", pq.StringArray{},
+				int32(1), "eu-west-1a", pq.StringArray{}, pq.StringArray{}).
+			WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+
+		id, err := svc.Register(context.Background(), RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080, Zone: " eu-west-1a ", Metadata: Metadata{
+			Description: " Platform overview ",
+			Version:     "1.4.0",
+			Tags:        []string{"UI", "ui"},
+		}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "00000000-0000-4000-a000-000000000001", id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects duplicate name", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := svc.Register(context.Background(), RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080})
+
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports a concurrently taken name as a conflict", func(t *testing.T) {
+		svc, mock := newMockService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		_, err := svc.Register(context.Background(), RegisterInput{Name: "dashboard", IP: "10.0.0.1", Port: 8080})
+
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("checks DNS names with a resolver", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { mockDB.Close() })
+		svc := NewModules(sqlx.NewDb(mockDB, "sqlmock"), WithResolver(fakeResolver{"dashboard.local": {"10.0.0.1"}}))
+
+		_, err = svc.Register(context.Background(), RegisterInput{Name: "dashboard", IP: "missing.local", Port: 8080})
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Contains(t, err.Error(), "cannot resolve host")
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS`).WithArgs("dashboard").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(`INSERT INTO modules`).
+			WithArgs("dashboard", "dashboard.local", int32(8080), "", "", "", pq.StringArray{}, "", pq.StringArray{}, int32(1), "", pq.StringArray{}, pq.StringArray{}).
+			WillReturnRows(sqlmock.NewRows([]string{"module_id"}).AddRow("00000000-0000-4000-a000-000000000001"))
+		mock.ExpectCommit()
+
+		_, err = svc.Register(context.Background(), RegisterInput{Name: "dashboard", IP: "dashboard.local", Port: 8080})
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// fakeResolver resolves the names it holds and fails for all others.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestGetMalformedID(t *testing.T) {
+	svc, mock := newMockService(t)
+
+	// Postgres would fail to compare the ID with the uuid column
+	_, err := svc.Get(context.Background(), "foo")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdate(t *testing.T) {
+	svc, mock := newMockService(t)
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"description", "version", "author", "tags", "homepage", "capabilities"}
+
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "Overview", "1.0.0", "ops", "{ui}", "", "{}"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM module_instances`).WithArgs("00000000-0000-4000-a000-000000000001", "10.0.0.1", int32(9090)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE modules SET name`).
+		WithArgs("dashboard", "10.0.0.1", int32(9090), int64(1500), "Overview", "1.1.0", "ops", pq.StringArray{"ui"}, "", pq.StringArray{}, "00000000-0000-4000-a000-000000000001",
+			"least_connections").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 9090, "10.0.0.1:9090", "ONLINE", nil, 1500, "Overview", "1.1.0", "ops", "{ui}", "", "{}"))
+
+	port := int32(9090)
+	timeout := int64(1500)
+	version := "1.1.0"
+	policy := "least_connections"
+	module, err := svc.Update(context.Background(), "00000000-0000-4000-a000-000000000001", UpdateInput{Port: &port, ProxyTimeoutMS: &timeout, Version: &version, LBPolicy: &policy})
+
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:9090", module.IPPort)
+	assert.Equal(t, int64(1500), *module.ProxyTimeoutMS)
+	assert.Equal(t, "1.1.0", module.Version)
+	assert.Equal(t, pq.StringArray{"ui"}, module.Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRenameConflict(t *testing.T) {
+	svc, mock := newMockService(t)
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"description", "version", "author", "tags", "homepage", "capabilities"}
+
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "", "", "", "{}", "", "{}"))
+	// The name is checked under the lock, inside the transaction that renames
+	mock.ExpectBegin()
+	mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT name, version_range FROM module_dependencies`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "version_range"}))
+	mock.ExpectQuery(`SELECT EXISTS .* module_id <> \$2`).WithArgs("reports", "00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	name := "reports"
+	_, err := svc.Update(context.Background(), "00000000-0000-4000-a000-000000000001", UpdateInput{Name: &name})
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReregister(t *testing.T) {
+	svc, mock := newMockService(t)
+	columns := []string{"module_id", "name", "host", "port", "ip_port", "status", "last_seen_at", "proxy_timeout_ms",
+		"description", "version", "author", "tags", "homepage", "capabilities"}
+
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "OFFLINE", nil, nil, "Overview", "1.0.0", "ops", "{ui}", "", "{}"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	// A replica already listening on the new address makes way for the primary
+	mock.ExpectExec(`DELETE FROM module_instances\s+WHERE module_id = \$1 AND host = \$2 AND port = \$3`).
+		WithArgs("00000000-0000-4000-a000-000000000001", "10.0.0.7", int32(8080)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE modules SET host = \$1, port = \$2, .*status = 'ONLINE'`).
+		WithArgs("10.0.0.7", int32(8080), "", "1.1.0", "", pq.StringArray{}, "", pq.StringArray{}, "00000000-0000-4000-a000-000000000001").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO module_dependencies`).WithArgs("00000000-0000-4000-a000-000000000001", pq.StringArray{}, pq.StringArray{}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.7", 8080, "10.0.0.7:8080", "ONLINE", nil, nil, "", "1.1.0", "", "{}", "", "{}"))
+
+	module, err := svc.Reregister(context.Background(), "00000000-0000-4000-a000-000000000001", RegisterInput{
+		Name: "ignored", IP: "10.0.0.7", Port: 8080, Metadata: Metadata{Version: "1.1.0"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "dashboard", module.Name)
+	assert.Equal(t, "10.0.0.7:8080", module.IPPort)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	t.Run("rejects invalid address", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT module_id, name, host`).WithArgs("00000000-0000-4000-a000-000000000001").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("00000000-0000-4000-a000-000000000001", "dashboard", "10.0.0.1", 8080, "10.0.0.1:8080", "ONLINE", nil, nil, "", "", "", "{}", "", "{}"))
+
+		_, err := svc.Reregister(context.Background(), "00000000-0000-4000-a000-000000000001", RegisterInput{IP: "10.0.0.7", Port: 0})
+
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
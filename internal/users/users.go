@@ -0,0 +1,406 @@
+// Package users manages the accounts that operate the platform through the
+// REST API.
+//
+// Local users log in with a username and password, external users through
+// an identity provider; both receive an opaque session token. Passwords are
+// stored as bcrypt hashes and session tokens as SHA-256 hashes. Every user
+// has one of three roles; each role includes the permissions of the ones
+// below it.
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/The-OpenPlatform/backend/internal/secrets"
+)
+
+// Role grants access to a set of REST endpoints.
+type Role string
+
+const (
+	// RoleViewer may read module data that is not public.
+	RoleViewer Role = "viewer"
+	// RoleOperator may additionally modify modules and use the module proxy.
+	RoleOperator Role = "operator"
+	// RoleAdmin may additionally manage users, API keys, join tokens and
+	// certificates.
+	RoleAdmin Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether r includes the permissions of required.
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[required]
+}
+
+// SessionPrefix starts every session token.
+const SessionPrefix = "ops_"
+
+const minPasswordLength = 8
+
+var (
+	// ErrValidation is returned for invalid usernames, passwords or roles.
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidLogin is returned for unknown users and wrong passwords alike.
+	ErrInvalidLogin = errors.New("invalid username or password")
+	// ErrInvalidSession is returned for unknown or expired session tokens.
+	ErrInvalidSession = errors.New("invalid session")
+	// ErrNotFound is returned when a user does not exist.
+	ErrNotFound = errors.New("user not found")
+	// ErrConflict is returned when a username is already taken.
+	ErrConflict = errors.New("username already exists")
+	// ErrLastAdmin is returned when a change would leave no admin.
+	ErrLastAdmin = errors.New("cannot remove the last admin")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,64}$`)
+
+// dummyHash is compared against when a login names an unknown user, so
+// that unknown and known usernames take the same time to reject.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// User is a local or external account.
+type User struct {
+	UserID    string    `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	Role      Role      `db:"role" json:"role"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Update describes changes to a user. Nil fields are left unchanged.
+type Update struct {
+	Role     *Role
+	Password *string
+}
+
+// Store persists users and their sessions.
+type Store struct {
+	db *sqlx.DB
+}
+
+// NewStore creates a store backed by the given database.
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create adds a user with the given password and role.
+func (s *Store) Create(ctx context.Context, username, password string, role Role) (*User, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: username must be 3-64 letters, digits, '.', '_' or '-'", ErrValidation)
+	}
+
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrValidation, role)
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	query := `INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3)
+		RETURNING user_id, username, role, created_at, updated_at`
+
+	err = s.db.GetContext(ctx, &user, query, username, passwordHash, role)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// List returns all users ordered by username.
+func (s *Store) List(ctx context.Context) ([]User, error) {
+	users := []User{}
+	if err := s.db.SelectContext(ctx, &users,
+		`SELECT user_id, username, role, created_at, updated_at FROM users ORDER BY username`); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// Update changes a user's role or password. Changing the password ends all
+// of the user's sessions. The last admin cannot be demoted.
+func (s *Store) Update(ctx context.Context, userID string, in Update) (*User, error) {
+	var passwordHash *string
+	if in.Password != nil {
+		hashed, err := hashPassword(*in.Password)
+		if err != nil {
+			return nil, err
+		}
+		passwordHash = &hashed
+	}
+
+	if in.Role != nil && !in.Role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrValidation, *in.Role)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	defer tx.Rollback()
+
+	if in.Role != nil && *in.Role != RoleAdmin {
+		if err := keepAdmin(ctx, tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	var user User
+	query := `UPDATE users SET
+			role = COALESCE($2, role),
+			password_hash = COALESCE($3, password_hash),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id::text = $1
+		RETURNING user_id, username, role, created_at, updated_at`
+
+	err = tx.GetContext(ctx, &user, query, userID, in.Role, passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if passwordHash != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_sessions WHERE user_id = $1`, user.UserID); err != nil {
+			return nil, fmt.Errorf("failed to end sessions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// Delete removes a user and its sessions. The last admin cannot be deleted.
+func (s *Store) Delete(ctx context.Context, userID string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := keepAdmin(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE user_id::text = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+// keepAdmin returns ErrLastAdmin when userID is the only admin. It locks the
+// admins until tx ends, so that concurrent changes cannot remove the last
+// two admins at once.
+func keepAdmin(ctx context.Context, tx *sqlx.Tx, userID string) error {
+	var admins []string
+	if err := tx.SelectContext(ctx, &admins,
+		`SELECT user_id::text FROM users WHERE role = 'admin' ORDER BY user_id FOR UPDATE`); err != nil {
+		return fmt.Errorf("failed to load admins: %w", err)
+	}
+
+	if len(admins) == 1 && admins[0] == userID {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// Authenticate checks a username and password.
+func (s *Store) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	var row struct {
+		User
+		PasswordHash string `db:"password_hash"`
+	}
+
+	err := s.db.GetContext(ctx, &row,
+		`SELECT user_id, username, role, created_at, updated_at, COALESCE(password_hash, '') AS password_hash
+		FROM users WHERE username = $1`, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidLogin
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	// External users have no password and can only log in through their provider
+	if row.PasswordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidLogin
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(row.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidLogin
+	}
+
+	return &row.User, nil
+}
+
+// UpsertExternal creates or updates the user for an identity-provider
+// account. The provider is authoritative, so the username and role are
+// refreshed on every login. ErrConflict is returned when the username is
+// taken by another account.
+func (s *Store) UpsertExternal(ctx context.Context, externalID, username string, role Role) (*User, error) {
+	if externalID == "" || username == "" {
+		return nil, fmt.Errorf("%w: external ID and username are required", ErrValidation)
+	}
+
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrValidation, role)
+	}
+
+	var user User
+	query := `INSERT INTO users (username, role, external_id) VALUES ($1, $2, $3)
+		ON CONFLICT (external_id) DO UPDATE SET
+			username = EXCLUDED.username,
+			role = EXCLUDED.role,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING user_id, username, role, created_at, updated_at`
+
+	err := s.db.GetContext(ctx, &user, query, username, role, externalID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store external user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// CreateSession starts a session for the user and returns its token.
+func (s *Store) CreateSession(ctx context.Context, userID string, ttl time.Duration) (string, time.Time, error) {
+	secret, err := secrets.New()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	var expiresAt time.Time
+	query := `INSERT INTO user_sessions (session_hash, user_id, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + $3 * INTERVAL '1 second')
+		RETURNING expires_at`
+
+	if err := s.db.GetContext(ctx, &expiresAt, query, secrets.Hash(secret), userID, int64(ttl.Seconds())); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return SessionPrefix + secret, expiresAt, nil
+}
+
+// VerifySession returns the user a session token belongs to.
+func (s *Store) VerifySession(ctx context.Context, token string) (*User, error) {
+	secret, ok := strings.CutPrefix(token, SessionPrefix)
+	if !ok || secret == "" {
+		return nil, ErrInvalidSession
+	}
+
+	var user User
+	query := `SELECT u.user_id, u.username, u.role, u.created_at, u.updated_at
+		FROM user_sessions s JOIN users u ON u.user_id = s.user_id
+		WHERE s.session_hash = $1 AND s.expires_at > CURRENT_TIMESTAMP`
+
+	err := s.db.GetContext(ctx, &user, query, secrets.Hash(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	return &user, nil
+}
+
+// EndSession deletes a session. Unknown tokens are ignored.
+func (s *Store) EndSession(ctx context.Context, token string) error {
+	secret, ok := strings.CutPrefix(token, SessionPrefix)
+	if !ok {
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE session_hash = $1`, secrets.Hash(secret)); err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that can no longer be used.
+func (s *Store) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("%w: password must be at least %d characters", ErrValidation, minPasswordLength)
+	}
+
+	// bcrypt ignores everything after 72 bytes
+	if len(password) > 72 {
+		return "", fmt.Errorf("%w: password cannot be longer than 72 bytes", ErrValidation)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashed), nil
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying the authenticated user.
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// FromContext returns the authenticated user, if any.
+func FromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey{}).(*User)
+	return user, ok
+}
@@ -0,0 +1,194 @@
+package users
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/The-OpenPlatform/backend/internal/secrets"
+)
+
+func newTestStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewStore(sqlx.NewDb(mockDB, "sqlmock")), mock
+}
+
+var userColumns = []string{"user_id", "username", "role", "created_at", "updated_at"}
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleOperator))
+	assert.True(t, RoleOperator.Allows(RoleOperator))
+	assert.False(t, RoleViewer.Allows(RoleOperator))
+	assert.False(t, Role("root").Allows(RoleViewer))
+}
+
+func TestCreate(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	for name, args := range map[string][3]string{
+		"short username": {"al", "password1", "viewer"},
+		"short password": {"alice", "short", "viewer"},
+		"unknown role":   {"alice", "password1", "root"},
+	} {
+		_, err := store.Create(ctx, args[0], args[1], Role(args[2]))
+		assert.ErrorIs(t, err, ErrValidation, name)
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("alice", sqlmock.AnyArg(), RoleOperator).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow("u1", "alice", "operator", now, now))
+
+	user, err := store.Create(ctx, "alice", "password1", RoleOperator)
+	require.NoError(t, err)
+	assert.Equal(t, RoleOperator, user.Role)
+
+	mock.ExpectQuery(`INSERT INTO users`).WillReturnError(&pq.Error{Code: "23505"})
+	_, err = store.Create(ctx, "alice", "password1", RoleOperator)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthenticate(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	rows := func() *sqlmock.Rows {
+		now := time.Now()
+		return sqlmock.NewRows(append(userColumns, "password_hash")).AddRow("u1", "alice", "admin", now, now, string(hashed))
+	}
+
+	mock.ExpectQuery(`FROM users WHERE username`).WithArgs("alice").WillReturnRows(rows())
+	user, err := store.Authenticate(ctx, "alice", "password1")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", user.UserID)
+
+	mock.ExpectQuery(`FROM users WHERE username`).WithArgs("alice").WillReturnRows(rows())
+	_, err = store.Authenticate(ctx, "alice", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+
+	mock.ExpectQuery(`FROM users WHERE username`).WithArgs("bob").WillReturnRows(sqlmock.NewRows(userColumns))
+	_, err = store.Authenticate(ctx, "bob", "password1")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+
+	// External users have no password
+	mock.ExpectQuery(`FROM users WHERE username`).WithArgs("carol").
+		WillReturnRows(sqlmock.NewRows(append(userColumns, "password_hash")).AddRow("u3", "carol", "viewer", time.Now(), time.Now(), ""))
+	_, err = store.Authenticate(ctx, "carol", "")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertExternal(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+
+	_, err := store.UpsertExternal(ctx, "https://idp|1", "alice", Role("root"))
+	assert.ErrorIs(t, err, ErrValidation)
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO users .* ON CONFLICT \(external_id\) DO UPDATE`).
+		WithArgs("alice", RoleViewer, "https://idp|1").
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow("u1", "alice", "viewer", now, now))
+	user, err := store.UpsertExternal(ctx, "https://idp|1", "alice", RoleViewer)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", user.UserID)
+
+	// The username is taken by a local account
+	mock.ExpectQuery(`INSERT INTO users`).WillReturnError(&pq.Error{Code: "23505"})
+	_, err = store.UpsertExternal(ctx, "https://idp|2", "alice", RoleViewer)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessions(t *testing.T) {
+	store, mock := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO user_sessions`).
+		WithArgs(sqlmock.AnyArg(), "u1", int64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at"}).AddRow(now.Add(time.Hour)))
+
+	token, expiresAt, err := store.CreateSession(ctx, "u1", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, SessionPrefix))
+	assert.Equal(t, now.Add(time.Hour), expiresAt)
+	sessionHash := secrets.Hash(strings.TrimPrefix(token, SessionPrefix))
+
+	mock.ExpectQuery(`FROM user_sessions s JOIN users u`).WithArgs(sessionHash).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow("u1", "alice", "viewer", now, now))
+	user, err := store.VerifySession(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+
+	_, err = store.VerifySession(ctx, "not-a-session")
+	assert.ErrorIs(t, err, ErrInvalidSession)
+
+	mock.ExpectExec(`DELETE FROM user_sessions WHERE session_hash`).WithArgs(sessionHash).WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.EndSession(ctx, token))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdatePasswordEndsSessions(t *testing.T) {
+	store, mock := newTestStore(t)
+	now := time.Now()
+	password := "new-password"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE users SET`).WithArgs("u1", nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow("u1", "alice", "viewer", now, now))
+	mock.ExpectExec(`DELETE FROM user_sessions WHERE user_id`).WithArgs("u1").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	_, err := store.Update(context.Background(), "u1", Update{Password: &password})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKeepLastAdmin(t *testing.T) {
+	store, mock := newTestStore(t)
+	admins := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"user_id"}).AddRow("u1") }
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id::text FROM users WHERE role = 'admin' .* FOR UPDATE`).WillReturnRows(admins())
+	mock.ExpectRollback()
+
+	viewer := RoleViewer
+	_, err := store.Update(context.Background(), "u1", Update{Role: &viewer})
+	assert.ErrorIs(t, err, ErrLastAdmin)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(admins())
+	mock.ExpectRollback()
+
+	assert.ErrorIs(t, store.Delete(context.Background(), "u1"), ErrLastAdmin)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(admins())
+	mock.ExpectExec(`DELETE FROM users`).WithArgs("u2").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, store.Delete(context.Background(), "u2"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}